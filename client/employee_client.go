@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"employee-maintenance/models"
+	"employee-maintenance/orgchart"
 )
 
 type EmployeeClient struct {
@@ -48,7 +51,7 @@ func (c *EmployeeClient) Create(emp models.Employee) (models.Employee, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return models.Employee{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return models.Employee{}, decodeProblem(resp)
 	}
 
 	var created models.Employee
@@ -66,12 +69,8 @@ func (c *EmployeeClient) Retrieve(id int) (models.Employee, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return models.Employee{}, fmt.Errorf("employee not found: %d", id)
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return models.Employee{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return models.Employee{}, decodeProblem(resp)
 	}
 
 	var emp models.Employee
@@ -90,7 +89,7 @@ func (c *EmployeeClient) RetrieveAll() ([]models.Employee, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, decodeProblem(resp)
 	}
 
 	var employees []models.Employee
@@ -101,6 +100,30 @@ func (c *EmployeeClient) RetrieveAll() ([]models.Employee, error) {
 	return employees, nil
 }
 
+// List fetches one page of employees matching filter.
+func (c *EmployeeClient) List(filter EmployeeFilter, opts ListOptions) (Page[models.Employee], error) {
+	q := url.Values{}
+	filter.encode(q)
+	opts.encode(q)
+	resp, err := c.httpClient.Get(c.baseURL + "/employees?" + q.Encode())
+	if err != nil {
+		return Page[models.Employee]{}, fmt.Errorf("failed to list employees: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Page[models.Employee]{}, decodeProblem(resp)
+	}
+
+	var page Page[models.Employee]
+	if err := json.NewDecoder(resp.Body).Decode(&page.Items); err != nil {
+		return Page[models.Employee]{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	page.Total, _ = strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
+
 func (c *EmployeeClient) Update(emp models.Employee) (models.Employee, error) {
 	body, err := json.Marshal(emp)
 	if err != nil {
@@ -116,6 +139,9 @@ func (c *EmployeeClient) Update(emp models.Employee) (models.Employee, error) {
 		return models.Employee{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if emp.Version != 0 {
+		req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, emp.Version))
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -123,12 +149,8 @@ func (c *EmployeeClient) Update(emp models.Employee) (models.Employee, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return models.Employee{}, fmt.Errorf("employee not found: %d", emp.ID)
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return models.Employee{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return models.Employee{}, decodeProblem(resp)
 	}
 
 	var updated models.Employee
@@ -155,14 +177,35 @@ func (c *EmployeeClient) Delete(id int) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("employee not found: %d", id)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return decodeProblem(resp)
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	return nil
+}
+
+// OrgChart writes the org chart below root, drawn in the given format (dot,
+// mermaid or svg), to w.
+func (c *EmployeeClient) OrgChart(w io.Writer, format string, root orgchart.Root) error {
+	q := url.Values{}
+	if format != "" {
+		q.Set("format", format)
+	}
+	if root != (orgchart.Root{}) {
+		q.Set("root", root.String())
 	}
+	resp, err := c.httpClient.Get(c.baseURL + "/orgchart?" + q.Encode())
+	if err != nil {
+		return fmt.Errorf("failed to retrieve org chart: %w", err)
+	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return decodeProblem(resp)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read org chart: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// FieldError describes why the server rejected a single field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ProblemError is an RFC 7807 problem returned by the API.
+type ProblemError struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	RequestID string       `json:"requestId"`
+	Errors    []FieldError `json:"errors"`
+}
+
+func (e *ProblemError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.Status, e.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+}
+
+// decodeProblem turns an error response into a *ProblemError. Responses that
+// are not problem+json still produce one, with the body as the detail.
+func decodeProblem(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	problem := &ProblemError{}
+	if err := json.Unmarshal(body, problem); err != nil || problem.Status == 0 {
+		problem = &ProblemError{Detail: string(body)}
+	}
+	problem.Status = resp.StatusCode
+	if problem.Title == "" {
+		problem.Title = http.StatusText(resp.StatusCode)
+	}
+	return problem
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+// EmployeeFilter narrows List to the employees matching every field set.
+// An empty Statuses lists employees of every status. AsOf and KnownAt ask
+// for the state at a past instant, as the asOf and knownAt parameters do.
+type EmployeeFilter struct {
+	DepartmentID   int
+	ManagerID      int
+	LastName       string
+	EmailPrefix    string
+	Statuses       []models.EmploymentStatus
+	AsOf           time.Time
+	KnownAt        time.Time
+	IncludeDeleted bool
+}
+
+func (f EmployeeFilter) encode(q url.Values) {
+	setTime(q, "asOf", f.AsOf)
+	setTime(q, "knownAt", f.KnownAt)
+	if f.IncludeDeleted {
+		q.Set("includeDeleted", "true")
+	}
+	setInt(q, "departmentId", f.DepartmentID)
+	setInt(q, "managerId", f.ManagerID)
+	if f.LastName != "" {
+		q.Set("lastName", f.LastName)
+	}
+	if f.EmailPrefix != "" {
+		q.Set("emailPrefix", f.EmailPrefix)
+	}
+	if len(f.Statuses) == 0 {
+		q.Set("status", "all")
+		return
+	}
+	statuses := make([]string, len(f.Statuses))
+	for i, s := range f.Statuses {
+		statuses[i] = string(s)
+	}
+	q.Set("status", strings.Join(statuses, ","))
+}
+
+// SortKey orders a listing by Field, descending if Desc is set.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions selects one page of a listing. A zero Limit returns
+// everything after Offset (or Cursor); Cursor is taken from a previous
+// Page.NextCursor.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   []SortKey
+}
+
+func (o ListOptions) encode(q url.Values) {
+	setInt(q, "limit", o.Limit)
+	setInt(q, "offset", o.Offset)
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if len(o.Sort) > 0 {
+		fields := make([]string, len(o.Sort))
+		for i, k := range o.Sort {
+			fields[i] = k.Field
+			if k.Desc {
+				fields[i] = "-" + k.Field
+			}
+		}
+		q.Set("sort", strings.Join(fields, ","))
+	}
+}
+
+// Page is one page of a listing. Total counts every match, and NextCursor
+// is empty on the last page.
+type Page[T any] struct {
+	Items      []T
+	Total      int
+	NextCursor string
+}
+
+func setInt(q url.Values, name string, v int) {
+	if v != 0 {
+		q.Set(name, strconv.Itoa(v))
+	}
+}
+
+func setTime(q url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		q.Set(name, t.Format(time.RFC3339Nano))
+	}
+}
@@ -1,20 +1,134 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
 
+	"employee-maintenance/idempotency"
+	"employee-maintenance/models"
 	"employee-maintenance/server"
 	"employee-maintenance/services"
+	"employee-maintenance/storage"
 )
 
 //go:embed openapi.yaml
 var openapiSpec []byte
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "orgchart" {
+		runOrgChart(os.Args[2:])
+		return
+	}
+
+	backend := flag.String("storage", "memory", "storage backend: memory, file or wal")
+	dataDir := flag.String("data", "data", "directory for persisted data (file and wal backends)")
+	compactEvery := flag.Int("compact-every", storage.DefaultCompactEvery, "log records between snapshots (wal backend)")
+	retention := flag.Duration("retention", 30*24*time.Hour, "how long deleted records can be restored before they are purged (0 keeps them forever)")
+	purgeInterval := flag.Duration("purge-interval", time.Hour, "how often to purge deleted records past the retention period")
+	idempotencyTTL := flag.Duration("idempotency-ttl", idempotency.DefaultTTL, "how long responses to requests with an Idempotency-Key are kept for replay")
+	scheduleInterval := flag.Duration("schedule-interval", time.Minute, "how often to apply scheduled changes that have become due")
+	txTimeout := flag.Duration("transaction-timeout", server.DefaultTransactionTimeout, "how long a transaction may go without a request before it is rolled back")
+	flag.Parse()
+
 	server.SetOpenAPISpec(openapiSpec)
 
-	employeeService := services.NewEmployeeService()
-	departmentService := services.NewDepartmentService()
-	srv := server.NewServer(employeeService, departmentService)
+	employeeService, departmentService, err := newServices(*backend, *dataDir, *compactEvery)
+	if err != nil {
+		log.Fatal("Failed to open storage: ", err)
+	}
+	auditLog, err := newAuditLog(*backend, *dataDir)
+	if err != nil {
+		log.Fatal("Failed to open audit log: ", err)
+	}
+	services.Link(employeeService, departmentService)
+	employeeService.SetAuditLog(auditLog)
+	departmentService.SetAuditLog(auditLog)
+	if *retention > 0 {
+		purger := services.Purger{Employees: employeeService, Departments: departmentService, Retention: *retention}
+		go purger.Run(context.Background(), *purgeInterval)
+	}
+	changes, err := newScheduledChanges(*backend, *dataDir, *compactEvery, employeeService)
+	if err != nil {
+		log.Fatal("Failed to open scheduled changes: ", err)
+	}
+	go changes.Run(context.Background(), *scheduleInterval)
+	positions, err := newPositions(*backend, *dataDir, *compactEvery, employeeService, departmentService)
+	if err != nil {
+		log.Fatal("Failed to open positions: ", err)
+	}
+	positions.SetAuditLog(auditLog)
+	srv := server.NewServer(employeeService, departmentService, auditLog)
+	srv.SetScheduledChangeService(changes)
+	srv.SetPositionService(positions)
+	srv.SetIdempotencyTTL(*idempotencyTTL)
+	srv.SetTransactionTimeout(*txTimeout)
 	srv.Start()
 }
+
+func newServices(backend, dataDir string, compactEvery int) (*services.EmployeeService, *services.DepartmentService, error) {
+	employees, err := openRepository[models.Employee](backend, dataDir, "employees", compactEvery)
+	if err != nil {
+		return nil, nil, err
+	}
+	departments, err := openRepository[models.Department](backend, dataDir, "departments", compactEvery)
+	if err != nil {
+		return nil, nil, err
+	}
+	return services.NewEmployeeServiceWithRepository(employees),
+		services.NewDepartmentServiceWithRepository(departments), nil
+}
+
+// openRepository opens the repository called name in the given backend: a
+// new one in memory, name.json in dataDir for file, or the log name in
+// dataDir for wal.
+func openRepository[T any](backend, dataDir, name string, compactEvery int) (storage.Repository[T], error) {
+	switch backend {
+	case "memory":
+		return storage.NewMemory[T](), nil
+	case "file":
+		return storage.OpenFile[T](filepath.Join(dataDir, name+".json"))
+	case "wal":
+		return storage.OpenLog[T](dataDir, name, compactEvery)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// newAuditLog keeps the audit trail in memory for the memory backend and in
+// an append-only journal next to the data otherwise.
+func newAuditLog(backend, dataDir string) (*services.AuditLog, error) {
+	if backend == "memory" {
+		return services.NewAuditLog(), nil
+	}
+	journal, err := storage.OpenJournal[models.AuditEntry](filepath.Join(dataDir, "audit.log"))
+	if err != nil {
+		return nil, err
+	}
+	return services.NewAuditLogWithJournal(journal), nil
+}
+
+// newScheduledChanges keeps scheduled changes alongside the employees they
+// apply to, in the same backend.
+func newScheduledChanges(backend, dataDir string, compactEvery int, employees *services.EmployeeService) (*services.ScheduledChangeService, error) {
+	repo, err := openRepository[models.ScheduledChange](backend, dataDir, "scheduled_changes", compactEvery)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewScheduledChangeServiceWithRepository(repo, employees), nil
+}
+
+// newPositions keeps positions in the same backend as the departments they
+// belong to.
+func newPositions(backend, dataDir string, compactEvery int, employees *services.EmployeeService, departments *services.DepartmentService) (*services.PositionService, error) {
+	repo, err := openRepository[models.Position](backend, dataDir, "positions", compactEvery)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewPositionServiceWithRepository(repo, employees, departments), nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"employee-maintenance/client"
+	"employee-maintenance/orgchart"
+)
+
+// runOrgChart implements the orgchart command, which fetches the org chart
+// from a running server and writes it to a file or standard output.
+func runOrgChart(args []string) {
+	flags := flag.NewFlagSet("orgchart", flag.ExitOnError)
+	serverURL := flags.String("server", "http://localhost:8080", "base URL of the running server")
+	format := flags.String("format", orgchart.DefaultFormat, "output format: dot, mermaid or svg")
+	rootFlag := flags.String("root", "", "draw only below {id}, employee:{id} or department:{id}")
+	output := flags.String("o", "", "file to write (default standard output)")
+	flags.Parse(args)
+
+	if _, err := orgchart.LookupFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+	root, err := orgchart.ParseRoot(*rootFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal("Failed to create output file: ", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := client.NewEmployeeClient(*serverURL).OrgChart(w, *format, root); err != nil {
+		log.Fatal("Failed to fetch org chart: ", err)
+	}
+}
@@ -0,0 +1,414 @@
+// Package codec encodes and decodes API values as JSON, XML, YAML and CSV.
+// The other formats are derived from the JSON form of a value, so field
+// names, order and omitted fields are the same in every format, and decoding
+// follows the json tags of the target type.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Codec reads and writes one media type.
+type Codec struct {
+	MediaType string
+	Encode    func(w io.Writer, v any) error
+	Decode    func(r io.Reader, v any) error
+}
+
+var (
+	JSON = Codec{MediaType: "application/json", Encode: encodeJSON, Decode: decodeJSON}
+	XML  = Codec{MediaType: "application/xml", Encode: encodeXML, Decode: decodeXML}
+	YAML = Codec{MediaType: "application/yaml", Encode: encodeYAML, Decode: decodeYAML}
+	CSV  = Codec{MediaType: "text/csv", Encode: encodeCSV, Decode: decodeCSV}
+)
+
+// aliases maps other names in use for the same media types to their codec.
+var aliases = map[string]Codec{
+	"text/xml":           XML,
+	"application/x-yaml": YAML,
+	"text/yaml":          YAML,
+	"text/x-yaml":        YAML,
+}
+
+// MediaTypes lists every media type a codec exists for, in order of
+// preference.
+var MediaTypes = []string{
+	JSON.MediaType, XML.MediaType, "text/xml", YAML.MediaType, "application/x-yaml", "text/yaml", CSV.MediaType,
+}
+
+// Lookup returns the codec for a media type, without parameters.
+func Lookup(mediaType string) (Codec, bool) {
+	for _, c := range []Codec{JSON, XML, YAML, CSV} {
+		if c.MediaType == mediaType {
+			return c, true
+		}
+	}
+	c, ok := aliases[mediaType]
+	return c, ok
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSON(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Kind is the type of a Node.
+type Kind int
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	// Text is a scalar whose type is not known, such as XML character data
+	// or a CSV cell. It takes the type of the field it is decoded into.
+	Text
+	Object
+	Array
+)
+
+// Node is a value in a format-neutral tree. Objects keep their keys in
+// order; Values holds the value for each key, or the items of an array.
+type Node struct {
+	Kind   Kind
+	Scalar string
+	Keys   []string
+	Values []*Node
+
+	// repeated marks a list collected from repeated XML elements.
+	repeated bool
+}
+
+func (n *Node) set(key string, v *Node) {
+	n.Keys = append(n.Keys, key)
+	n.Values = append(n.Values, v)
+}
+
+// toTree converts v to a tree by way of its JSON encoding.
+func toTree(v any) (*Node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return readJSONNode(dec)
+}
+
+func readJSONNode(dec *json.Decoder) (*Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case nil:
+		return &Node{Kind: Null}, nil
+	case bool:
+		return &Node{Kind: Bool, Scalar: strconv.FormatBool(t)}, nil
+	case json.Number:
+		return &Node{Kind: Number, Scalar: t.String()}, nil
+	case string:
+		return &Node{Kind: String, Scalar: t}, nil
+	case json.Delim:
+		if t == '[' {
+			n := &Node{Kind: Array}
+			for dec.More() {
+				item, err := readJSONNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				n.Values = append(n.Values, item)
+			}
+			_, err := dec.Token()
+			return n, err
+		}
+		n := &Node{Kind: Object}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.set(key.(string), value)
+		}
+		_, err := dec.Token()
+		return n, err
+	}
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// fromTree decodes a tree into v, which must be a pointer. Text scalars are
+// given the type of the field they land in.
+func fromTree(n *Node, v any) error {
+	var buf bytes.Buffer
+	writeJSON(&buf, n, reflect.TypeOf(v))
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+var (
+	rawMessageType  = reflect.TypeOf(json.RawMessage(nil))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// writeJSON writes n as JSON for a target of type t, which may be nil when
+// the target is unknown.
+func writeJSON(buf *bytes.Buffer, n *Node, t reflect.Type) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t == rawMessageType || reflect.PointerTo(t).Implements(unmarshalerType)) && n.Kind == Text {
+		// Types with their own JSON form, such as time.Time, take strings.
+		if n.Scalar == "" {
+			buf.WriteString("null")
+		} else {
+			writeJSONString(buf, n.Scalar)
+		}
+		return
+	}
+	if t == rawMessageType {
+		// Raw JSON holds whatever was sent, read as if untyped.
+		t = nil
+	}
+	switch n.Kind {
+	case Null:
+		buf.WriteString("null")
+	case Bool, Number:
+		buf.WriteString(n.Scalar)
+	case String:
+		writeJSONString(buf, n.Scalar)
+	case Text:
+		writeText(buf, n.Scalar, t)
+	case Array:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		writeJSONArray(buf, n.Values, elem)
+	case Object:
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			// XML wraps list items in an element per item.
+			writeJSONArray(buf, n.items(), t.Elem())
+			return
+		}
+		buf.WriteByte('{')
+		for i, key := range n.Keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, key)
+			buf.WriteByte(':')
+			writeJSON(buf, n.Values[i], fieldType(t, key))
+		}
+		buf.WriteByte('}')
+	}
+}
+
+// items returns the children of an object read as a list, expanding
+// repeated elements.
+func (n *Node) items() []*Node {
+	var items []*Node
+	for _, v := range n.Values {
+		if v.repeated {
+			items = append(items, v.Values...)
+		} else {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+func writeJSONArray(buf *bytes.Buffer, items []*Node, elem reflect.Type) {
+	buf.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSON(buf, item, elem)
+	}
+	buf.WriteByte(']')
+}
+
+// writeText writes an untyped scalar as the JSON type t expects. A value
+// that does not fit is written as a string so that decoding reports it.
+func writeText(buf *bytes.Buffer, s string, t reflect.Type) {
+	if t == nil || t.Kind() == reflect.Interface {
+		writeJSONInferred(buf, s)
+		return
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s == "" {
+			buf.WriteString("null")
+		} else if _, err := strconv.ParseFloat(s, 64); err == nil {
+			buf.WriteString(s)
+		} else {
+			writeJSONString(buf, s)
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			buf.WriteString(strconv.FormatBool(b))
+		} else if s == "" {
+			buf.WriteString("null")
+		} else {
+			writeJSONString(buf, s)
+		}
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		// A CSV cell holds nested values as JSON.
+		if strings.TrimSpace(s) == "" {
+			buf.WriteString("null")
+		} else if json.Valid([]byte(s)) {
+			buf.WriteString(s)
+		} else {
+			writeJSONString(buf, s)
+		}
+	default:
+		writeJSONString(buf, s)
+	}
+}
+
+// writeJSONInferred writes a scalar with no target type the way YAML would
+// read it.
+func writeJSONInferred(buf *bytes.Buffer, s string) {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		buf.WriteString("null")
+		return
+	case "true", "True", "TRUE":
+		buf.WriteString("true")
+		return
+	case "false", "False", "FALSE":
+		buf.WriteString("false")
+		return
+	}
+	if looksNumeric(s) {
+		buf.WriteString(s)
+		return
+	}
+	writeJSONString(buf, s)
+}
+
+// looksNumeric reports whether s is a number JSON can carry as is.
+func looksNumeric(s string) bool {
+	if !json.Valid([]byte(s)) {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// fieldType returns the type of the member key of an object of type t: the
+// struct field with that JSON name (matched like encoding/json does,
+// ignoring case) or the map's element type.
+func fieldType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		return structField(t, key)
+	}
+	return nil
+}
+
+func structField(t reflect.Type, key string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n, _, _ := strings.Cut(tag, ","); n != "" {
+				name = n
+			}
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if found := structField(ft, key); found != nil {
+					return found
+				}
+				continue
+			}
+		}
+		if strings.EqualFold(name, key) {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// typeName returns the lower camel case name of v's type, without package
+// or type parameters, and whether v is a list.
+func typeName(v any) (name string, list bool) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		list = true
+		t = t.Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	if t == nil || t.Name() == "" {
+		return "item", list
+	}
+	name, _, _ = strings.Cut(t.Name(), "[")
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes), list
+}
+
+// plural and singular name list elements: employees holds employee
+// elements, changes holds change, employeeIds holds employeeId.
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y"):
+		return strings.TrimSuffix(name, "y") + "ies"
+	case strings.HasSuffix(name, "s"):
+		return name + "es"
+	}
+	return name + "s"
+}
+
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return "item"
+}
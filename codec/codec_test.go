@@ -0,0 +1,194 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type department struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type employee struct {
+	ID          int         `json:"id"`
+	FirstName   string      `json:"firstName"`
+	ManagerID   *int        `json:"managerId,omitempty"`
+	Active      bool        `json:"active"`
+	Tags        []string    `json:"tags,omitempty"`
+	Department  *department `json:"department,omitempty"`
+	DeletedAt   *time.Time  `json:"deletedAt,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+func sample() []employee {
+	manager := 1
+	deleted := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []employee{
+		{ID: 1, FirstName: "Ada", Active: true, Tags: []string{"lead", "x: y"}, Department: &department{ID: 2, Name: "R&D"}},
+		{ID: 2, FirstName: "true", ManagerID: &manager, DeletedAt: &deleted, Description: "line one\nline \"two\""},
+	}
+}
+
+func roundTrip(t *testing.T, c Codec, v, into any) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, v); err != nil {
+		t.Fatalf("%s encode: %v", c.MediaType, err)
+	}
+	if err := c.Decode(bytes.NewReader(buf.Bytes()), into); err != nil {
+		t.Fatalf("%s decode: %v\n%s", c.MediaType, err, buf.String())
+	}
+	return buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range []Codec{JSON, XML, YAML, CSV} {
+		var list []employee
+		out := roundTrip(t, c, sample(), &list)
+		if !reflect.DeepEqual(list, sample()) {
+			t.Errorf("%s round trip =\n%+v\nwant\n%+v\nencoded:\n%s", c.MediaType, list, sample(), out)
+		}
+
+		var one employee
+		roundTrip(t, c, sample()[0], &one)
+		if !reflect.DeepEqual(one, sample()[0]) {
+			t.Errorf("%s round trip of one value = %+v", c.MediaType, one)
+		}
+	}
+}
+
+func TestXML(t *testing.T) {
+	var buf bytes.Buffer
+	XML.Encode(&buf, sample()[:1])
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<employees>
+  <employee>
+    <id>1</id>
+    <firstName>Ada</firstName>
+    <active>true</active>
+    <tags>
+      <tag>lead</tag>
+      <tag>x: y</tag>
+    </tags>
+    <department>
+      <id>2</id>
+      <name>R&amp;D</name>
+    </department>
+  </employee>
+</employees>
+`
+	if buf.String() != want {
+		t.Errorf("XML =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	var e employee
+	err := XML.Decode(strings.NewReader(`<employee><id>3</id><tags><tag>solo</tag></tags><managerId nil="true"/></employee>`), &e)
+	if err != nil || e.ID != 3 || len(e.Tags) != 1 || e.Tags[0] != "solo" || e.ManagerID != nil {
+		t.Errorf("Decode() = %+v, %v", e, err)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	var buf bytes.Buffer
+	YAML.Encode(&buf, sample())
+	want := `- id: 1
+  firstName: Ada
+  active: true
+  tags:
+    - lead
+    - "x: y"
+  department:
+    id: 2
+    name: R&D
+- id: 2
+  firstName: "true"
+  managerId: 1
+  active: false
+  deletedAt: 2024-03-01T12:00:00Z
+  description: "line one\nline \"two\""
+`
+	if buf.String() != want {
+		t.Errorf("YAML =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDecodeYAMLSyntax(t *testing.T) {
+	doc := `---
+# a comment
+id: 7   # trailing comment
+firstName: 'O''Brien'
+tags: [a, "b, c"]
+department: {id: 3, name: Sales}
+description: |
+  first
+  second
+active: True
+`
+	var e employee
+	if err := YAML.Decode(strings.NewReader(doc), &e); err != nil {
+		t.Fatal(err)
+	}
+	want := employee{ID: 7, FirstName: "O'Brien", Tags: []string{"a", "b, c"},
+		Active: true, Department: &department{ID: 3, Name: "Sales"}, Description: "first\nsecond\n"}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("Decode() = %+v, want %+v", e, want)
+	}
+
+	for _, bad := range []string{"id: 1\nid: 2\n", "id: \"open\n", "id: 1\n  name: x\n", "\tid: 1\n"} {
+		if err := YAML.Decode(strings.NewReader(bad), &e); err == nil {
+			t.Errorf("Decode(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	CSV.Encode(&buf, sample())
+	want := "id,firstName,active,tags,department.id,department.name,managerId,deletedAt,description\n" +
+		`1,Ada,true,"[""lead"",""x: y""]",2,R&D,,,` + "\n" +
+		`2,true,false,,,,1,2024-03-01T12:00:00Z,"line one` + "\n" + `line ""two"""` + "\n"
+	if buf.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	var e employee
+	if err := CSV.Decode(strings.NewReader("id,firstName\n1,A\n2,B\n"), &e); err == nil {
+		t.Error("Decode() of two rows into one value succeeded")
+	}
+}
+
+func TestDecodeReportsTypeErrors(t *testing.T) {
+	var e employee
+	for _, c := range []struct {
+		codec Codec
+		body  string
+	}{
+		{XML, "<employee><id>seven</id></employee>"},
+		{YAML, "id: seven\n"},
+		{CSV, "id\nseven\n"},
+	} {
+		if err := c.codec.Decode(strings.NewReader(c.body), &e); err == nil {
+			t.Errorf("%s Decode(%q) succeeded, want a type error", c.codec.MediaType, c.body)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for mediaType, want := range map[string]string{
+		"application/json":   JSON.MediaType,
+		"text/xml":           XML.MediaType,
+		"application/x-yaml": YAML.MediaType,
+		"text/csv":           CSV.MediaType,
+	} {
+		if c, ok := Lookup(mediaType); !ok || c.MediaType != want {
+			t.Errorf("Lookup(%s) = %s, %v", mediaType, c.MediaType, ok)
+		}
+	}
+	if _, ok := Lookup("application/pdf"); ok {
+		t.Error("Lookup(application/pdf) succeeded")
+	}
+}
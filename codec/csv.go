@@ -0,0 +1,135 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// encodeCSV writes v as a table with a header row. A list gives a row per
+// item and anything else a single row. Nested objects are flattened into
+// dotted column names such as department.name, and nested lists are
+// written as JSON in one cell. Columns appear in the order first seen.
+func encodeCSV(w io.Writer, v any) error {
+	n, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	rows := []*Node{n}
+	if n.Kind == Array {
+		rows = n.Values
+	}
+	var columns []string
+	index := make(map[string]int)
+	cells := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make(map[string]string)
+		flattenCSV(row, "", func(column, value string) {
+			if _, ok := index[column]; !ok {
+				index[column] = len(columns)
+				columns = append(columns, column)
+			}
+			cells[i][column] = value
+		})
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(columns)
+	record := make([]string, len(columns))
+	for _, row := range cells {
+		for i, column := range columns {
+			record[i] = row[column]
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func flattenCSV(n *Node, prefix string, emit func(column, value string)) {
+	switch n.Kind {
+	case Object:
+		for i, key := range n.Keys {
+			flattenCSV(n.Values[i], prefix+key+".", emit)
+		}
+		return
+	case Array:
+		var buf bytes.Buffer
+		writeJSON(&buf, n, nil)
+		emit(csvColumn(prefix), buf.String())
+	case Null:
+		emit(csvColumn(prefix), "")
+	default:
+		emit(csvColumn(prefix), n.Scalar)
+	}
+}
+
+// csvColumn names the column for a flattened path; a list of scalars has a
+// single column called value.
+func csvColumn(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+	return strings.TrimSuffix(prefix, ".")
+}
+
+// decodeCSV reads a table with a header row into v. A list takes a row per
+// item; anything else must have exactly one row. Dotted column names fill
+// nested objects and empty cells leave their field unset.
+func decodeCSV(r io.Reader, v any) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("CSV body has no header row")
+	}
+	header := records[0]
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	rows := &Node{Kind: Array}
+	for _, record := range records[1:] {
+		row := &Node{Kind: Object}
+		for i, cell := range record {
+			if cell != "" {
+				setPath(row, strings.Split(strings.TrimSpace(header[i]), "."), &Node{Kind: Text, Scalar: cell})
+			}
+		}
+		rows.Values = append(rows.Values, row)
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		return fromTree(rows, v)
+	}
+	if len(rows.Values) != 1 {
+		return errors.New("CSV body must have a header row and exactly one data row")
+	}
+	return fromTree(rows.Values[0], v)
+}
+
+// setPath sets the member at path below n, creating objects on the way.
+func setPath(n *Node, path []string, v *Node) {
+	for _, key := range path[:len(path)-1] {
+		var child *Node
+		for i, k := range n.Keys {
+			if k == key && n.Values[i].Kind == Object {
+				child = n.Values[i]
+			}
+		}
+		if child == nil {
+			child = &Node{Kind: Object}
+			n.set(key, child)
+		}
+		n = child
+	}
+	n.set(path[len(path)-1], v)
+}
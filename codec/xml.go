@@ -0,0 +1,161 @@
+package codec
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// encodeXML writes v as an XML document. The root element is named after
+// v's type, pluralized for a list; list items are named after their type
+// at the top level and after the singular of their member name below it.
+// Null values are written as empty elements with nil="true".
+func encodeXML(w io.Writer, v any) error {
+	n, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	name, list := typeName(v)
+	root, item := name, singular(name)
+	if list || n.Kind == Array {
+		root, item = plural(name), name
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := writeXMLElement(enc, root, item, n); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func writeXMLElement(enc *xml.Encoder, name, item string, n *Node) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if n.Kind == Null {
+		start.Attr = []xml.Attr{{Name: xml.Name{Local: "nil"}, Value: "true"}}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch n.Kind {
+	case Object:
+		for i, key := range n.Keys {
+			if err := writeXMLElement(enc, key, singular(key), n.Values[i]); err != nil {
+				return err
+			}
+		}
+	case Array:
+		for _, v := range n.Values {
+			if err := writeXMLElement(enc, item, singular(item), v); err != nil {
+				return err
+			}
+		}
+	case Null:
+	default:
+		if err := enc.EncodeToken(xml.CharData(n.Scalar)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName turns a member name into a valid element name.
+func xmlName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		case i == 0 && unicode.IsDigit(r):
+			b.WriteRune('_')
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// decodeXML reads an XML document into v. The root element's name is not
+// checked. Elements with children become objects, repeated elements become
+// lists, and character data takes the type of the field it is decoded into.
+func decodeXML(r io.Reader, v any) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return errors.New("XML body has no root element")
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			n, err := readXMLElement(dec, start)
+			if err != nil {
+				return err
+			}
+			return fromTree(n, v)
+		}
+	}
+}
+
+func readXMLElement(dec *xml.Decoder, start xml.StartElement) (*Node, error) {
+	null := false
+	for _, a := range start.Attr {
+		if a.Name.Local == "nil" && a.Value == "true" {
+			null = true
+		}
+	}
+	var text strings.Builder
+	obj := &Node{Kind: Object}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			obj.add(t.Name.Local, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			switch {
+			case null:
+				return &Node{Kind: Null}, nil
+			case len(obj.Keys) > 0:
+				return obj, nil
+			}
+			return &Node{Kind: Text, Scalar: strings.TrimSpace(text.String())}, nil
+		}
+	}
+}
+
+// add sets key to v, collecting repeated keys into a list.
+func (n *Node) add(key string, v *Node) {
+	for i, k := range n.Keys {
+		if k != key {
+			continue
+		}
+		if !n.Values[i].repeated {
+			n.Values[i] = &Node{Kind: Array, Values: []*Node{n.Values[i]}, repeated: true}
+		}
+		n.Values[i].Values = append(n.Values[i].Values, v)
+		return
+	}
+	n.set(key, v)
+}
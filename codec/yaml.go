@@ -0,0 +1,530 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// encodeYAML writes v as a block-style YAML document.
+func encodeYAML(w io.Writer, v any) error {
+	n, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	switch {
+	case n.Kind == Object && len(n.Keys) > 0:
+		writeYAMLObject(&buf, n, 0, "")
+	case n.Kind == Array && len(n.Values) > 0:
+		writeYAMLArray(&buf, n, 0)
+	default:
+		buf.WriteString(yamlScalar(n))
+		buf.WriteByte('\n')
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writeYAMLObject writes the members of n at indent. If first is set it
+// replaces the indentation of the first member, to start a list item.
+func writeYAMLObject(buf *bytes.Buffer, n *Node, indent int, first string) {
+	pad := strings.Repeat(" ", indent)
+	for i, key := range n.Keys {
+		if i == 0 && first != "" {
+			buf.WriteString(first)
+		} else {
+			buf.WriteString(pad)
+		}
+		buf.WriteString(yamlString(key))
+		buf.WriteByte(':')
+		writeYAMLValue(buf, n.Values[i], indent)
+	}
+}
+
+func writeYAMLArray(buf *bytes.Buffer, n *Node, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, v := range n.Values {
+		switch {
+		case v.Kind == Object && len(v.Keys) > 0:
+			writeYAMLObject(buf, v, indent+2, pad+"- ")
+		case v.Kind == Array && len(v.Values) > 0:
+			buf.WriteString(pad + "-\n")
+			writeYAMLArray(buf, v, indent+2)
+		default:
+			buf.WriteString(pad + "- " + yamlScalar(v) + "\n")
+		}
+	}
+}
+
+// writeYAMLValue writes the value of a member whose key is at indent.
+func writeYAMLValue(buf *bytes.Buffer, v *Node, indent int) {
+	switch {
+	case v.Kind == Object && len(v.Keys) > 0:
+		buf.WriteByte('\n')
+		writeYAMLObject(buf, v, indent+2, "")
+	case v.Kind == Array && len(v.Values) > 0:
+		buf.WriteByte('\n')
+		writeYAMLArray(buf, v, indent+2)
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+// yamlScalar renders a scalar, or an empty object or list in flow style.
+func yamlScalar(n *Node) string {
+	switch n.Kind {
+	case Null:
+		return "null"
+	case Bool, Number:
+		return n.Scalar
+	case Object:
+		return "{}"
+	case Array:
+		return "[]"
+	}
+	return yamlString(n.Scalar)
+}
+
+// yamlString renders s plain when YAML would read it back as the same
+// string, and double-quoted otherwise.
+func yamlString(s string) string {
+	if yamlPlain(s) {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func yamlPlain(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`~") {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlLine is one line of a YAML document. text is the content after the
+// indentation with any comment removed, and is empty for blank lines.
+type yamlLine struct {
+	num    int
+	indent int
+	raw    string
+	text   string
+}
+
+// yamlParser reads the block-style subset of YAML that encodeYAML writes,
+// plus flow collections, quoted scalars, block scalars and comments.
+// Anchors, tags and multi-line plain scalars are not supported.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// decodeYAML reads a single YAML document into v. Plain scalars take the
+// type of the field they are decoded into.
+func decodeYAML(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	p, err := newYAMLParser(string(data))
+	if err != nil {
+		return err
+	}
+	n := &Node{Kind: Null}
+	if l := p.next(); l != nil {
+		if n, err = p.block(l.indent); err != nil {
+			return err
+		}
+	}
+	if l := p.next(); l != nil {
+		return fmt.Errorf("yaml: line %d: unexpected indentation", l.num)
+	}
+	return fromTree(n, v)
+}
+
+func newYAMLParser(doc string) (*yamlParser, error) {
+	doc = strings.TrimPrefix(doc, "\ufeff")
+	p := &yamlParser{}
+	for i, raw := range strings.Split(doc, "\n") {
+		raw = strings.TrimSuffix(raw, "\r")
+		content := strings.TrimLeft(raw, " ")
+		l := yamlLine{num: i + 1, indent: len(raw) - len(content), raw: raw}
+		if strings.HasPrefix(content, "\t") && strings.TrimSpace(content) != "" {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed in indentation", l.num)
+		}
+		l.text = strings.TrimRight(stripYAMLComment(content), " \t")
+		if l.indent == 0 && (l.text == "---" || l.text == "..." || strings.HasPrefix(l.text, "%")) {
+			l.text = ""
+		}
+		p.lines = append(p.lines, l)
+	}
+	return p, nil
+}
+
+// stripYAMLComment removes a comment: a # at the start or after a space,
+// outside quotes.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" [{,:", rune(s[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// next returns the next line with content, without consuming it.
+func (p *yamlParser) next() *yamlLine {
+	for p.pos < len(p.lines) && p.lines[p.pos].text == "" {
+		p.pos++
+	}
+	if p.pos == len(p.lines) {
+		return nil
+	}
+	return &p.lines[p.pos]
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block reads the node starting at the next line, which is at indent.
+func (p *yamlParser) block(indent int) (*Node, error) {
+	l := p.next()
+	if l == nil || l.indent < indent {
+		return &Node{Kind: Null}, nil
+	}
+	if isSeqItem(l.text) {
+		return p.sequence(l.indent)
+	}
+	if _, _, ok := splitYAMLKey(l.text); ok {
+		return p.mapping(l.indent)
+	}
+	p.pos++
+	return parseYAMLScalar(l.text, l.num)
+}
+
+// nested reads the value on the lines after a key or list marker at indent.
+func (p *yamlParser) nested(indent int, seqAllowed bool) (*Node, error) {
+	l := p.next()
+	if l != nil && (l.indent > indent || (seqAllowed && l.indent == indent && isSeqItem(l.text))) {
+		return p.block(l.indent)
+	}
+	return &Node{Kind: Null}, nil
+}
+
+func (p *yamlParser) sequence(indent int) (*Node, error) {
+	n := &Node{Kind: Array}
+	for {
+		l := p.next()
+		if l == nil || l.indent != indent || !isSeqItem(l.text) {
+			return n, nil
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		var item *Node
+		var err error
+		if rest == "" {
+			p.pos++
+			item, err = p.nested(indent, false)
+		} else {
+			// Read the rest of the line as if it began a block of its own,
+			// so "- name: x" starts a mapping at the column of name.
+			l.indent += len(l.text) - len(rest)
+			l.text = rest
+			item, err = p.block(l.indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.Values = append(n.Values, item)
+	}
+}
+
+func (p *yamlParser) mapping(indent int) (*Node, error) {
+	n := &Node{Kind: Object}
+	for {
+		l := p.next()
+		if l == nil || l.indent != indent || isSeqItem(l.text) {
+			return n, nil
+		}
+		key, rest, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml: line %d: expected a key", l.num)
+		}
+		for _, k := range n.Keys {
+			if k == key {
+				return nil, fmt.Errorf("yaml: line %d: duplicate key %q", l.num, key)
+			}
+		}
+		p.pos++
+		var value *Node
+		var err error
+		switch {
+		case rest == "":
+			value, err = p.nested(indent, true)
+		case rest[0] == '|' || rest[0] == '>':
+			value = p.blockScalar(rest, indent)
+		default:
+			value, err = parseYAMLScalar(rest, l.num)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.set(key, value)
+	}
+}
+
+// blockScalar reads a literal (|) or folded (>) scalar whose key is at
+// indent.
+func (p *yamlParser) blockScalar(header string, indent int) *Node {
+	var lines []string
+	content := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if strings.TrimSpace(l.raw) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if l.indent <= indent {
+			break
+		}
+		if content < 0 {
+			content = l.indent
+		}
+		if l.indent < content {
+			break
+		}
+		lines = append(lines, l.raw[content:])
+	}
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var s string
+	if header[0] == '|' {
+		s = strings.Join(lines, "\n")
+	} else {
+		var b strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				if line == "" || lines[i-1] == "" {
+					b.WriteByte('\n')
+				} else {
+					b.WriteByte(' ')
+				}
+			}
+			b.WriteString(line)
+		}
+		s = b.String()
+	}
+	switch {
+	case strings.Contains(header, "-"):
+	case strings.Contains(header, "+"):
+		s += strings.Repeat("\n", trailing+1)
+	case len(lines) > 0:
+		s += "\n"
+	}
+	return &Node{Kind: String, Scalar: s}
+}
+
+// splitYAMLKey splits "key: value" into its key and the trimmed value.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	end := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end = closingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		end++
+	}
+	for i := end; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			k, err := parseYAMLScalar(strings.TrimSpace(text[:i]), 0)
+			if err != nil {
+				return "", "", false
+			}
+			return k.Scalar, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// closingQuote returns the index of the quote that closes the one s starts
+// with, or -1.
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+func parseYAMLScalar(s string, line int) (*Node, error) {
+	if s != "" && (s[0] == '[' || s[0] == '{') {
+		f := &yamlFlow{s: s, line: line}
+		n, err := f.value()
+		if err == nil {
+			f.space()
+			if f.i < len(f.s) {
+				err = f.errorf("unexpected %q after flow collection", f.s[f.i:])
+			}
+		}
+		return n, err
+	}
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end := closingQuote(s)
+		if end != len(s)-1 {
+			return nil, fmt.Errorf("yaml: line %d: unterminated or trailing text after quoted string", line)
+		}
+		return unquoteYAML(s, line)
+	}
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return &Node{Kind: Null}, nil
+	}
+	return &Node{Kind: Text, Scalar: s}, nil
+}
+
+func unquoteYAML(s string, line int) (*Node, error) {
+	if s[0] == '\'' {
+		return &Node{Kind: String, Scalar: strings.ReplaceAll(s[1:len(s)-1], "''", "'")}, nil
+	}
+	var v string
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		if v, err = strconv.Unquote(s); err != nil {
+			return nil, fmt.Errorf("yaml: line %d: invalid double-quoted string", line)
+		}
+	}
+	return &Node{Kind: String, Scalar: v}, nil
+}
+
+// yamlFlow parses a flow collection such as [1, 2] or {a: b}.
+type yamlFlow struct {
+	s    string
+	i    int
+	line int
+}
+
+func (f *yamlFlow) errorf(format string, args ...any) error {
+	return fmt.Errorf("yaml: line %d: "+format, append([]any{f.line}, args...)...)
+}
+
+func (f *yamlFlow) space() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) value() (*Node, error) {
+	f.space()
+	if f.i == len(f.s) {
+		return nil, f.errorf("unexpected end of flow collection")
+	}
+	switch f.s[f.i] {
+	case '[':
+		return f.collection(']')
+	case '{':
+		return f.collection('}')
+	case '"', '\'':
+		end := closingQuote(f.s[f.i:])
+		if end < 0 {
+			return nil, f.errorf("unterminated quoted string")
+		}
+		n, err := unquoteYAML(f.s[f.i:f.i+end+1], f.line)
+		f.i += end + 1
+		return n, err
+	}
+	start := f.i
+	for f.i < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.i])) &&
+		!(f.s[f.i] == ':' && (f.i+1 == len(f.s) || f.s[f.i+1] == ' ')) {
+		f.i++
+	}
+	return parseYAMLScalar(strings.TrimSpace(f.s[start:f.i]), f.line)
+}
+
+func (f *yamlFlow) collection(end byte) (*Node, error) {
+	f.i++
+	n := &Node{Kind: Array}
+	if end == '}' {
+		n.Kind = Object
+	}
+	for {
+		f.space()
+		if f.i < len(f.s) && f.s[f.i] == end {
+			f.i++
+			return n, nil
+		}
+		item, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		if end == '}' {
+			f.space()
+			if f.i == len(f.s) || f.s[f.i] != ':' {
+				return nil, f.errorf("expected ':' after key in flow mapping")
+			}
+			f.i++
+			value, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			n.set(item.Scalar, value)
+		} else {
+			n.Values = append(n.Values, item)
+		}
+		f.space()
+		if f.i < len(f.s) && f.s[f.i] == ',' {
+			f.i++
+		} else if f.i == len(f.s) || f.s[f.i] != end {
+			return nil, f.errorf("expected ',' or '%c' in flow collection", end)
+		}
+	}
+}
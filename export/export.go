@@ -0,0 +1,196 @@
+// Package export writes records as CSV, NDJSON or XLSX one row at a time,
+// so a table of any size can be streamed without holding it in memory.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"employee-maintenance/services"
+)
+
+// DefaultFormat is used when no format is asked for.
+const DefaultFormat = "csv"
+
+// Writer writes one table. Values may be strings, ints, bools or times;
+// nil writes an empty cell. Close finishes the output but does not close
+// the underlying writer.
+type Writer interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+// Format is one output format.
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	// New starts a table with the given columns on w. sheet names the
+	// table in formats that can hold several.
+	New func(w io.Writer, sheet string, columns []string) (Writer, error)
+}
+
+var formats = []Format{
+	{Name: "csv", ContentType: "text/csv; charset=utf-8", Extension: ".csv", New: newCSVWriter},
+	{Name: "ndjson", ContentType: "application/x-ndjson", Extension: ".ndjson", New: newNDJSONWriter},
+	{Name: "xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extension: ".xlsx", New: newXLSXWriter},
+}
+
+// LookupFormat returns the format called name, or DefaultFormat if name is
+// empty.
+func LookupFormat(name string) (Format, error) {
+	if name == "" {
+		name = DefaultFormat
+	}
+	for _, f := range formats {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return Format{}, fmt.Errorf("%w: format must be csv, ndjson or xlsx", services.ErrInvalidQuery)
+}
+
+// Column is one exportable field of a T.
+type Column[T any] struct {
+	Name  string
+	Value func(T) any
+}
+
+// SelectColumns returns the columns named in names, in that order, or all
+// of them if names is empty.
+func SelectColumns[T any](all []Column[T], names []string) ([]Column[T], error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+	selected := make([]Column[T], 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, c := range all {
+			if c.Name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown column %q", services.ErrInvalidQuery, name)
+		}
+	}
+	return selected, nil
+}
+
+// Names returns the names of columns.
+func Names[T any](columns []Column[T]) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Row returns the values of columns for v.
+func Row[T any](columns []Column[T], v T) []any {
+	values := make([]any, len(columns))
+	for i, c := range columns {
+		values[i] = c.Value(v)
+	}
+	return values
+}
+
+// text renders a value as a CSV cell.
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, _ string, columns []string) (Writer, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (c *csvWriter) WriteRow(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = text(v)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w       io.Writer
+	columns []string
+	buf     bytes.Buffer
+	enc     *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer, _ string, columns []string) (Writer, error) {
+	n := &ndjsonWriter{w: w, columns: columns}
+	n.enc = json.NewEncoder(&n.buf)
+	n.enc.SetEscapeHTML(false)
+	return n, nil
+}
+
+// WriteRow writes the row as a JSON object, keeping the column order.
+func (n *ndjsonWriter) WriteRow(values []any) error {
+	n.buf.Reset()
+	n.buf.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+		if err := n.value(n.columns[i]); err != nil {
+			return err
+		}
+		n.buf.WriteByte(':')
+		if err := n.value(v); err != nil {
+			return err
+		}
+	}
+	n.buf.WriteString("}\n")
+	_, err := n.w.Write(n.buf.Bytes())
+	return err
+}
+
+// value appends v to the buffer without the newline Encode adds.
+func (n *ndjsonWriter) value(v any) error {
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+	n.buf.Truncate(n.buf.Len() - 1)
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
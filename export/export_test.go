@@ -0,0 +1,141 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"employee-maintenance/services"
+)
+
+var rows = [][]any{
+	{1, "Ada, Countess", true, nil, 0.5},
+	{2, `<Grace "Amazing">`, false, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), 1.0},
+}
+
+func write(t *testing.T, format string) []byte {
+	t.Helper()
+	f, err := LookupFormat(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := f.New(&buf, "People", []string{"id", "name", "active", "deletedAt", "fte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	want := "id,name,active,deletedAt,fte\n" +
+		"1,\"Ada, Countess\",true,,0.5\n" +
+		"2,\"<Grace \"\"Amazing\"\">\",false,2024-03-01T12:00:00Z,1\n"
+	if got := string(write(t, "csv")); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	want := `{"id":1,"name":"Ada, Countess","active":true,"deletedAt":null,"fte":0.5}` + "\n" +
+		`{"id":2,"name":"<Grace \"Amazing\">","active":false,"deletedAt":"2024-03-01T12:00:00Z","fte":1}` + "\n"
+	if got := string(write(t, "ndjson")); got != want {
+		t.Errorf("NDJSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	data := write(t, "xlsx")
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+		if err := xml.Unmarshal(parts[f.Name], new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed: %v", f.Name, err)
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/worksheets/sheet1.xml"} {
+		if parts[name] == nil {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(sheet.Rows))
+	}
+	first := sheet.Rows[1].Cells
+	if len(first) != 4 || first[0].Value != "1" || first[1].Inline != "Ada, Countess" || first[2].Type != "b" || first[3].Value != "0.5" {
+		t.Errorf("row 2 = %+v", first)
+	}
+	if got := sheet.Rows[2].Cells[1].Inline; got != `<Grace "Amazing">` {
+		t.Errorf("B3 = %q", got)
+	}
+	if got := sheet.Rows[2].Cells[3].Ref; got != "D3" {
+		t.Errorf("fourth cell of row 3 is %s, want D3", got)
+	}
+	if !strings.Contains(string(parts["xl/workbook.xml"]), `name="People"`) {
+		t.Errorf("workbook does not name the sheet People")
+	}
+}
+
+func TestColumnLetters(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnLetters(i); got != want {
+			t.Errorf("columnLetters(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	all := []Column[int]{
+		{Name: "n", Value: func(v int) any { return v }},
+		{Name: "double", Value: func(v int) any { return 2 * v }},
+	}
+	cols, err := SelectColumns(all, []string{"double", "n"})
+	if err != nil || len(cols) != 2 {
+		t.Fatalf("SelectColumns() = %v, %v", Names(cols), err)
+	}
+	if row := Row(cols, 3); row[0] != 6 || row[1] != 3 {
+		t.Errorf("Row() = %v, want [6 3]", row)
+	}
+	if _, err := SelectColumns(all, []string{"salary"}); !errors.Is(err, services.ErrInvalidQuery) {
+		t.Errorf("SelectColumns(salary) error = %v, want %v", err, services.ErrInvalidQuery)
+	}
+	if _, err := LookupFormat("pdf"); !errors.Is(err, services.ErrInvalidQuery) {
+		t.Errorf("LookupFormat(pdf) error = %v, want %v", err, services.ErrInvalidQuery)
+	}
+}
@@ -0,0 +1,116 @@
+// Package idempotency remembers the response to a request sent with an
+// idempotency key, so that a retry of the same request gets the same
+// response instead of repeating its effect.
+package idempotency
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long a response is kept when no TTL is given.
+const DefaultTTL = 24 * time.Hour
+
+var (
+	ErrKeyReused  = errors.New("idempotency key was already used for a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// Response is a stored response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type entry struct {
+	fingerprint string
+	response    *Response
+	expires     time.Time
+}
+
+// Store holds the responses for keys until they expire. Keys are scoped by
+// the caller that used them, so two callers can use the same key. It is
+// safe for concurrent use.
+type Store struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*entry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, entries: make(map[string]*entry), now: time.Now}
+}
+
+// SetTTL changes how long responses stored from now on are kept.
+func (s *Store) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+func scoped(caller, key string) string {
+	return caller + "\x00" + key
+}
+
+// Begin claims key for a request identified by fingerprint, typically a
+// hash of its method, target and body. If the key has completed it returns
+// the stored response. It returns ErrKeyReused if the key was used with a
+// different fingerprint and ErrInProgress if a request with the key has
+// not finished yet. Otherwise the key is claimed and Begin returns nil, nil;
+// the caller must then call Complete or Release.
+func (s *Store) Begin(caller, key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	k := scoped(caller, key)
+	if e, ok := s.entries[k]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, ErrKeyReused
+		case e.response == nil:
+			return nil, ErrInProgress
+		}
+		return e.response, nil
+	}
+	s.entries[k] = &entry{fingerprint: fingerprint, expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+// Complete stores the response for a key claimed with Begin.
+func (s *Store) Complete(caller, key string, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[scoped(caller, key)]; ok {
+		e.response = &resp
+		e.expires = s.now().Add(s.ttl)
+	}
+}
+
+// Release gives up a key claimed with Begin without storing a response, so
+// the request can be retried.
+func (s *Store) Release(caller, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[scoped(caller, key)]; ok && e.response == nil {
+		delete(s.entries, scoped(caller, key))
+	}
+}
+
+// sweep drops expired entries, at most once a minute.
+func (s *Store) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for k, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.nextSweep = now.Add(time.Minute)
+}
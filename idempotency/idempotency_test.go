@@ -0,0 +1,54 @@
+package idempotency
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestStore(ttl time.Duration) (*Store, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStore(ttl)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestStore_ReplaysCompletedResponse(t *testing.T) {
+	s, _ := newTestStore(time.Hour)
+	if resp, err := s.Begin("alice", "k1", "a"); resp != nil || err != nil {
+		t.Fatalf("first Begin() = %v, %v", resp, err)
+	}
+	if _, err := s.Begin("alice", "k1", "a"); !errors.Is(err, ErrInProgress) {
+		t.Errorf("Begin() while in progress error = %v, want %v", err, ErrInProgress)
+	}
+	s.Complete("alice", "k1", Response{Status: 201, Body: []byte("created")})
+
+	resp, err := s.Begin("alice", "k1", "a")
+	if err != nil || resp == nil || resp.Status != 201 || string(resp.Body) != "created" {
+		t.Errorf("Begin() after Complete = %+v, %v", resp, err)
+	}
+	if _, err := s.Begin("alice", "k1", "b"); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("Begin() with another fingerprint error = %v, want %v", err, ErrKeyReused)
+	}
+	if resp, err := s.Begin("bob", "k1", "b"); resp != nil || err != nil {
+		t.Errorf("Begin() by another caller = %v, %v, want a fresh claim", resp, err)
+	}
+}
+
+func TestStore_ReleaseAndExpiry(t *testing.T) {
+	s, now := newTestStore(time.Hour)
+	s.Begin("alice", "k1", "a")
+	s.Release("alice", "k1")
+	if resp, err := s.Begin("alice", "k1", "b"); resp != nil || err != nil {
+		t.Fatalf("Begin() after Release = %v, %v", resp, err)
+	}
+	s.Complete("alice", "k1", Response{Status: 200})
+
+	*now = now.Add(time.Hour)
+	if resp, err := s.Begin("alice", "k1", "c"); resp != nil || err != nil {
+		t.Errorf("Begin() after the TTL = %v, %v, want a fresh claim", resp, err)
+	}
+	if len(s.entries) != 1 {
+		t.Errorf("store holds %d entries, want 1", len(s.entries))
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records one change made through the services. Time is when the
+// change was recorded, EffectiveTime when it took effect in the organization;
+// together they make the audit log a bitemporal record of every change.
+// DecidedAt is set for a scheduled change to when it was decided, which is
+// before it was recorded.
+type AuditEntry struct {
+	ID            int       `json:"id"`
+	Time          time.Time `json:"time"`
+	EffectiveTime time.Time `json:"effectiveTime"`
+	DecidedAt     time.Time `json:"decidedAt,omitzero"`
+	Actor         string    `json:"actor"`
+	RequestID     string    `json:"requestId,omitempty"`
+	Entity        string    `json:"entity"`
+	EntityID      int       `json:"entityId"`
+	Operation     string    `json:"operation"`
+	// Before and After hold the full record on either side of the change;
+	// Before is empty for a create and After for a purge.
+	Before  json.RawMessage `json:"before,omitempty"`
+	After   json.RawMessage `json:"after,omitempty"`
+	Changes []FieldChange   `json:"changes,omitempty"`
+}
+
+// FieldChange is one field that differs between an entry's Before and After.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
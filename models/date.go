@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is how a Date is written.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day, without a time of day or time zone. The zero Date
+// means no date and is written as null.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the day t falls on in its own location.
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q: want YYYY-MM-DD", s)
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("date must be a string in YYYY-MM-DD form")
+	}
+	if s == nil || *s == "" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(*s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
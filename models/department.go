@@ -1,6 +1,19 @@
 package models
 
+import "time"
+
 type Department struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
+	// ParentID is the department this one belongs to; zero means it is at
+	// the top of the hierarchy.
+	ParentID int `json:"parentId"`
+	// HeadcountBudget is the total FTE of positions the department may
+	// have; nil means it has no budget.
+	HeadcountBudget *float64 `json:"headcountBudget,omitempty"`
+	// Version is incremented by the service on every write.
+	Version int `json:"version"`
+	// DeletedAt is set when the department is deleted. Deleted departments
+	// are kept as tombstones, hidden from normal reads, until they are purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
@@ -1,9 +1,41 @@
 package models
 
+import "time"
+
 type Employee struct {
-	ID         int        `json:"id"`
-	FirstName  string     `json:"firstName"`
-	LastName   string     `json:"lastName"`
-	Email      string     `json:"email"`
-	Department Department `json:"department"`
+	ID           int    `json:"id"`
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	Email        string `json:"email"`
+	Title        string `json:"title,omitempty"`
+	DepartmentID int    `json:"departmentId"`
+	// ManagerID is the employee this one reports to; zero means none.
+	ManagerID int `json:"managerId"`
+	// HireDate is the first day of the current period of employment.
+	HireDate Date `json:"hireDate,omitzero"`
+	// Status is where the employee is in their employment. Records stored
+	// before it existed are active.
+	Status EmploymentStatus `json:"status"`
+	// TerminationDate and TerminationReason are set when the employee is
+	// terminated and cleared when they are rehired.
+	TerminationDate   Date   `json:"terminationDate,omitzero"`
+	TerminationReason string `json:"terminationReason,omitempty"`
+	// Version is incremented by the service on every write.
+	Version int `json:"version"`
+	// DeletedAt is set when the employee is deleted. Deleted employees are
+	// kept as tombstones, hidden from normal reads, until they are purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Department is resolved from DepartmentID at read time when a caller asks
+	// for it; it is never stored.
+	Department *Department `json:"department,omitempty"`
 }
+
+// EmploymentStatus is the stage of an employee's employment.
+type EmploymentStatus string
+
+const (
+	StatusCandidate  EmploymentStatus = "candidate"
+	StatusActive     EmploymentStatus = "active"
+	StatusOnLeave    EmploymentStatus = "on_leave"
+	StatusTerminated EmploymentStatus = "terminated"
+)
@@ -0,0 +1,22 @@
+package models
+
+// Position is a budgeted role in a department, either filled by an employee
+// or open.
+type Position struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Level        string `json:"level,omitempty"`
+	DepartmentID int    `json:"departmentId"`
+	// FTE is the share of a full-time role the position accounts for,
+	// greater than 0 and at most 1.
+	FTE float64 `json:"fte"`
+	// EmployeeID is the employee filling the position; zero means it is open.
+	EmployeeID int `json:"employeeId"`
+	// Version is incremented by the service on every write.
+	Version int `json:"version"`
+}
+
+// Open reports whether the position has no employee.
+func (p Position) Open() bool {
+	return p.EmployeeID == 0
+}
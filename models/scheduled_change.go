@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ScheduledChange is a change to an employee decided now to take effect
+// later. Only the fields that are set change; a zero ID clears a
+// department or manager and an empty title clears the title.
+type ScheduledChange struct {
+	ID           int          `json:"id"`
+	EmployeeID   int          `json:"employeeId"`
+	EffectiveAt  time.Time    `json:"effectiveAt"`
+	DepartmentID *int         `json:"departmentId,omitempty"`
+	ManagerID    *int         `json:"managerId,omitempty"`
+	Title        *string      `json:"title,omitempty"`
+	Status       ChangeStatus `json:"status"`
+	// CreatedAt and CreatedBy record when and by whom the change was
+	// decided, and RequestID the request that decided it.
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	RequestID string    `json:"requestId,omitempty"`
+	// AppliedAt is set when the change was made or failed, CancelledAt
+	// and CancelledBy when it was cancelled.
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
+	CancelledBy string     `json:"cancelledBy,omitempty"`
+	// Error says why a failed change could not be made.
+	Error string `json:"error,omitempty"`
+}
+
+// ChangeStatus is where a scheduled change is in its life.
+type ChangeStatus string
+
+const (
+	ChangePending   ChangeStatus = "pending"
+	ChangeApplied   ChangeStatus = "applied"
+	ChangeCancelled ChangeStatus = "cancelled"
+	ChangeFailed    ChangeStatus = "failed"
+)
@@ -0,0 +1,251 @@
+// Package orgchart draws the department and employee hierarchy as Graphviz
+// DOT, Mermaid or SVG.
+package orgchart
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"employee-maintenance/models"
+	"employee-maintenance/services"
+)
+
+const (
+	KindDepartment = "department"
+	KindEmployee   = "employee"
+)
+
+// Node is one box of the chart. A department's children are its child
+// departments followed by the employees at the top of its reporting lines;
+// an employee's children are the reports that sit in the same part of the
+// chart.
+type Node struct {
+	ID       string
+	Kind     string
+	Label    string
+	Children []*Node
+}
+
+// Link is a reporting line the tree does not show, because the employee sits
+// in a different department from their manager.
+type Link struct {
+	From string
+	To   string
+}
+
+// Chart is a forest of nodes plus the reporting lines that cross it.
+type Chart struct {
+	Roots []*Node
+	Links []Link
+}
+
+// Root selects the part of the organisation to draw. The zero Root draws all
+// of it. A Root with an ID but no Kind names an employee or a department by
+// ID alone, and Build works out which.
+type Root struct {
+	Kind string
+	ID   int
+}
+
+// ParseRoot reads a root of the form {id}, employee:{id} or department:{id}.
+// An empty string is the zero Root.
+func ParseRoot(s string) (Root, error) {
+	if s == "" {
+		return Root{}, nil
+	}
+	kind, id, found := strings.Cut(s, ":")
+	if !found {
+		kind, id = "", s
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil || n <= 0 || (found && kind != KindEmployee && kind != KindDepartment) {
+		return Root{}, fmt.Errorf("%w: root must be {id}, employee:{id} or department:{id}", services.ErrInvalidQuery)
+	}
+	return Root{Kind: kind, ID: n}, nil
+}
+
+func (r Root) String() string {
+	if r.Kind == "" {
+		if r.ID == 0 {
+			return ""
+		}
+		return strconv.Itoa(r.ID)
+	}
+	return fmt.Sprintf("%s:%d", r.Kind, r.ID)
+}
+
+// Build lays out the departments and employees as a chart starting at root.
+// An employee root draws their reports across departments; a department root
+// draws it with its child departments and their employees; the zero Root
+// draws every top-level department, then employees without a department. A
+// root given by ID alone must match exactly one employee or department.
+func Build(departments []models.Department, employees []models.Employee, root Root) (*Chart, error) {
+	b := newBuilder(departments, employees)
+	if root.Kind == "" && root.ID != 0 {
+		kind, err := b.kindOf(root.ID)
+		if err != nil {
+			return nil, err
+		}
+		root.Kind = kind
+	}
+	switch root.Kind {
+	case "":
+		var roots []*Node
+		for _, d := range b.departments {
+			if _, exists := b.deptByID[d.ParentID]; !exists {
+				roots = append(roots, b.department(d))
+			}
+		}
+		roots = append(roots, b.heads(0)...)
+		b.chart.Roots = roots
+	case KindDepartment:
+		d, exists := b.deptByID[root.ID]
+		if !exists {
+			return nil, fmt.Errorf("%w: %d", services.ErrDepartmentNotFound, root.ID)
+		}
+		b.chart.Roots = []*Node{b.department(d)}
+	case KindEmployee:
+		e, exists := b.empByID[root.ID]
+		if !exists {
+			return nil, fmt.Errorf("%w: %d", services.ErrEmployeeNotFound, root.ID)
+		}
+		b.acrossDepartments = true
+		b.chart.Roots = []*Node{b.employee(e)}
+	default:
+		return nil, fmt.Errorf("%w: unknown root kind %q", services.ErrInvalidQuery, root.Kind)
+	}
+	b.link()
+	return &b.chart, nil
+}
+
+// kindOf says whether id, given without a kind, names an employee or a
+// department.
+func (b *builder) kindOf(id int) (string, error) {
+	_, isEmployee := b.empByID[id]
+	_, isDepartment := b.deptByID[id]
+	switch {
+	case isEmployee && isDepartment:
+		return "", fmt.Errorf("%w: %d is both an employee and a department; use employee:%d or department:%d",
+			services.ErrInvalidQuery, id, id, id)
+	case isEmployee:
+		return KindEmployee, nil
+	case isDepartment:
+		return KindDepartment, nil
+	default:
+		return "", fmt.Errorf("%w: no employee or department has ID %d", services.ErrInvalidQuery, id)
+	}
+}
+
+type builder struct {
+	departments []models.Department
+	employees   []models.Employee
+	deptByID    map[int]models.Department
+	empByID     map[int]models.Employee
+	subDepts    map[int][]models.Department
+	reports     map[int][]models.Employee
+	byDept      map[int][]models.Employee
+	// acrossDepartments nests reports under their manager wherever they
+	// work, rather than under their own department.
+	acrossDepartments bool
+	drawn             map[string]bool
+	chart             Chart
+}
+
+func newBuilder(departments []models.Department, employees []models.Employee) *builder {
+	b := &builder{
+		departments: sortedDepartments(departments),
+		employees:   sortedEmployees(employees),
+		deptByID:    make(map[int]models.Department, len(departments)),
+		empByID:     make(map[int]models.Employee, len(employees)),
+		subDepts:    make(map[int][]models.Department),
+		reports:     make(map[int][]models.Employee),
+		byDept:      make(map[int][]models.Employee),
+		drawn:       make(map[string]bool),
+	}
+	for _, d := range b.departments {
+		b.deptByID[d.ID] = d
+		b.subDepts[d.ParentID] = append(b.subDepts[d.ParentID], d)
+	}
+	for _, e := range b.employees {
+		b.empByID[e.ID] = e
+		b.reports[e.ManagerID] = append(b.reports[e.ManagerID], e)
+		b.byDept[e.DepartmentID] = append(b.byDept[e.DepartmentID], e)
+	}
+	return b
+}
+
+func (b *builder) department(d models.Department) *Node {
+	n := &Node{ID: departmentNodeID(d.ID), Kind: KindDepartment, Label: d.Name}
+	b.drawn[n.ID] = true
+	for _, child := range b.subDepts[d.ID] {
+		if !b.drawn[departmentNodeID(child.ID)] {
+			n.Children = append(n.Children, b.department(child))
+		}
+	}
+	n.Children = append(n.Children, b.heads(d.ID)...)
+	return n
+}
+
+// heads returns the employees of department deptID whose manager is not in
+// the same department, each with their reports below them.
+func (b *builder) heads(deptID int) []*Node {
+	var nodes []*Node
+	for _, e := range b.byDept[deptID] {
+		if m, exists := b.empByID[e.ManagerID]; exists && m.DepartmentID == deptID {
+			continue
+		}
+		if !b.drawn[employeeNodeID(e.ID)] {
+			nodes = append(nodes, b.employee(e))
+		}
+	}
+	return nodes
+}
+
+func (b *builder) employee(e models.Employee) *Node {
+	n := &Node{ID: employeeNodeID(e.ID), Kind: KindEmployee, Label: strings.TrimSpace(e.FirstName + " " + e.LastName)}
+	b.drawn[n.ID] = true
+	for _, r := range b.reports[e.ID] {
+		if !b.acrossDepartments && r.DepartmentID != e.DepartmentID {
+			continue
+		}
+		if !b.drawn[employeeNodeID(r.ID)] {
+			n.Children = append(n.Children, b.employee(r))
+		}
+	}
+	return n
+}
+
+// link records the reporting lines between drawn employees that the tree
+// leaves out.
+func (b *builder) link() {
+	if b.acrossDepartments {
+		return
+	}
+	for _, e := range b.employees {
+		m, exists := b.empByID[e.ManagerID]
+		if !exists || m.DepartmentID == e.DepartmentID {
+			continue
+		}
+		from, to := employeeNodeID(m.ID), employeeNodeID(e.ID)
+		if b.drawn[from] && b.drawn[to] {
+			b.chart.Links = append(b.chart.Links, Link{From: from, To: to})
+		}
+	}
+}
+
+func departmentNodeID(id int) string { return "d" + strconv.Itoa(id) }
+func employeeNodeID(id int) string   { return "e" + strconv.Itoa(id) }
+
+func sortedDepartments(depts []models.Department) []models.Department {
+	result := append([]models.Department(nil), depts...)
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func sortedEmployees(emps []models.Employee) []models.Employee {
+	result := append([]models.Employee(nil), emps...)
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
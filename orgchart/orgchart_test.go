@@ -0,0 +1,189 @@
+package orgchart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"employee-maintenance/models"
+	"employee-maintenance/services"
+)
+
+// org is Engineering (1) with Platform (2) below it, and Sales (3). Ada heads
+// Engineering; Grace reports to her in Engineering and Linus reports to her
+// from Platform. Alan has no department.
+func org() ([]models.Department, []models.Employee) {
+	depts := []models.Department{
+		{ID: 3, Name: "Sales"},
+		{ID: 1, Name: "Engineering"},
+		{ID: 2, Name: "Platform", ParentID: 1},
+	}
+	emps := []models.Employee{
+		{ID: 1, FirstName: "Ada", LastName: "Lovelace", DepartmentID: 1},
+		{ID: 2, FirstName: "Grace", LastName: "Hopper", DepartmentID: 1, ManagerID: 1},
+		{ID: 3, FirstName: "Linus", LastName: "Torvalds", DepartmentID: 2, ManagerID: 1},
+		{ID: 4, FirstName: "Alan", LastName: "Turing"},
+	}
+	return depts, emps
+}
+
+// shape renders a node tree as id(child child ...) for comparison.
+func shape(nodes []*Node) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.ID
+		if len(n.Children) > 0 {
+			parts[i] += "(" + shape(n.Children) + ")"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBuild(t *testing.T) {
+	depts, emps := org()
+	tests := []struct {
+		root  Root
+		want  string
+		links []Link
+	}{
+		{Root{}, "d1(d2(e3) e1(e2)) d3 e4", []Link{{From: "e1", To: "e3"}}},
+		{Root{Kind: KindDepartment, ID: 2}, "d2(e3)", nil},
+		{Root{Kind: KindEmployee, ID: 1}, "e1(e2 e3)", nil},
+		{Root{ID: 4}, "e4", nil},
+	}
+	for _, tt := range tests {
+		chart, err := Build(depts, emps, tt.root)
+		if err != nil {
+			t.Fatalf("Build(%v) error = %v", tt.root, err)
+		}
+		if got := shape(chart.Roots); got != tt.want {
+			t.Errorf("Build(%v) = %s, want %s", tt.root, got, tt.want)
+		}
+		if len(chart.Links) != len(tt.links) || (len(tt.links) > 0 && chart.Links[0] != tt.links[0]) {
+			t.Errorf("Build(%v) links = %v, want %v", tt.root, chart.Links, tt.links)
+		}
+	}
+}
+
+func TestBuildUnknownRoot(t *testing.T) {
+	depts, emps := org()
+	if _, err := Build(depts, emps, Root{Kind: KindEmployee, ID: 99}); !errors.Is(err, services.ErrEmployeeNotFound) {
+		t.Errorf("Build(employee:99) error = %v, want %v", err, services.ErrEmployeeNotFound)
+	}
+	if _, err := Build(depts, emps, Root{Kind: KindDepartment, ID: 99}); !errors.Is(err, services.ErrDepartmentNotFound) {
+		t.Errorf("Build(department:99) error = %v, want %v", err, services.ErrDepartmentNotFound)
+	}
+	// 1 is both Ada and Engineering; 99 is neither.
+	for _, id := range []int{1, 99} {
+		if _, err := Build(depts, emps, Root{ID: id}); !errors.Is(err, services.ErrInvalidQuery) {
+			t.Errorf("Build(%d) error = %v, want %v", id, err, services.ErrInvalidQuery)
+		}
+	}
+}
+
+func TestParseRoot(t *testing.T) {
+	for _, s := range []string{"", "5", "employee:5", "department:2"} {
+		root, err := ParseRoot(s)
+		if err != nil || root.String() != s {
+			t.Errorf("ParseRoot(%q) = %v, %v", s, root, err)
+		}
+	}
+	for _, s := range []string{"0", "team:5", "employee:x", "department:0", ":5"} {
+		if _, err := ParseRoot(s); !errors.Is(err, services.ErrInvalidQuery) {
+			t.Errorf("ParseRoot(%q) error = %v, want %v", s, err, services.ErrInvalidQuery)
+		}
+	}
+}
+
+func TestLookupFormat(t *testing.T) {
+	f, err := LookupFormat("")
+	if err != nil || f.Name != DefaultFormat {
+		t.Errorf("LookupFormat(\"\") = %q, %v, want %q", f.Name, err, DefaultFormat)
+	}
+	if _, err := LookupFormat("png"); !errors.Is(err, services.ErrInvalidQuery) {
+		t.Errorf("LookupFormat(png) error = %v, want %v", err, services.ErrInvalidQuery)
+	}
+}
+
+func render(t *testing.T, format string, chart *Chart) string {
+	t.Helper()
+	f, err := LookupFormat(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf, chart); err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestWriteDOT(t *testing.T) {
+	depts, emps := org()
+	emps[3].FirstName = `Alan "Prof"`
+	chart, _ := Build(depts, emps, Root{})
+	out := render(t, "dot", chart)
+	for _, want := range []string{"digraph orgchart {", "  d1 -> d2;", "  e1 -> e2;", "  e1 -> e3 [style=dashed];", `e4 [label="Alan \"Prof\" Turing"];`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	depts, emps := org()
+	chart, _ := Build(depts, emps, Root{})
+	out := render(t, "mermaid", chart)
+	for _, want := range []string{"flowchart TD", `  d1["Engineering"]`, "  d2 --> e3", "  e1 -.-> e3", "  class d1,d2,d3 department"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	depts, emps := org()
+	emps[0].LastName = "<Lovelace & co>"
+	chart, _ := Build(depts, emps, Root{})
+	out := render(t, "svg", chart)
+
+	var doc struct {
+		Groups []struct {
+			ID   string `xml:"id,attr"`
+			Rect struct {
+				X float64 `xml:"x,attr"`
+				Y float64 `xml:"y,attr"`
+				W float64 `xml:"width,attr"`
+			} `xml:"rect"`
+			Text string `xml:"text"`
+		} `xml:"g"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("SVG is not well-formed: %v\n%s", err, out)
+	}
+	type box struct{ x, y, w float64 }
+	boxes := make(map[string]box)
+	for _, g := range doc.Groups {
+		if g.ID != "" {
+			boxes[g.ID] = box{g.Rect.X, g.Rect.Y, g.Rect.W}
+		}
+		if g.ID == "e1" && g.Text != "Ada <Lovelace & co>" {
+			t.Errorf("e1 text = %q", g.Text)
+		}
+	}
+	if len(boxes) != 7 {
+		t.Fatalf("got %d boxes, want 7", len(boxes))
+	}
+	for a, ba := range boxes {
+		for b, bb := range boxes {
+			if a < b && ba.y == bb.y && ba.x < bb.x+bb.w && bb.x < ba.x+ba.w {
+				t.Errorf("boxes %s and %s overlap", a, b)
+			}
+		}
+	}
+	if boxes["d2"].y <= boxes["d1"].y {
+		t.Errorf("d2 is not below its parent d1")
+	}
+}
@@ -0,0 +1,114 @@
+package orgchart
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"employee-maintenance/services"
+)
+
+// DefaultFormat is used when no format is asked for.
+const DefaultFormat = "svg"
+
+// Format is one way of drawing a chart.
+type Format struct {
+	Name        string
+	ContentType string
+	Write       func(w io.Writer, c *Chart) error
+}
+
+var formats = []Format{
+	{Name: "dot", ContentType: "text/vnd.graphviz; charset=utf-8", Write: WriteDOT},
+	{Name: "mermaid", ContentType: "text/vnd.mermaid; charset=utf-8", Write: WriteMermaid},
+	{Name: "svg", ContentType: "image/svg+xml", Write: WriteSVG},
+}
+
+// LookupFormat returns the format called name, or DefaultFormat if name is
+// empty.
+func LookupFormat(name string) (Format, error) {
+	if name == "" {
+		name = DefaultFormat
+	}
+	for _, f := range formats {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return Format{}, fmt.Errorf("%w: format must be dot, mermaid or svg", services.ErrInvalidQuery)
+}
+
+// walk calls fn for every node, parents before their children.
+func (c *Chart) walk(fn func(parent, n *Node)) {
+	var visit func(parent, n *Node)
+	visit = func(parent, n *Node) {
+		fn(parent, n)
+		for _, child := range n.Children {
+			visit(n, child)
+		}
+	}
+	for _, root := range c.Roots {
+		visit(nil, root)
+	}
+}
+
+// WriteDOT writes the chart as a Graphviz digraph.
+func WriteDOT(w io.Writer, c *Chart) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph orgchart {")
+	fmt.Fprintln(bw, `  node [shape=box, fontname="Helvetica"];`)
+	c.walk(func(_, n *Node) {
+		attrs := ""
+		if n.Kind == KindDepartment {
+			attrs = `, style=filled, fillcolor="#dde7f5"`
+		}
+		fmt.Fprintf(bw, "  %s [label=%s%s];\n", n.ID, dotQuote(n.Label), attrs)
+	})
+	c.walk(func(parent, n *Node) {
+		if parent != nil {
+			fmt.Fprintf(bw, "  %s -> %s;\n", parent.ID, n.ID)
+		}
+	})
+	for _, l := range c.Links {
+		fmt.Fprintf(bw, "  %s -> %s [style=dashed];\n", l.From, l.To)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteMermaid writes the chart as a Mermaid flowchart.
+func WriteMermaid(w io.Writer, c *Chart) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart TD")
+	var departments []string
+	c.walk(func(_, n *Node) {
+		fmt.Fprintf(bw, "  %s[%s]\n", n.ID, mermaidQuote(n.Label))
+		if n.Kind == KindDepartment {
+			departments = append(departments, n.ID)
+		}
+	})
+	c.walk(func(parent, n *Node) {
+		if parent != nil {
+			fmt.Fprintf(bw, "  %s --> %s\n", parent.ID, n.ID)
+		}
+	})
+	for _, l := range c.Links {
+		fmt.Fprintf(bw, "  %s -.-> %s\n", l.From, l.To)
+	}
+	if len(departments) > 0 {
+		fmt.Fprintln(bw, "  classDef department fill:#dde7f5")
+		fmt.Fprintf(bw, "  class %s department\n", strings.Join(departments, ","))
+	}
+	return bw.Flush()
+}
+
+// mermaidQuote quotes a label, writing characters Mermaid would read as
+// markup as entity codes.
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", " ").Replace(s) + `"`
+}
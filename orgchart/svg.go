@@ -0,0 +1,127 @@
+package orgchart
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Sizes used by the SVG layout, in pixels. Text is not measured; each
+// character is assumed to be charWidth wide.
+const (
+	boxHeight   = 36.0
+	minBoxWidth = 100.0
+	charWidth   = 7.5
+	boxPadding  = 16.0
+	hGap        = 20.0
+	vGap        = 40.0
+	margin      = 20.0
+)
+
+// placed is a node with its box position. x and y are the top-left corner.
+type placed struct {
+	node *Node
+	x, y float64
+	w    float64
+}
+
+// svgLayout places each subtree in a band as wide as the subtree needs,
+// centring parents over their children, so boxes never overlap.
+type svgLayout struct {
+	widths map[*Node]float64
+	boxes  []placed
+	edges  [][2]int
+	width  float64
+	height float64
+}
+
+func boxWidth(n *Node) float64 {
+	return max(minBoxWidth, float64(utf8.RuneCountInString(n.Label))*charWidth+2*boxPadding)
+}
+
+func (l *svgLayout) subtreeWidth(n *Node) float64 {
+	if w, ok := l.widths[n]; ok {
+		return w
+	}
+	children := 0.0
+	for i, c := range n.Children {
+		if i > 0 {
+			children += hGap
+		}
+		children += l.subtreeWidth(c)
+	}
+	w := max(boxWidth(n), children)
+	l.widths[n] = w
+	return w
+}
+
+// place puts n in the band starting at left and returns its box index.
+func (l *svgLayout) place(n *Node, left float64, depth int) int {
+	width := l.subtreeWidth(n)
+	centre := left + width/2
+	w := boxWidth(n)
+	y := margin + float64(depth)*(boxHeight+vGap)
+	idx := len(l.boxes)
+	l.boxes = append(l.boxes, placed{node: n, x: centre - w/2, y: y, w: w})
+	l.width = max(l.width, left+width)
+	l.height = max(l.height, y+boxHeight)
+
+	children := -hGap
+	for _, c := range n.Children {
+		children += l.subtreeWidth(c) + hGap
+	}
+	x := centre - children/2
+	for _, c := range n.Children {
+		child := l.place(c, x, depth+1)
+		l.edges = append(l.edges, [2]int{idx, child})
+		x += l.subtreeWidth(c) + hGap
+	}
+	return idx
+}
+
+func layoutChart(c *Chart) *svgLayout {
+	l := &svgLayout{widths: make(map[*Node]float64), width: margin, height: margin}
+	left := margin
+	for _, root := range c.Roots {
+		l.place(root, left, 0)
+		left += l.subtreeWidth(root) + hGap
+	}
+	l.width += margin
+	l.height += margin
+	return l
+}
+
+// WriteSVG lays the chart out top-down and writes it as a standalone SVG
+// image. Reporting lines across departments are not drawn.
+func WriteSVG(w io.Writer, c *Chart) error {
+	l := layoutChart(c)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n",
+		l.width, l.height, l.width, l.height)
+	fmt.Fprintln(bw, `<g fill="none" stroke="#666">`)
+	for _, e := range l.edges {
+		p, c := l.boxes[e[0]], l.boxes[e[1]]
+		px, py := p.x+p.w/2, p.y+boxHeight
+		cx, cy := c.x+c.w/2, c.y
+		mid := py + vGap/2
+		fmt.Fprintf(bw, `<path d="M%g %gV%gH%gV%g"/>`+"\n", px, py, mid, cx, cy)
+	}
+	fmt.Fprintln(bw, `</g>`)
+	for _, b := range l.boxes {
+		fill := "#ffffff"
+		if b.node.Kind == KindDepartment {
+			fill = "#dde7f5"
+		}
+		fmt.Fprintf(bw, `<g id="%s"><rect x="%g" y="%g" width="%g" height="%g" rx="4" fill="%s" stroke="#333"/>`,
+			b.node.ID, b.x, b.y, b.w, boxHeight, fill)
+		fmt.Fprintf(bw, `<text x="%g" y="%g" text-anchor="middle" dominant-baseline="central">`, b.x+b.w/2, b.y+boxHeight/2)
+		if err := xml.EscapeText(bw, []byte(b.node.Label)); err != nil {
+			return err
+		}
+		fmt.Fprintln(bw, `</text></g>`)
+	}
+	fmt.Fprintln(bw, `</svg>`)
+	return bw.Flush()
+}
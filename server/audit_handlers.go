@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"employee-maintenance/services"
+)
+
+func (s *Server) RegisterAuditRoutes() {
+	s.handle("GET /audit", s.getAudit)
+	s.handle("GET /employees/{id}/history", s.getEmployeeHistory)
+	s.handle("GET /departments/{id}/history", s.getDepartmentHistory)
+}
+
+func (s *Server) getAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := services.ParseAuditFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := s.auditLog.Query(filter, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePageHeaders(w, r, opts, page)
+	writeResponse(w, r, page.Items)
+}
+
+// getEmployeeHistory answers GET /employees/{id}/history. History stays
+// available after a record is deleted; only IDs that never existed are
+// reported as not found.
+func (s *Server) getEmployeeHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+	history := s.auditLog.History(services.EntityEmployee, id)
+	if len(history) == 0 {
+		if _, err := s.employees(r).Retrieve(id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	writeResponse(w, r, history)
+}
+
+func (s *Server) getDepartmentHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid department ID")
+		return
+	}
+	history := s.auditLog.History(services.EntityDepartment, id)
+	if len(history) == 0 {
+		if _, err := s.departments(r).Retrieve(id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	writeResponse(w, r, history)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"employee-maintenance/services"
+)
+
+func (s *Server) RegisterBatchRoutes() {
+	s.handle("POST /batch", s.runBatch)
+}
+
+type batchRequest struct {
+	Operations []services.BatchOperation `json:"operations"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// batchResult is one entry of a batch response. Status is what the
+// operation would have answered on its own endpoint; when the batch fails,
+// the failed operation carries its error status and the rest 424.
+type batchResult struct {
+	Status int `json:"status"`
+	services.BatchResult
+}
+
+func (s *Server) runBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	b := services.Batch{Employees: s.employees(r), Departments: s.departments(r)}
+	results, err := b.Run(r.Context(), req.Operations)
+	var berr *services.BatchError
+	if errors.As(err, &berr) {
+		p := problemFor(r, berr.Err)
+		p.Detail = err.Error()
+		failed := make([]batchResult, len(req.Operations))
+		for i, op := range req.Operations {
+			failed[i] = batchResult{Status: http.StatusFailedDependency, BatchResult: services.BatchResult{Op: op.Op, Entity: op.Entity, Ref: op.Ref}}
+		}
+		failed[berr.Index].Status = p.Status
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]any)
+		}
+		p.Extensions["failedOperation"] = berr.Index
+		p.Extensions["results"] = failed
+		writeProblem(w, r, p)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := make([]batchResult, len(results))
+	for i, result := range results {
+		resp[i] = batchResult{Status: http.StatusOK, BatchResult: result}
+		if result.Op == services.BatchDelete {
+			resp[i].Status = http.StatusNoContent
+		}
+	}
+	writeResponse(w, r, batchResponse{Results: resp})
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag renders a record version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// notModified answers a conditional GET: if the If-None-Match header matches
+// the current version it writes 304 and reports true. If-None-Match uses weak
+// comparison, so W/ prefixes are ignored.
+func notModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag(version) {
+			setETag(w, version)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion turns the If-Match header into the version the services
+// should require. No header or "*" yields zero, which the services treat as
+// unconditional (the record must still exist). If-Match uses strong
+// comparison, so weak tags never match; a header that cannot match anything
+// yields -1, which no stored version equals. With several tags, current is
+// consulted to pick the one that matches.
+func ifMatchVersion(r *http.Request, current func() (int, error)) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		if v, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil && v > 0 {
+			versions = append(versions, v)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return -1, nil
+	case 1:
+		return versions[0], nil
+	}
+
+	v, err := current()
+	if err != nil {
+		return 0, err
+	}
+	for _, candidate := range versions {
+		if candidate == v {
+			return v, nil
+		}
+	}
+	return -1, nil
+}
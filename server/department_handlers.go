@@ -1,7 +1,8 @@
 package server
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -10,94 +11,253 @@ import (
 )
 
 func (s *Server) RegisterDepartmentRoutes() {
-	s.mux.HandleFunc("GET /departments", s.getDepartments)
-	s.mux.HandleFunc("POST /departments", s.createDepartment)
-	s.mux.HandleFunc("GET /departments/{id}", s.getDepartment)
-	s.mux.HandleFunc("PUT /departments/{id}", s.updateDepartment)
-	s.mux.HandleFunc("DELETE /departments/{id}", s.deleteDepartment)
+	s.handle("GET /departments", s.getDepartments)
+	s.handle("POST /departments", s.createDepartment)
+	s.handle("GET /departments/{id}", s.getDepartment)
+	s.handle("PUT /departments/{id}", s.updateDepartment)
+	s.handle("PATCH /departments/{id}", s.patchDepartment)
+	s.handle("DELETE /departments/{id}", s.deleteDepartment)
+	s.handle("POST /departments/{id}/restore", s.restoreDepartment)
+	s.handle("POST /departments/{id}/move", s.moveDepartment)
+	s.handle("GET /departments/{id}/tree", s.getDepartmentTree)
 }
 
 func (s *Server) createDepartment(w http.ResponseWriter, r *http.Request) {
 	var dept models.Department
-	if err := json.NewDecoder(r.Body).Decode(&dept); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !readBody(w, r, &dept) {
 		return
 	}
-	newDept := s.departmentService.Create(dept)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(newDept)
+	newDept, err := s.departments(r).Create(r.Context(), dept)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, newDept.Version)
+	writeResponse(w, r, newDept)
 }
 
 func (s *Server) getDepartments(w http.ResponseWriter, r *http.Request) {
-	departments := s.departmentService.RetrieveAll()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(departments)
+	filter, err := services.ParseDepartmentFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := s.departments(r).List(filter, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePageHeaders(w, r, opts, page)
+	writeResponse(w, r, page.Items)
 }
 
 func (s *Server) getDepartment(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "invalid department ID")
 		return
 	}
 
-	dept, err := s.departmentService.Retrieve(id)
+	at, err := services.ParseAsOf(r.URL.Query())
 	if err != nil {
-		if err == services.ErrDepartmentNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
+		return
+	}
+	if !at.IsZero() {
+		dept, err := s.departments(r).RetrieveAsOf(id, at)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeResponse(w, r, dept)
+		return
+	}
+
+	dept, err := s.departments(r).Retrieve(id)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dept)
+	if notModified(w, r, dept.Version) {
+		return
+	}
+	setETag(w, dept.Version)
+	writeResponse(w, r, dept)
 }
 
 func (s *Server) updateDepartment(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "invalid department ID")
 		return
 	}
 
 	var dept models.Department
-	if err := json.NewDecoder(r.Body).Decode(&dept); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !readBody(w, r, &dept) {
 		return
 	}
 	if dept.ID != id {
-		http.Error(w, "ID in body does not match ID in URL", http.StatusBadRequest)
+		writeBadRequest(w, r, "ID in body does not match ID in URL")
 		return
 	}
-	updatedDept, err := s.departmentService.Update(dept)
+	dept.Version, err = ifMatchVersion(r, s.departmentVersion(r, id))
 	if err != nil {
-		if err == services.ErrDepartmentNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedDept)
+	updatedDept, err := s.departments(r).Update(r.Context(), dept)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, updatedDept.Version)
+	writeResponse(w, r, updatedDept)
+}
+
+func (s *Server) patchDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid department ID")
+		return
+	}
+
+	patch, ok := readPatch(w, r)
+	if !ok {
+		return
+	}
+	version, err := ifMatchVersion(r, s.departmentVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	patched, err := s.departments(r).Patch(r.Context(), id, patch, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, patched.Version)
+	writeResponse(w, r, patched)
 }
 
 func (s *Server) deleteDepartment(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "invalid department ID")
 		return
 	}
 
-	err = s.departmentService.Delete(id)
+	opts, err := parseDeleteOptions(r)
 	if err != nil {
-		if err == services.ErrDepartmentNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, r, err.Error())
+		return
+	}
+	opts.IfVersion, err = ifMatchVersion(r, s.departmentVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = s.departments(r).DeleteWithOptions(r.Context(), id, opts)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *Server) restoreDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid department ID")
+		return
+	}
+
+	dept, err := s.departments(r).Restore(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, dept.Version)
+	writeResponse(w, r, dept)
+}
+
+// moveRequest is the body of POST /departments/{id}/move.
+type moveRequest struct {
+	ParentID int `json:"parentId"`
+}
+
+func (s *Server) moveDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid department ID")
+		return
+	}
+
+	var req moveRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	version, err := ifMatchVersion(r, s.departmentVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	moved, err := s.departments(r).Move(r.Context(), id, req.ParentID, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, moved.Version)
+	writeResponse(w, r, moved)
+}
+
+func (s *Server) getDepartmentTree(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid department ID")
+		return
+	}
+
+	tree, err := s.departments(r).Tree(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, tree)
+}
+
+// departmentVersion looks up the stored version of a department for
+// ifMatchVersion.
+func (s *Server) departmentVersion(r *http.Request, id int) func() (int, error) {
+	return func() (int, error) {
+		dept, err := s.departments(r).Retrieve(id)
+		return dept.Version, err
+	}
+}
+
+// parseDeleteOptions reads the onDelete policy (restrict, cascade or
+// reassign) and, for reassign, the reassignTo department ID.
+func parseDeleteOptions(r *http.Request) (services.DeleteOptions, error) {
+	opts := services.DeleteOptions{Policy: services.DeleteRestrict}
+	if policy := r.URL.Query().Get("onDelete"); policy != "" {
+		opts.Policy = services.DeletePolicy(policy)
+	}
+	switch opts.Policy {
+	case services.DeleteRestrict, services.DeleteCascade:
+		return opts, nil
+	case services.DeleteReassign:
+		target, err := strconv.Atoi(r.URL.Query().Get("reassignTo"))
+		if err != nil {
+			return opts, errors.New("reassignTo must be a department ID")
+		}
+		opts.ReassignTo = target
+		return opts, nil
+	default:
+		return opts, fmt.Errorf("onDelete must be restrict, cascade or reassign, got %q", opts.Policy)
+	}
+}
@@ -1,103 +1,360 @@
 package server
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"employee-maintenance/models"
 	"employee-maintenance/services"
 )
 
 func (s *Server) RegisterEmployeeRoutes() {
-	s.mux.HandleFunc("GET /employees", s.getEmployees)
-	s.mux.HandleFunc("POST /employees", s.createEmployee)
-	s.mux.HandleFunc("GET /employees/{id}", s.getEmployee)
-	s.mux.HandleFunc("PUT /employees/{id}", s.updateEmployee)
-	s.mux.HandleFunc("DELETE /employees/{id}", s.deleteEmployee)
+	s.handle("GET /employees", s.getEmployees)
+	s.handle("POST /employees", s.createEmployee)
+	s.handle("POST /employees/import", s.importEmployees)
+	s.handle("GET /employees/search", s.searchEmployees)
+	s.handle("GET /employees/diff", s.diffEmployees)
+	s.handle("GET /employees/{id}", s.getEmployee)
+	s.handle("PUT /employees/{id}", s.updateEmployee)
+	s.handle("PATCH /employees/{id}", s.patchEmployee)
+	s.handle("DELETE /employees/{id}", s.deleteEmployee)
+	s.handle("POST /employees/{id}/restore", s.restoreEmployee)
+	s.handle("POST /employees/{id}/terminate", s.terminateEmployee)
+	s.handle("POST /employees/{id}/rehire", s.rehireEmployee)
 }
 
 func (s *Server) createEmployee(w http.ResponseWriter, r *http.Request) {
 	var emp models.Employee
-	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !readBody(w, r, &emp) {
 		return
 	}
-	newEmp := s.employeeService.Create(emp)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(newEmp)
+	newEmp, err := s.employees(r).Create(r.Context(), emp)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, newEmp.Version)
+	writeResponse(w, r, newEmp)
+}
+
+// maxImportBytes caps the size of a CSV import.
+const maxImportBytes = 10 << 20
+
+func (s *Server) importEmployees(w http.ResponseWriter, r *http.Request) {
+	opts, err := services.ParseImportOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	result, err := s.employees(r).Import(r.Context(), http.MaxBytesReader(w, r.Body, maxImportBytes), opts)
+	if writeTooLarge(w, r, err) {
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, result)
 }
 
 func (s *Server) getEmployees(w http.ResponseWriter, r *http.Request) {
-	employees := s.employeeService.RetrieveAll()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(employees)
+	filter, err := services.ParseEmployeeFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := s.employees(r).List(filter, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	employees := page.Items
+	if expandDepartment(r) {
+		employees, err = s.employees(r).ExpandDepartmentsAsOf(filter.AsOf, employees...)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	writePageHeaders(w, r, opts, page)
+	writeResponse(w, r, employees)
+}
+
+// defaultSearchLimit is the number of search results returned when the
+// request does not set a limit.
+const defaultSearchLimit = 20
+
+func (s *Server) searchEmployees(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeBadRequest(w, r, "limit must be an integer")
+			return
+		}
+		limit = n
+	}
+	results, err := s.employees(r).Search(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, results)
 }
 
 func (s *Server) getEmployee(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "invalid employee ID")
 		return
 	}
 
-	emp, err := s.employeeService.Retrieve(id)
+	at, err := services.ParseAsOf(r.URL.Query())
 	if err != nil {
-		if err == services.ErrEmployeeNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
+		return
+	}
+	if !at.IsZero() {
+		s.getEmployeeAsOf(w, r, id, at)
+		return
+	}
+
+	emp, err := s.employees(r).Retrieve(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// An expanded body also changes with the department, which the
+	// employee's version does not track, so it carries no ETag.
+	if expandDepartment(r) {
+		writeResponse(w, r, s.employees(r).ExpandDepartments(emp)[0])
+		return
+	}
+	if notModified(w, r, emp.Version) {
+		return
+	}
+	setETag(w, emp.Version)
+	writeResponse(w, r, emp)
+}
+
+// getEmployeeAsOf answers GET /employees/{id}?asOf=... Past states carry no
+// ETag, since the version they show is not one a write could match.
+func (s *Server) getEmployeeAsOf(w http.ResponseWriter, r *http.Request, id int, at services.AsOf) {
+	emp, err := s.employees(r).RetrieveAsOf(id, at)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if expandDepartment(r) {
+		expanded, err := s.employees(r).ExpandDepartmentsAsOf(at, emp)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		emp = expanded[0]
+	}
+	writeResponse(w, r, emp)
+}
+
+func (s *Server) diffEmployees(w http.ResponseWriter, r *http.Request) {
+	from, to, err := services.ParseDiffRange(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(emp)
+	diff, err := s.employees(r).Diff(from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, diff)
 }
 
 func (s *Server) updateEmployee(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "invalid employee ID")
 		return
 	}
 
 	var emp models.Employee
-	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !readBody(w, r, &emp) {
 		return
 	}
 	if emp.ID != id {
-		http.Error(w, "ID in body does not match ID in URL", http.StatusBadRequest)
+		writeBadRequest(w, r, "ID in body does not match ID in URL")
 		return
 	}
-	updatedEmp, err := s.employeeService.Update(emp)
+	emp.Version, err = ifMatchVersion(r, s.employeeVersion(r, id))
 	if err != nil {
-		if err == services.ErrEmployeeNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	updatedEmp, err := s.employees(r).Update(r.Context(), emp)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedEmp)
+	setETag(w, updatedEmp.Version)
+	writeResponse(w, r, updatedEmp)
 }
 
-func (s *Server) deleteEmployee(w http.ResponseWriter, r *http.Request) {
+func (s *Server) patchEmployee(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	patch, ok := readPatch(w, r)
+	if !ok {
+		return
+	}
+	version, err := ifMatchVersion(r, s.employeeVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+	patched, err := s.employees(r).Patch(r.Context(), id, patch, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, patched.Version)
+	writeResponse(w, r, patched)
+}
 
-	err = s.employeeService.Delete(id)
+func (s *Server) deleteEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		if err == services.ErrEmployeeNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	var opts services.EmployeeDeleteOptions
+	if v := r.URL.Query().Get("reassignReportsTo"); v != "" {
+		if opts.ReassignReportsTo, err = strconv.Atoi(v); err != nil {
+			writeBadRequest(w, r, "reassignReportsTo must be an employee ID")
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	opts.IfVersion, err = ifMatchVersion(r, s.employeeVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	err = s.employees(r).DeleteWithOptions(r.Context(), id, opts)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *Server) restoreEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	emp, err := s.employees(r).Restore(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, emp.Version)
+	writeResponse(w, r, emp)
+}
+
+// terminateRequest is the body of POST /employees/{id}/terminate.
+type terminateRequest struct {
+	Date              models.Date `json:"date"`
+	Reason            string      `json:"reason"`
+	ReassignReportsTo int         `json:"reassignReportsTo"`
+}
+
+func (s *Server) terminateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	var req terminateRequest
+	if !readBody(w, r, &req) {
+		return
+	}
+	opts := services.TerminateOptions{Date: req.Date, Reason: req.Reason, ReassignReportsTo: req.ReassignReportsTo}
+	opts.IfVersion, err = ifMatchVersion(r, s.employeeVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	emp, err := s.employees(r).Terminate(r.Context(), id, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, emp.Version)
+	writeResponse(w, r, emp)
+}
+
+// rehireRequest is the optional body of POST /employees/{id}/rehire.
+type rehireRequest struct {
+	HireDate     models.Date `json:"hireDate"`
+	DepartmentID int         `json:"departmentId"`
+	ManagerID    int         `json:"managerId"`
+}
+
+func (s *Server) rehireEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	var req rehireRequest
+	if r.ContentLength != 0 && !readBody(w, r, &req) {
+		return
+	}
+	opts := services.RehireOptions{HireDate: req.HireDate, DepartmentID: req.DepartmentID, ManagerID: req.ManagerID}
+	opts.IfVersion, err = ifMatchVersion(r, s.employeeVersion(r, id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	emp, err := s.employees(r).Rehire(r.Context(), id, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, emp.Version)
+	writeResponse(w, r, emp)
+}
+
+// employeeVersion looks up the stored version of an employee for
+// ifMatchVersion.
+func (s *Server) employeeVersion(r *http.Request, id int) func() (int, error) {
+	return func() (int, error) {
+		emp, err := s.employees(r).Retrieve(id)
+		return emp.Version, err
+	}
+}
+
+// expandDepartment reports whether the request asked for the full department
+// object (?expand=department) instead of just its ID.
+func expandDepartment(r *http.Request) bool {
+	for _, field := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		if strings.TrimSpace(field) == "department" {
+			return true
+		}
+	}
+	return false
+}
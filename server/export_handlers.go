@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"employee-maintenance/export"
+	"employee-maintenance/models"
+	"employee-maintenance/services"
+)
+
+func (s *Server) RegisterExportRoutes() {
+	s.mux.HandleFunc("GET /employees/export", s.exportEmployees)
+	s.mux.HandleFunc("GET /departments/export", s.exportDepartments)
+}
+
+// employeeColumns lists the columns an employee export can have.
+// departmentName is looked up in names.
+func employeeColumns(names map[int]string) []export.Column[models.Employee] {
+	return []export.Column[models.Employee]{
+		{Name: "id", Value: func(e models.Employee) any { return e.ID }},
+		{Name: "firstName", Value: func(e models.Employee) any { return e.FirstName }},
+		{Name: "lastName", Value: func(e models.Employee) any { return e.LastName }},
+		{Name: "email", Value: func(e models.Employee) any { return e.Email }},
+		{Name: "title", Value: func(e models.Employee) any { return e.Title }},
+		{Name: "departmentId", Value: func(e models.Employee) any { return e.DepartmentID }},
+		{Name: "departmentName", Value: func(e models.Employee) any { return names[e.DepartmentID] }},
+		{Name: "managerId", Value: func(e models.Employee) any { return e.ManagerID }},
+		{Name: "hireDate", Value: func(e models.Employee) any { return e.HireDate.String() }},
+		{Name: "status", Value: func(e models.Employee) any { return string(e.Status) }},
+		{Name: "terminationDate", Value: func(e models.Employee) any { return e.TerminationDate.String() }},
+		{Name: "terminationReason", Value: func(e models.Employee) any { return e.TerminationReason }},
+		{Name: "version", Value: func(e models.Employee) any { return e.Version }},
+		{Name: "deletedAt", Value: func(e models.Employee) any { return timeOrNil(e.DeletedAt) }},
+	}
+}
+
+var departmentColumns = []export.Column[models.Department]{
+	{Name: "id", Value: func(d models.Department) any { return d.ID }},
+	{Name: "name", Value: func(d models.Department) any { return d.Name }},
+	{Name: "parentId", Value: func(d models.Department) any { return d.ParentID }},
+	{Name: "headcountBudget", Value: func(d models.Department) any { return floatOrNil(d.HeadcountBudget) }},
+	{Name: "version", Value: func(d models.Department) any { return d.Version }},
+	{Name: "deletedAt", Value: func(d models.Department) any { return timeOrNil(d.DeletedAt) }},
+}
+
+func timeOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func floatOrNil(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+func (s *Server) exportEmployees(w http.ResponseWriter, r *http.Request) {
+	filter, err := services.ParseEmployeeFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	names := make(map[int]string)
+	for _, d := range s.departments(r).RetrieveAll() {
+		names[d.ID] = d.Name
+	}
+	columns, err := export.SelectColumns(employeeColumns(names), exportColumns(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeExport(w, r, "employees", columns, func(fn func(models.Employee) error) error {
+		return s.employees(r).Export(filter, fn)
+	})
+}
+
+func (s *Server) exportDepartments(w http.ResponseWriter, r *http.Request) {
+	filter, err := services.ParseDepartmentFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	columns, err := export.SelectColumns(departmentColumns, exportColumns(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeExport(w, r, "departments", columns, func(fn func(models.Department) error) error {
+		return s.departments(r).Export(filter, fn)
+	})
+}
+
+// exportColumns reads the comma-separated columns query parameter.
+func exportColumns(r *http.Request) []string {
+	var names []string
+	for _, name := range strings.Split(r.URL.Query().Get("columns"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// writeExport streams the records produced by each as an attachment in the
+// requested format. Once output has reached the client the status can no
+// longer change, so a later failure is logged and the output cut short.
+func writeExport[T any](w http.ResponseWriter, r *http.Request, name string, columns []export.Column[T], each func(func(T) error) error) {
+	format, err := export.LookupFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, name, format.Extension))
+	cw := &countingWriter{w: w}
+	out, err := format.New(cw, name, export.Names(columns))
+	if err == nil {
+		err = each(func(v T) error {
+			return out.WriteRow(export.Row(columns, v))
+		})
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil && cw.n == 0 {
+		w.Header().Del("Content-Disposition")
+		writeError(w, r, err)
+		return
+	}
+	if err != nil {
+		log.Printf("request %s: export of %s failed: %v", requestID(r), name, err)
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) RegisterHierarchyRoutes() {
+	s.handle("GET /employees/span-of-control", s.getSpanStats)
+	s.handle("GET /employees/{id}/reports", s.getReports)
+	s.handle("GET /employees/{id}/chain", s.getChain)
+	s.handle("GET /employees/{id}/span-of-control", s.getSpanOfControl)
+}
+
+func (s *Server) getReports(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+	depth := 1
+	switch v := r.URL.Query().Get("depth"); v {
+	case "":
+	case "all":
+		depth = 0
+	default:
+		if depth, err = strconv.Atoi(v); err != nil || depth < 1 {
+			writeBadRequest(w, r, "depth must be a positive integer or all")
+			return
+		}
+	}
+
+	reports, err := s.employees(r).Reports(id, depth)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if expandDepartment(r) {
+		reports = s.employees(r).ExpandDepartments(reports...)
+	}
+	writeResponse(w, r, reports)
+}
+
+func (s *Server) getChain(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	chain, err := s.employees(r).Chain(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if expandDepartment(r) {
+		chain = s.employees(r).ExpandDepartments(chain...)
+	}
+	writeResponse(w, r, chain)
+}
+
+func (s *Server) getSpanOfControl(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	span, err := s.employees(r).SpanOfControl(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, span)
+}
+
+func (s *Server) getSpanStats(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, s.employees(r).SpanStats())
+}
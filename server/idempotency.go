@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"employee-maintenance/idempotency"
+	"employee-maintenance/services"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+)
+
+// SetIdempotencyTTL sets how long responses to requests with an
+// Idempotency-Key are kept for replay.
+func (s *Server) SetIdempotencyTTL(ttl time.Duration) {
+	s.idempotency.SetTTL(ttl)
+}
+
+// withIdempotency makes a POST carrying an Idempotency-Key safe to retry.
+// The first response for a key, scoped by the caller in X-Actor, is stored
+// and replayed for later requests with the same key and the same method,
+// target, Content-Type, transaction and body. Reusing the key for a
+// different request is rejected with 422, and a retry while the first
+// attempt is still running with 409. Error responses are not stored: a
+// failed request changes nothing, so it can be corrected and retried with
+// the same key.
+func (s *Server) withIdempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeBadRequest(w, r, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+		if writeTooLarge(w, r, err) {
+			return
+		}
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		caller := services.ActorFrom(r.Context())
+		stored, err := s.idempotency.Begin(caller, key, fingerprint(r, body))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if stored != nil {
+			replay(w, stored)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// A handler that panicked has no response worth replaying; free
+			// the key for a retry and let the panic go on.
+			if p := recover(); p != nil {
+				s.idempotency.Release(caller, key)
+				panic(p)
+			}
+			if !rec.wroteHeader {
+				rec.header = w.Header().Clone()
+			}
+			if rec.status >= http.StatusBadRequest {
+				s.idempotency.Release(caller, key)
+				return
+			}
+			header := rec.header.Clone()
+			header.Del(requestIDHeader)
+			s.idempotency.Complete(caller, key, idempotency.Response{Status: rec.status, Header: header, Body: rec.body.Bytes()})
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// fingerprint identifies what a request asks for, to tell a retry from a
+// different request that reuses the key.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get(transactionHeader)} {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, resp *idempotency.Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(replayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.header = rec.Header().Clone()
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"employee-maintenance/codec"
+)
+
+// maxBodyBytes caps the size of a create or update request body.
+const maxBodyBytes = 1 << 20
+
+type mediaTypeKey struct{}
+
+// handle registers a handler whose responses are negotiated: it writes
+// them with writeResponse, in the media type picked from Accept.
+func (s *Server) handle(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, negotiated(h))
+}
+
+// negotiated picks the response media type from the Accept header before
+// h runs, so a request the server cannot answer is refused with 406 before
+// it changes anything.
+func negotiated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		mediaType, ok := negotiate(r.Header.Get("Accept"), codec.MediaTypes)
+		if !ok {
+			writeProblem(w, r, newProblem(http.StatusNotAcceptable,
+				"Accept must allow one of "+strings.Join(codec.MediaTypes, ", ")))
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), mediaTypeKey{}, mediaType)))
+	}
+}
+
+// acceptRange is one media range of an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// matches reports how specifically the range matches mediaType: 3 for an
+// exact match, 2 for type/*, 1 for */* and 0 for no match.
+func (a acceptRange) matches(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case a.typ == "*" && a.subtype == "*":
+		return 1
+	case a.typ != typ:
+		return 0
+	case a.subtype == "*":
+		return 2
+	case a.subtype == subtype:
+		return 3
+	}
+	return 0
+}
+
+// negotiate returns the offer with the highest quality in accept, using
+// the most specific range that matches each offer. Ties go to the earlier
+// offer. A missing Accept header accepts the first offer; ok is false if
+// no offer is acceptable.
+func negotiate(accept string, offers []string) (mediaType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+	best := 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, 0
+		for _, a := range ranges {
+			if m := a.matches(offer); m > specificity {
+				q, specificity = a.q, m
+			}
+		}
+		if q > best {
+			mediaType, best = offer, q
+		}
+	}
+	return mediaType, best > 0
+}
+
+// parseAccept parses an Accept header, skipping ranges that are malformed
+// or carry an invalid q value.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		typ, subtype, found := strings.Cut(mediaType, "/")
+		if !found || (typ == "*" && subtype != "*") {
+			continue
+		}
+		a := acceptRange{typ: typ, subtype: subtype, q: 1}
+		if v, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			a.q = q
+		}
+		ranges = append(ranges, a)
+	}
+	return ranges
+}
+
+// responseType returns the media type negotiated for r.
+func responseType(r *http.Request) string {
+	if mediaType, ok := r.Context().Value(mediaTypeKey{}).(string); ok {
+		return mediaType
+	}
+	return codec.JSON.MediaType
+}
+
+// writeResponse writes v in the media type negotiated for the request.
+func writeResponse(w http.ResponseWriter, r *http.Request, v any) {
+	mediaType := responseType(r)
+	c, _ := codec.Lookup(mediaType)
+	if c.MediaType == codec.JSON.MediaType {
+		w.Header().Set("Content-Type", mediaType)
+		c.Encode(w, v)
+		return
+	}
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, v); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// readBody decodes the request body into v according to its Content-Type,
+// which defaults to JSON. On failure it writes the error response (415 for
+// an unsupported type) and returns false.
+func readBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	mediaType := codec.JSON.MediaType
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+			mediaType = ct
+		}
+	}
+	c, ok := codec.Lookup(mediaType)
+	if !ok {
+		writeProblem(w, r, newProblem(http.StatusUnsupportedMediaType,
+			"Content-Type must be one of "+strings.Join(codec.MediaTypes, ", ")))
+		return false
+	}
+	if err := c.Decode(http.MaxBytesReader(w, r.Body, maxBodyBytes), v); err != nil {
+		writeBadRequest(w, r, err.Error())
+		return false
+	}
+	return true
+}
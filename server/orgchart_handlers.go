@@ -0,0 +1,39 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+
+	"employee-maintenance/orgchart"
+)
+
+func (s *Server) RegisterOrgChartRoutes() {
+	s.mux.HandleFunc("GET /orgchart", s.getOrgChart)
+}
+
+func (s *Server) getOrgChart(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format, err := orgchart.LookupFormat(q.Get("format"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	root, err := orgchart.ParseRoot(q.Get("root"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	chart, err := orgchart.Build(s.departments(r).RetrieveAll(), s.employees(r).RetrieveStaff(), root)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := format.Write(&buf, chart); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Write(buf.Bytes())
+}
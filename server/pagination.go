@@ -0,0 +1,72 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"employee-maintenance/services"
+)
+
+const (
+	totalCountHeader = "X-Total-Count"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// listOptions parses the paging and sorting query parameters of r.
+func listOptions(r *http.Request) (services.ListOptions, error) {
+	return services.ParseListOptions(r.URL.Query())
+}
+
+// writePageHeaders sets X-Total-Count, X-Next-Cursor when more items follow,
+// and an RFC 8288 Link header pointing at the neighbouring pages. Cursor
+// requests only get a next link, since a cursor cannot be walked backwards;
+// offset requests also get first, prev and last.
+func writePageHeaders[T any](w http.ResponseWriter, r *http.Request, opts services.ListOptions, page services.Page[T]) {
+	w.Header().Set(totalCountHeader, strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		w.Header().Set(nextCursorHeader, page.NextCursor)
+	}
+	if opts.Limit == 0 {
+		return
+	}
+
+	var links []string
+	link := func(rel string, set func(q url.Values)) {
+		u := *r.URL
+		q := u.Query()
+		q.Del("cursor")
+		q.Del("offset")
+		set(q)
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), rel))
+	}
+	setOffset := func(offset int) func(url.Values) {
+		return func(q url.Values) {
+			if offset > 0 {
+				q.Set("offset", strconv.Itoa(offset))
+			}
+		}
+	}
+
+	if opts.Cursor != "" {
+		if page.NextCursor != "" {
+			link("next", func(q url.Values) { q.Set("cursor", page.NextCursor) })
+		}
+		link("first", setOffset(0))
+	} else {
+		if opts.Offset+opts.Limit < page.Total {
+			link("next", setOffset(opts.Offset+opts.Limit))
+		}
+		if opts.Offset > 0 {
+			link("prev", setOffset(max(opts.Offset-opts.Limit, 0)))
+		}
+		link("first", setOffset(0))
+		if page.Total > 0 {
+			link("last", setOffset((page.Total-1)/opts.Limit*opts.Limit))
+		}
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"employee-maintenance/services"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+	acceptPatch           = mergePatchContentType + ", " + jsonPatchContentType
+	maxPatchBytes         = 1 << 20
+)
+
+// readPatch reads the request body as a merge patch or JSON patch depending
+// on its Content-Type. On failure it writes the error response (415 for an
+// unsupported type) and returns false.
+func readPatch(w http.ResponseWriter, r *http.Request) (services.Patch, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchContentType && mediaType != jsonPatchContentType {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		writeProblem(w, r, newProblem(http.StatusUnsupportedMediaType,
+			"PATCH requires Content-Type "+mergePatchContentType+" or "+jsonPatchContentType))
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		writeBadRequest(w, r, err.Error())
+		return nil, false
+	}
+	if mediaType == mergePatchContentType {
+		return services.MergePatch(body), true
+	}
+	return services.JSONPatch(body), true
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"employee-maintenance/models"
+	"employee-maintenance/services"
+)
+
+func (s *Server) RegisterPositionRoutes() {
+	s.handle("GET /positions", s.getPositions)
+	s.handle("POST /positions", s.createPosition)
+	s.handle("GET /positions/{id}", s.getPosition)
+	s.handle("PUT /positions/{id}", s.updatePosition)
+	s.handle("DELETE /positions/{id}", s.deletePosition)
+	s.handle("GET /departments/{id}/headcount", s.getHeadcount)
+}
+
+// SetPositionService replaces the service that keeps positions, by default
+// one held in memory, and makes department deletes check it.
+func (s *Server) SetPositionService(svc *services.PositionService) {
+	s.positions = svc
+	s.departmentService.SetPositionService(svc)
+}
+
+func (s *Server) getPositions(w http.ResponseWriter, r *http.Request) {
+	filter, err := services.ParsePositionFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := s.positions.List(filter, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePageHeaders(w, r, opts, page)
+	writeResponse(w, r, page.Items)
+}
+
+func (s *Server) createPosition(w http.ResponseWriter, r *http.Request) {
+	opts, err := services.ParsePositionOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var pos models.Position
+	if !readBody(w, r, &pos) {
+		return
+	}
+	newPos, err := s.positions.Create(r.Context(), pos, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, newPos.Version)
+	writeResponse(w, r, newPos)
+}
+
+func (s *Server) getPosition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid position ID")
+		return
+	}
+
+	pos, err := s.positions.Retrieve(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if notModified(w, r, pos.Version) {
+		return
+	}
+	setETag(w, pos.Version)
+	writeResponse(w, r, pos)
+}
+
+func (s *Server) updatePosition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid position ID")
+		return
+	}
+
+	opts, err := services.ParsePositionOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var pos models.Position
+	if !readBody(w, r, &pos) {
+		return
+	}
+	if pos.ID != id {
+		writeBadRequest(w, r, "ID in body does not match ID in URL")
+		return
+	}
+	opts.IfVersion, err = ifMatchVersion(r, s.positionVersion(id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	updated, err := s.positions.Update(r.Context(), pos, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, updated.Version)
+	writeResponse(w, r, updated)
+}
+
+func (s *Server) deletePosition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid position ID")
+		return
+	}
+
+	version, err := ifMatchVersion(r, s.positionVersion(id))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := s.positions.Delete(r.Context(), id, version); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getHeadcount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid department ID")
+		return
+	}
+
+	headcount, err := s.positions.Headcount(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, headcount)
+}
+
+// positionVersion looks up the stored version of a position for
+// ifMatchVersion.
+func (s *Server) positionVersion(id int) func() (int, error) {
+	return func() (int, error) {
+		pos, err := s.positions.Retrieve(id)
+		return pos.Version, err
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"employee-maintenance/idempotency"
+	"employee-maintenance/services"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Extensions holds extra
+// members that are specific to a problem type and are serialized alongside
+// the standard ones.
+type Problem struct {
+	Type       string                `json:"type"`
+	Title      string                `json:"title"`
+	Status     int                   `json:"status"`
+	Detail     string                `json:"detail,omitempty"`
+	Instance   string                `json:"instance,omitempty"`
+	RequestID  string                `json:"requestId,omitempty"`
+	Errors     []services.FieldError `json:"errors,omitempty"`
+	Extensions map[string]any        `json:"-"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	base, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return base, err
+	}
+	members := make(map[string]any, len(p.Extensions))
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	var standard map[string]any
+	if err := json.Unmarshal(base, &standard); err != nil {
+		return nil, err
+	}
+	for k, v := range standard {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// problemRule maps a sentinel error, matched with errors.Is, to a problem
+// type. extend may add type-specific members from the concrete error.
+type problemRule struct {
+	err    error
+	status int
+	slug   string
+	title  string
+	extend func(err error, p *Problem)
+}
+
+// problemTypes lists the errors the API knows how to describe. Anything not
+// matched here is reported as a 500 without exposing the error text.
+var problemTypes = []problemRule{
+	{err: services.ErrEmployeeNotFound, status: http.StatusNotFound, slug: "employee-not-found", title: "Employee not found"},
+	{err: services.ErrEmployeeExists, status: http.StatusConflict, slug: "employee-exists", title: "Employee already exists"},
+	{err: services.ErrDepartmentNotFound, status: http.StatusNotFound, slug: "department-not-found", title: "Department not found"},
+	{err: services.ErrValidation, status: http.StatusUnprocessableEntity, slug: "validation-failed", title: "Validation failed", extend: fieldErrors},
+	{err: services.ErrInvalidDepartment, status: http.StatusUnprocessableEntity, slug: "invalid-department", title: "Department does not exist"},
+	{err: services.ErrDepartmentInUse, status: http.StatusConflict, slug: "department-in-use", title: "Department has employees", extend: dependents},
+	{err: services.ErrVersionConflict, status: http.StatusPreconditionFailed, slug: "version-mismatch", title: "Version mismatch"},
+	{err: services.ErrInvalidPatch, status: http.StatusBadRequest, slug: "invalid-patch", title: "Invalid patch document"},
+	{err: services.ErrPatchFailed, status: http.StatusUnprocessableEntity, slug: "patch-failed", title: "Patch cannot be applied"},
+	{err: services.ErrPatchTestFailed, status: http.StatusConflict, slug: "patch-test-failed", title: "Patch test failed"},
+	{err: services.ErrInvalidQuery, status: http.StatusBadRequest, slug: "invalid-query", title: "Invalid query parameters"},
+	{err: services.ErrInvalidManager, status: http.StatusUnprocessableEntity, slug: "invalid-manager", title: "Manager does not exist"},
+	{err: services.ErrManagerCycle, status: http.StatusUnprocessableEntity, slug: "manager-cycle", title: "Reporting cycle"},
+	{err: services.ErrHasReports, status: http.StatusConflict, slug: "employee-has-reports", title: "Employee has reports", extend: reports},
+	{err: services.ErrInvalidParent, status: http.StatusUnprocessableEntity, slug: "invalid-parent", title: "Parent department does not exist"},
+	{err: services.ErrDepartmentCycle, status: http.StatusUnprocessableEntity, slug: "department-cycle", title: "Department cycle"},
+	{err: services.ErrDepartmentHasChildren, status: http.StatusConflict, slug: "department-has-children", title: "Department has child departments", extend: childDepartments},
+	{err: services.ErrInvalidImport, status: http.StatusBadRequest, slug: "invalid-import", title: "Import file cannot be read"},
+	{err: services.ErrImportFailed, status: http.StatusUnprocessableEntity, slug: "import-failed", title: "Import rows are invalid", extend: importRows},
+	{err: services.ErrInvalidTransition, status: http.StatusConflict, slug: "invalid-status-transition", title: "Status change not allowed", extend: transition},
+	{err: services.ErrNotDeleted, status: http.StatusConflict, slug: "not-deleted", title: "Record is not deleted"},
+	{err: services.ErrInvalidBatch, status: http.StatusBadRequest, slug: "invalid-batch", title: "Invalid batch"},
+	{err: services.ErrScheduledChangeNotFound, status: http.StatusNotFound, slug: "scheduled-change-not-found", title: "Scheduled change not found"},
+	{err: services.ErrInvalidScheduledChange, status: http.StatusUnprocessableEntity, slug: "invalid-scheduled-change", title: "Change cannot be scheduled"},
+	{err: services.ErrChangeNotPending, status: http.StatusConflict, slug: "scheduled-change-not-pending", title: "Scheduled change is not pending"},
+	{err: services.ErrPositionNotFound, status: http.StatusNotFound, slug: "position-not-found", title: "Position not found"},
+	{err: services.ErrOverBudget, status: http.StatusConflict, slug: "over-budget", title: "Headcount budget exceeded", extend: budget},
+	{err: services.ErrDepartmentHasPositions, status: http.StatusConflict, slug: "department-has-positions", title: "Department has positions", extend: positions},
+	{err: errTransactionNotFound, status: http.StatusNotFound, slug: "transaction-not-found", title: "Transaction not found"},
+	{err: services.ErrTxConflict, status: http.StatusConflict, slug: "transaction-conflict", title: "Transaction conflict"},
+	{err: services.ErrTxDone, status: http.StatusConflict, slug: "transaction-closed", title: "Transaction already ended"},
+	{err: idempotency.ErrKeyReused, status: http.StatusUnprocessableEntity, slug: "idempotency-key-reused", title: "Idempotency key reused"},
+	{err: idempotency.ErrInProgress, status: http.StatusConflict, slug: "idempotency-key-in-use", title: "Request with this idempotency key in progress"},
+}
+
+func fieldErrors(err error, p *Problem) {
+	var verr *services.ValidationError
+	if errors.As(err, &verr) {
+		p.Errors = verr.Fields
+		p.Detail = "one or more fields are invalid"
+	}
+}
+
+func dependents(err error, p *Problem) {
+	var derr *services.DependentsError
+	if errors.As(err, &derr) {
+		p.Extensions = map[string]any{"employeeIds": derr.EmployeeIDs}
+	}
+}
+
+func reports(err error, p *Problem) {
+	var rerr *services.ReportsError
+	if errors.As(err, &rerr) {
+		p.Extensions = map[string]any{"employeeIds": rerr.EmployeeIDs}
+	}
+}
+
+func childDepartments(err error, p *Problem) {
+	var cerr *services.ChildrenError
+	if errors.As(err, &cerr) {
+		p.Extensions = map[string]any{"departmentIds": cerr.ChildIDs}
+	}
+}
+
+func importRows(err error, p *Problem) {
+	var ierr *services.ImportError
+	if errors.As(err, &ierr) {
+		p.Extensions = map[string]any{"rows": ierr.Rows}
+	}
+}
+
+func transition(err error, p *Problem) {
+	var terr *services.TransitionError
+	if errors.As(err, &terr) {
+		p.Extensions = map[string]any{"from": terr.From, "to": terr.To, "allowed": terr.Allowed}
+	}
+}
+
+func budget(err error, p *Problem) {
+	var berr *services.BudgetError
+	if errors.As(err, &berr) {
+		p.Extensions = map[string]any{"departmentId": berr.DepartmentID, "budget": berr.Budget, "allocated": berr.Allocated, "requested": berr.Requested}
+	}
+}
+
+func positions(err error, p *Problem) {
+	var perr *services.PositionsError
+	if errors.As(err, &perr) {
+		p.Extensions = map[string]any{"positionIds": perr.PositionIDs}
+	}
+}
+
+// problemFor translates err into a Problem for the given request.
+func problemFor(r *http.Request, err error) Problem {
+	for _, rule := range problemTypes {
+		if !errors.Is(err, rule.err) {
+			continue
+		}
+		p := Problem{
+			Type:   "/problems/" + rule.slug,
+			Title:  rule.title,
+			Status: rule.status,
+			Detail: err.Error(),
+		}
+		if rule.extend != nil {
+			rule.extend(err, &p)
+		}
+		return p
+	}
+	log.Printf("request %s: %s %s: %v", requestID(r), r.Method, r.URL.Path, err)
+	return newProblem(http.StatusInternalServerError, "an unexpected error occurred")
+}
+
+// newProblem builds a problem that needs no type of its own, such as a
+// malformed request.
+func newProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// writeError responds with the problem that describes err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, problemFor(r, err))
+}
+
+// writeTooLarge responds with 413 and reports true if err came from reading a
+// request body past its http.MaxBytesReader limit.
+func writeTooLarge(w http.ResponseWriter, r *http.Request, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	writeProblem(w, r, newProblem(http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("the request body must be at most %d bytes", tooLarge.Limit)))
+	return true
+}
+
+// writeBadRequest responds with a 400 problem carrying detail.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, newProblem(http.StatusBadRequest, detail))
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Instance = r.URL.RequestURI()
+	p.RequestID = requestID(r)
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"employee-maintenance/services"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	actorHeader     = "X-Actor"
+)
+
+// withRequestID tags every request with an ID, taken from the X-Request-ID
+// header when the caller supplies one, and echoes it in the response. The ID
+// and the caller named in X-Actor are put into the request context, where the
+// services pick them up for the audit log.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := services.WithRequestID(r.Context(), id)
+		if actor := strings.TrimSpace(r.Header.Get(actorHeader)); actor != "" && len(actor) <= 128 {
+			ctx = services.WithActor(ctx, actor)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the ID assigned to r by withRequestID.
+func requestID(r *http.Request) string {
+	return services.RequestIDFrom(r.Context())
+}
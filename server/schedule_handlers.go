@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"employee-maintenance/models"
+	"employee-maintenance/services"
+)
+
+func (s *Server) RegisterScheduleRoutes() {
+	s.handle("GET /employees/{id}/scheduled-changes", s.getScheduledChanges)
+	s.handle("POST /employees/{id}/scheduled-changes", s.scheduleChange)
+	s.handle("GET /employees/{id}/scheduled-changes/{changeId}", s.getScheduledChange)
+	s.handle("DELETE /employees/{id}/scheduled-changes/{changeId}", s.cancelScheduledChange)
+}
+
+// SetScheduledChangeService replaces the service that keeps scheduled
+// changes, by default one held in memory.
+func (s *Server) SetScheduledChangeService(svc *services.ScheduledChangeService) {
+	s.scheduledChanges = svc
+}
+
+// scheduledChangeIDs reads the employee and change IDs from the path.
+func scheduledChangeIDs(w http.ResponseWriter, r *http.Request) (empID, changeID int, ok bool) {
+	empID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return 0, 0, false
+	}
+	changeID, err = strconv.Atoi(r.PathValue("changeId"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid scheduled change ID")
+		return 0, 0, false
+	}
+	return empID, changeID, true
+}
+
+func (s *Server) getScheduledChanges(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+	statuses, err := services.ParseChangeStatuses(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	changes := s.scheduledChanges.List(id, statuses)
+	if len(changes) == 0 {
+		if _, err := s.employees(r).Retrieve(id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if changes == nil {
+		changes = []models.ScheduledChange{}
+	}
+	writeResponse(w, r, changes)
+}
+
+func (s *Server) scheduleChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeBadRequest(w, r, "invalid employee ID")
+		return
+	}
+
+	var change models.ScheduledChange
+	if !readBody(w, r, &change) {
+		return
+	}
+	change.EmployeeID = id
+	change, err = s.scheduledChanges.Schedule(r.Context(), change)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, change)
+}
+
+func (s *Server) getScheduledChange(w http.ResponseWriter, r *http.Request) {
+	empID, changeID, ok := scheduledChangeIDs(w, r)
+	if !ok {
+		return
+	}
+	change, err := s.scheduledChanges.Retrieve(empID, changeID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, change)
+}
+
+func (s *Server) cancelScheduledChange(w http.ResponseWriter, r *http.Request) {
+	empID, changeID, ok := scheduledChangeIDs(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.scheduledChanges.Cancel(r.Context(), empID, changeID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,21 +4,34 @@ import (
 	"log"
 	"net/http"
 
+	"employee-maintenance/idempotency"
 	"employee-maintenance/services"
 )
 
 type Server struct {
 	employeeService   *services.EmployeeService
 	departmentService *services.DepartmentService
+	auditLog          *services.AuditLog
+	scheduledChanges  *services.ScheduledChangeService
+	positions         *services.PositionService
+	idempotency       *idempotency.Store
+	transactions      *transactionStore
 	mux               *http.ServeMux
 }
 
-func NewServer(empService *services.EmployeeService, deptService *services.DepartmentService) *Server {
+func NewServer(empService *services.EmployeeService, deptService *services.DepartmentService, auditLog *services.AuditLog) *Server {
 	s := &Server{
 		employeeService:   empService,
 		departmentService: deptService,
+		auditLog:          auditLog,
+		scheduledChanges:  services.NewScheduledChangeService(empService),
+		idempotency:       idempotency.NewStore(idempotency.DefaultTTL),
+		transactions:      newTransactionStore(DefaultTransactionTimeout),
 		mux:               http.NewServeMux(),
 	}
+	positions := services.NewPositionService(empService, deptService)
+	positions.SetAuditLog(auditLog)
+	s.SetPositionService(positions)
 	s.registerRoutes()
 	return s
 }
@@ -26,13 +39,26 @@ func NewServer(empService *services.EmployeeService, deptService *services.Depar
 func (s *Server) registerRoutes() {
 	s.RegisterEmployeeRoutes()
 	s.RegisterDepartmentRoutes()
+	s.RegisterHierarchyRoutes()
+	s.RegisterOrgChartRoutes()
+	s.RegisterExportRoutes()
+	s.RegisterAuditRoutes()
+	s.RegisterScheduleRoutes()
+	s.RegisterPositionRoutes()
+	s.RegisterBatchRoutes()
+	s.RegisterTransactionRoutes()
 	s.RegisterSwaggerRoutes()
 }
 
+// Handler returns the server's routes wrapped in its middleware.
+func (s *Server) Handler() http.Handler {
+	return withRequestID(s.withIdempotency(s.withTransaction(s.mux)))
+}
+
 func (s *Server) Start() {
 	log.Println("Server starting on http://localhost:8080")
 	log.Println("Swagger UI available at http://localhost:8080/swagger")
-	if err := http.ListenAndServe(":8080", s.mux); err != nil {
+	if err := http.ListenAndServe(":8080", s.Handler()); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
@@ -14,7 +14,7 @@ func (s *Server) RegisterSwaggerRoutes() {
 	// Serve the OpenAPI spec
 	s.mux.HandleFunc("GET /api/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
 		if openapiSpec == nil {
-			http.Error(w, "OpenAPI spec not loaded", http.StatusInternalServerError)
+			writeProblem(w, r, newProblem(http.StatusInternalServerError, "OpenAPI spec not loaded"))
 			return
 		}
 		w.Header().Set("Content-Type", "application/yaml")
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"employee-maintenance/services"
+)
+
+const transactionHeader = "X-Transaction-ID"
+
+// DefaultTransactionTimeout is how long a transaction may sit idle before
+// it is rolled back.
+const DefaultTransactionTimeout = 5 * time.Minute
+
+var errTransactionNotFound = errors.New("transaction not found")
+
+func (s *Server) RegisterTransactionRoutes() {
+	s.handle("POST /transactions", s.beginTransaction)
+	s.handle("POST /transactions/{id}/commit", s.commitTransaction)
+	s.handle("POST /transactions/{id}/rollback", s.rollbackTransaction)
+}
+
+// SetTransactionTimeout sets how long a transaction may go without a
+// request before it is rolled back.
+func (s *Server) SetTransactionTimeout(timeout time.Duration) {
+	s.transactions.mu.Lock()
+	defer s.transactions.mu.Unlock()
+	s.transactions.timeout = timeout
+}
+
+type openTransaction struct {
+	tx      *services.Tx
+	expires time.Time
+}
+
+// transactionStore holds the open transactions by ID and rolls back those
+// left idle past the timeout. It is safe for concurrent use.
+type transactionStore struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	open    map[string]*openTransaction
+}
+
+func newTransactionStore(timeout time.Duration) *transactionStore {
+	return &transactionStore{timeout: timeout, open: make(map[string]*openTransaction)}
+}
+
+// add registers tx and returns its ID and when it expires if unused.
+func (ts *transactionStore) add(tx *services.Tx) (string, time.Time) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.sweep()
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	expires := time.Now().Add(ts.timeout)
+	ts.open[id] = &openTransaction{tx: tx, expires: expires}
+	return id, expires
+}
+
+// get returns the open transaction id and extends its life.
+func (ts *transactionStore) get(id string) (*services.Tx, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.sweep()
+	open, ok := ts.open[id]
+	if !ok {
+		return nil, errTransactionNotFound
+	}
+	open.expires = time.Now().Add(ts.timeout)
+	return open.tx, nil
+}
+
+// take removes the open transaction id so that it can be ended.
+func (ts *transactionStore) take(id string) (*services.Tx, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.sweep()
+	open, ok := ts.open[id]
+	if !ok {
+		return nil, errTransactionNotFound
+	}
+	delete(ts.open, id)
+	return open.tx, nil
+}
+
+// sweep rolls back expired transactions. ts.mu must be held.
+func (ts *transactionStore) sweep() {
+	now := time.Now()
+	for id, open := range ts.open {
+		if now.After(open.expires) {
+			open.tx.Rollback()
+			delete(ts.open, id)
+		}
+	}
+}
+
+type transactionKey struct{}
+
+// withTransaction runs a request carrying X-Transaction-ID inside that
+// transaction: its reads and writes go through the transaction's services.
+func (s *Server) withTransaction(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(transactionHeader)
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tx, err := s.transactions.get(id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), transactionKey{}, tx)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// employees returns the employee service for r: the transaction's if r runs
+// in one, otherwise the server's.
+func (s *Server) employees(r *http.Request) *services.EmployeeService {
+	if tx, ok := r.Context().Value(transactionKey{}).(*services.Tx); ok {
+		return tx.Employees()
+	}
+	return s.employeeService
+}
+
+// departments is employees for the department service.
+func (s *Server) departments(r *http.Request) *services.DepartmentService {
+	if tx, ok := r.Context().Value(transactionKey{}).(*services.Tx); ok {
+		return tx.Departments()
+	}
+	return s.departmentService
+}
+
+type transactionResponse struct {
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (s *Server) beginTransaction(w http.ResponseWriter, r *http.Request) {
+	tx := services.Begin(s.employeeService, s.departmentService)
+	id, expires := s.transactions.add(tx)
+	w.Header().Set("Location", "/transactions/"+id)
+	writeResponse(w, r, transactionResponse{ID: id, ExpiresAt: expires.UTC()})
+}
+
+func (s *Server) commitTransaction(w http.ResponseWriter, r *http.Request) {
+	tx, err := s.transactions.take(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) rollbackTransaction(w http.ResponseWriter, r *http.Request) {
+	tx, err := s.transactions.take(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := tx.Rollback(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+// AsOf selects a point in the history kept by the audit log. Time is the
+// instant whose state is wanted, compared against when changes took effect.
+// KnownAt, when set, leaves out changes recorded after it, answering "what
+// did we believe at KnownAt about Time". The zero AsOf means the current
+// state.
+type AsOf struct {
+	Time    time.Time
+	KnownAt time.Time
+}
+
+// ParseAsOf reads asOf and knownAt (RFC 3339) from query parameters. A
+// knownAt on its own also serves as asOf.
+func ParseAsOf(q url.Values) (AsOf, error) {
+	var at AsOf
+	var err error
+	if at.Time, err = timeParam(q, "asOf"); err != nil {
+		return at, err
+	}
+	if at.KnownAt, err = timeParam(q, "knownAt"); err != nil {
+		return at, err
+	}
+	if at.Time.IsZero() && !at.KnownAt.IsZero() {
+		at.Time = at.KnownAt
+	}
+	return at, nil
+}
+
+// Encode writes at into q in the form ParseAsOf reads.
+func (at AsOf) Encode(q url.Values) {
+	setTime(q, "asOf", at.Time)
+	setTime(q, "knownAt", at.KnownAt)
+}
+
+func (at AsOf) IsZero() bool {
+	return at.Time.IsZero() && at.KnownAt.IsZero()
+}
+
+func effectiveTime(e models.AuditEntry) time.Time {
+	if e.EffectiveTime.IsZero() {
+		return e.Time
+	}
+	return e.EffectiveTime
+}
+
+// effectiveBefore orders entries by when they took effect, then by the order
+// they were recorded in.
+func effectiveBefore(a, b models.AuditEntry) bool {
+	ta, tb := effectiveTime(a), effectiveTime(b)
+	if !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	return a.ID < b.ID
+}
+
+// stateAt returns the JSON of one record as it was at the given point, or nil
+// if it did not exist or was deleted then. The record is the After image of
+// the last change in effect at at.Time; before its first change it is that
+// change's Before image, which covers records that predate the audit log.
+// known is false if the record has no history at all.
+func (a *AuditLog) stateAt(entity string, id int, at AsOf) (state json.RawMessage, known bool) {
+	indices := a.byRecord[recordKey{entity, id}]
+	if len(indices) == 0 {
+		return nil, false
+	}
+	var first, last *models.AuditEntry
+	for _, idx := range indices {
+		e := &a.entries[idx]
+		if first == nil || effectiveBefore(*e, *first) {
+			first = e
+		}
+		if !at.KnownAt.IsZero() && e.Time.After(at.KnownAt) {
+			continue
+		}
+		if effectiveTime(*e).After(at.Time) {
+			continue
+		}
+		if last == nil || effectiveBefore(*last, *e) {
+			last = e
+		}
+	}
+	switch {
+	case last != nil && (last.Operation == OperationDelete || last.Operation == OperationPurge):
+		return nil, true
+	case last != nil:
+		return last.After, true
+	case first.Operation == OperationRestore || first.Operation == OperationPurge:
+		return nil, true
+	default:
+		return first.Before, true
+	}
+}
+
+// recordAsOf reconstructs one record from the audit log. current is the
+// record as stored now, used when the log has no history for it.
+func recordAsOf[T any](a *AuditLog, entity string, id int, at AsOf, current T, exists bool) (T, bool, error) {
+	var zero T
+	if a == nil {
+		return zero, false, fmt.Errorf("%w: asOf requires the audit log", ErrInvalidQuery)
+	}
+	a.mu.RLock()
+	state, known := a.stateAt(entity, id, at)
+	a.mu.RUnlock()
+	if !known {
+		return current, exists, nil
+	}
+	if state == nil {
+		return zero, false, nil
+	}
+	var v T
+	if err := json.Unmarshal(state, &v); err != nil {
+		return zero, false, fmt.Errorf("failed to decode %s %d from the audit log: %w", entity, id, err)
+	}
+	return v, true, nil
+}
+
+// recordsAsOf reconstructs every record of entity that existed at the given
+// point, sorted by ID. current holds the records as stored now.
+func recordsAsOf[T any](a *AuditLog, entity string, at AsOf, current []T, id func(T) int) ([]T, error) {
+	if a == nil {
+		return nil, fmt.Errorf("%w: asOf requires the audit log", ErrInvalidQuery)
+	}
+	byID := make(map[int]T, len(current))
+	for _, v := range current {
+		byID[id(v)] = v
+	}
+
+	a.mu.RLock()
+	states := make(map[int]json.RawMessage)
+	for key := range a.byRecord {
+		if key.entity == entity {
+			states[key.id], _ = a.stateAt(entity, key.id, at)
+		}
+	}
+	a.mu.RUnlock()
+
+	for recID, state := range states {
+		if state == nil {
+			delete(byID, recID)
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(state, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode %s %d from the audit log: %w", entity, recID, err)
+		}
+		byID[recID] = v
+	}
+
+	result := make([]T, 0, len(byID))
+	for _, v := range byID {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool { return id(result[i]) < id(result[j]) })
+	return result, nil
+}
+
+// Diff lists the records added, removed and changed between two instants.
+type Diff[T any] struct {
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Added   []T            `json:"added"`
+	Removed []T            `json:"removed"`
+	Changed []RecordChange `json:"changed"`
+}
+
+// RecordChange is a record present at both ends of a Diff whose fields
+// differ.
+type RecordChange struct {
+	ID      int                  `json:"id"`
+	Changes []models.FieldChange `json:"changes"`
+}
+
+func diffRecords[T any](from, to time.Time, before, after []T, id func(T) int) (Diff[T], error) {
+	d := Diff[T]{From: from, To: to, Added: []T{}, Removed: []T{}, Changed: []RecordChange{}}
+	old := make(map[int]T, len(before))
+	for _, v := range before {
+		old[id(v)] = v
+	}
+	for _, v := range after {
+		prev, existed := old[id(v)]
+		if !existed {
+			d.Added = append(d.Added, v)
+			continue
+		}
+		delete(old, id(v))
+		prevJSON, err := json.Marshal(prev)
+		if err != nil {
+			return d, err
+		}
+		curJSON, err := json.Marshal(v)
+		if err != nil {
+			return d, err
+		}
+		if changes := diff(prevJSON, curJSON); len(changes) > 0 {
+			d.Changed = append(d.Changed, RecordChange{ID: id(v), Changes: changes})
+		}
+	}
+	for _, v := range before {
+		if _, removed := old[id(v)]; removed {
+			d.Removed = append(d.Removed, v)
+		}
+	}
+	return d, nil
+}
+
+// ParseDiffRange reads the required from and to (RFC 3339) query parameters.
+func ParseDiffRange(q url.Values) (from, to time.Time, err error) {
+	if from, err = timeParam(q, "from"); err != nil {
+		return
+	}
+	if to, err = timeParam(q, "to"); err != nil {
+		return
+	}
+	if from.IsZero() || to.IsZero() {
+		err = fmt.Errorf("%w: from and to are required", ErrInvalidQuery)
+	}
+	return
+}
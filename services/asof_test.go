@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+// clock lets a test step the audit log's time forward.
+type clock struct{ now time.Time }
+
+// tick returns the current time and moves the clock on by an hour, so the
+// returned instant includes every change made so far and none made after.
+func (c *clock) tick() time.Time {
+	t := c.now
+	c.now = c.now.Add(time.Hour)
+	return t
+}
+
+func newClockedServices() (*EmployeeService, *DepartmentService, *clock) {
+	emps, depts, log := newAuditedServices()
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	log.now = func() time.Time { return c.now }
+	return emps, depts, c
+}
+
+func TestEmployeeService_RetrieveAsOf(t *testing.T) {
+	emps, depts, c := newClockedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	beforeCreate := c.tick()
+	c.tick()
+	emp, _ := emps.Create(ctx, testEmployee(0, 1))
+	created := c.tick()
+	emp.Email = "jd@example.com"
+	emps.Update(ctx, emp)
+	updated := c.tick()
+	emps.Delete(ctx, emp.ID)
+
+	if _, err := emps.RetrieveAsOf(emp.ID, AsOf{Time: beforeCreate}); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("RetrieveAsOf(before create) error = %v, want %v", err, ErrEmployeeNotFound)
+	}
+	got, err := emps.RetrieveAsOf(emp.ID, AsOf{Time: created})
+	if err != nil || got.Email != "john.doe@example.com" || got.Version != 1 {
+		t.Errorf("RetrieveAsOf(after create) = %+v, %v", got, err)
+	}
+	got, err = emps.RetrieveAsOf(emp.ID, AsOf{Time: updated})
+	if err != nil || got.Email != "jd@example.com" {
+		t.Errorf("RetrieveAsOf(after update) = %+v, %v", got, err)
+	}
+	if _, err := emps.RetrieveAsOf(emp.ID, AsOf{Time: c.tick()}); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("RetrieveAsOf(after delete) error = %v, want %v", err, ErrEmployeeNotFound)
+	}
+}
+
+func TestEmployeeService_ListAsOf(t *testing.T) {
+	emps, depts, c := newClockedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+	c.tick()
+	emps.Create(ctx, testEmployee(2, 1))
+	middle := c.tick()
+	emps.Delete(ctx, 1)
+
+	page, err := emps.List(EmployeeFilter{AsOf: AsOf{Time: middle}}, ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := ids(page.Items); !equalInts(got, []int{1, 2}) {
+		t.Errorf("List(asOf) ids = %v, want [1 2]", got)
+	}
+	page, _ = emps.List(EmployeeFilter{}, ListOptions{})
+	if got := ids(page.Items); !equalInts(got, []int{2}) {
+		t.Errorf("List() ids = %v, want [2]", got)
+	}
+}
+
+func TestEmployeeService_KnownAt(t *testing.T) {
+	emps, depts, c := newClockedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+	known := c.tick()
+	c.tick()
+	emps.Create(ctx, testEmployee(2, 1))
+
+	page, _ := emps.List(EmployeeFilter{AsOf: AsOf{Time: c.tick(), KnownAt: known}}, ListOptions{})
+	if got := ids(page.Items); !equalInts(got, []int{1}) {
+		t.Errorf("List(knownAt) ids = %v, want [1]", got)
+	}
+}
+
+func TestEmployeeService_RecordsWithoutHistory(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+	log := NewAuditLog()
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+	past := time.Now().Add(-time.Hour)
+
+	got, err := emps.RetrieveAsOf(1, AsOf{Time: past})
+	if err != nil || got.ID != 1 {
+		t.Errorf("RetrieveAsOf() = %+v, %v; want the current record", got, err)
+	}
+
+	emps.Patch(ctx, 1, MergePatch(`{"email":"jd@example.com"}`), 0)
+	got, _ = emps.RetrieveAsOf(1, AsOf{Time: past})
+	if got.Email != "john.doe@example.com" {
+		t.Errorf("RetrieveAsOf() email = %q, want the state before the first recorded change", got.Email)
+	}
+}
+
+func TestEmployeeService_AsOfWithoutAuditLog(t *testing.T) {
+	emps := NewEmployeeService()
+	_, err := emps.RetrieveAsOf(1, AsOf{Time: time.Now()})
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("RetrieveAsOf() error = %v, want %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestEmployeeService_Diff(t *testing.T) {
+	emps, depts, c := newClockedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Sales"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Create(ctx, testEmployee(2, 1))
+	emps.Create(ctx, testEmployee(3, 1))
+	from := c.tick()
+	emps.Delete(ctx, 1)
+	emps.Patch(ctx, 2, MergePatch(`{"departmentId":2}`), 0)
+	emps.Patch(ctx, 3, MergePatch(`{"departmentId":2}`), 0)
+	emps.Patch(ctx, 3, MergePatch(`{"departmentId":1}`), 0)
+	emps.Create(ctx, testEmployee(4, 2))
+	to := c.tick()
+
+	d, err := emps.Diff(from, to)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if got := ids(d.Added); !equalInts(got, []int{4}) {
+		t.Errorf("Added = %v, want [4]", got)
+	}
+	if got := ids(d.Removed); !equalInts(got, []int{1}) {
+		t.Errorf("Removed = %v, want [1]", got)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].ID != 2 || d.Changed[0].Changes[0].Field != "departmentId" {
+		t.Errorf("Changed = %+v, want only employee 2's departmentId", d.Changed)
+	}
+}
+
+func TestDepartmentService_RetrieveAsOf(t *testing.T) {
+	_, depts, c := newClockedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	named := c.tick()
+	depts.Update(ctx, models.Department{ID: 1, Name: "R&D"})
+
+	got, err := depts.RetrieveAsOf(1, AsOf{Time: named})
+	if err != nil || got.Name != "Engineering" {
+		t.Errorf("RetrieveAsOf() = %+v, %v; want Engineering", got, err)
+	}
+	page, _ := depts.List(DepartmentFilter{NamePrefix: "eng", AsOf: AsOf{Time: named}}, ListOptions{})
+	if page.Total != 1 {
+		t.Errorf("List(asOf) total = %d, want 1", page.Total)
+	}
+}
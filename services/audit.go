@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+// Entities and operations recorded in the audit log.
+const (
+	EntityEmployee   = "employee"
+	EntityDepartment = "department"
+	EntityPosition   = "position"
+
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+)
+
+// writeOperation names a write by whether the record existed before it and
+// whether it was deleted on either side of it.
+func writeOperation(existed bool, wasDeleted, isDeleted *time.Time) string {
+	switch {
+	case !existed:
+		return OperationCreate
+	case wasDeleted == nil && isDeleted != nil:
+		return OperationDelete
+	case wasDeleted != nil && isDeleted == nil:
+		return OperationRestore
+	default:
+		return OperationUpdate
+	}
+}
+
+// AnonymousActor is recorded for changes made without an actor in the
+// context.
+const AnonymousActor = "anonymous"
+
+type actorKey struct{}
+type requestIDKey struct{}
+type scheduleKey struct{}
+
+// WithActor returns a context that attributes changes to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, or AnonymousActor.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+// WithRequestID returns a context that tags changes with a request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the ID set by WithRequestID.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// schedule is when a scheduled change took effect and was decided.
+type schedule struct {
+	effective, decided time.Time
+}
+
+// withSchedule returns a context whose changes are recorded as taking
+// effect at effective, having been decided at decided.
+func withSchedule(ctx context.Context, effective, decided time.Time) context.Context {
+	return context.WithValue(ctx, scheduleKey{}, schedule{effective.UTC(), decided.UTC()})
+}
+
+type recordKey struct {
+	entity string
+	id     int
+}
+
+// AuditLog is the append-only record of every change made through the
+// services it is attached to. Entries are persisted to a journal and kept in
+// memory for querying.
+type AuditLog struct {
+	mu       sync.RWMutex
+	journal  storage.Journal[models.AuditEntry]
+	entries  []models.AuditEntry
+	byRecord map[recordKey][]int
+	now      func() time.Time
+}
+
+func NewAuditLog() *AuditLog {
+	return NewAuditLogWithJournal(storage.NewMemoryJournal[models.AuditEntry]())
+}
+
+func NewAuditLogWithJournal(journal storage.Journal[models.AuditEntry]) *AuditLog {
+	a := &AuditLog{
+		journal:  journal,
+		byRecord: make(map[recordKey][]int),
+		now:      time.Now,
+	}
+	for _, entry := range journal.Entries() {
+		a.index(entry)
+	}
+	return a
+}
+
+func (a *AuditLog) index(entry models.AuditEntry) {
+	key := recordKey{entry.Entity, entry.EntityID}
+	a.byRecord[key] = append(a.byRecord[key], len(a.entries))
+	a.entries = append(a.entries, entry)
+}
+
+// record appends an entry describing the change of one record from before to
+// after. before is nil for a create and after is nil for a purge, or for the
+// delete of a position, which leaves no tombstone.
+func (a *AuditLog) record(ctx context.Context, op, entity string, id int, before, after any) error {
+	if a == nil {
+		return nil
+	}
+	now := a.now().UTC()
+	effective := now
+	var decided time.Time
+	if sched, ok := ctx.Value(scheduleKey{}).(schedule); ok {
+		effective, decided = sched.effective, sched.decided
+	}
+	entry := models.AuditEntry{
+		Time:          now,
+		EffectiveTime: effective,
+		DecidedAt:     decided,
+		Actor:         ActorFrom(ctx),
+		RequestID:     RequestIDFrom(ctx),
+		Entity:        entity,
+		EntityID:      id,
+		Operation:     op,
+	}
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+	entry.Changes = diff(entry.Before, entry.After)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.add(entry)
+}
+
+// add numbers entry, writes it to the journal and indexes it. a.mu must be
+// held.
+func (a *AuditLog) add(entry models.AuditEntry) error {
+	entry.ID = len(a.entries) + 1
+	if err := a.journal.Append(entry); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	a.index(entry)
+	return nil
+}
+
+// stage returns an empty log that collects entries for changes that are
+// not made yet, to be added to a by commitStaged.
+func (a *AuditLog) stage() *AuditLog {
+	if a == nil {
+		return nil
+	}
+	staged := NewAuditLog()
+	staged.now = a.now
+	return staged
+}
+
+// commitStaged adds the entries collected in staged, in order, writing them
+// to the journal in one append so that either all of them are kept or none
+// are. They are recorded, and take effect, when they are committed rather
+// than when they were staged, since no one saw the changes before then; the
+// effective time of a scheduled change is kept.
+func (a *AuditLog) commitStaged(staged *AuditLog) error {
+	if a == nil {
+		return nil
+	}
+	if len(staged.entries) == 0 {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now().UTC()
+	entries := make([]models.AuditEntry, len(staged.entries))
+	for i, entry := range staged.entries {
+		entry.ID = len(a.entries) + i + 1
+		entry.Time = now
+		if entry.DecidedAt.IsZero() {
+			entry.EffectiveTime = now
+		}
+		entries[i] = entry
+	}
+	if err := a.journal.Append(entries...); err != nil {
+		return fmt.Errorf("failed to record audit entries: %w", err)
+	}
+	for _, entry := range entries {
+		a.index(entry)
+	}
+	return nil
+}
+
+// restore puts back the value a repository held for id before a write whose
+// audit entry could not be recorded: prev if it existed, nothing otherwise.
+func restore[T any](repo storage.Repository[T], id int, prev T, existed bool) {
+	if existed {
+		repo.Put(id, prev)
+	} else {
+		repo.Delete(id)
+	}
+}
+
+// diff lists the top-level fields that differ between two JSON objects. The
+// version is left out since it changes on every write.
+func diff(before, after json.RawMessage) []models.FieldChange {
+	var b, a map[string]any
+	if len(before) > 0 {
+		json.Unmarshal(before, &b)
+	}
+	if len(after) > 0 {
+		json.Unmarshal(after, &a)
+	}
+	fields := make(map[string]bool)
+	for k := range b {
+		fields[k] = true
+	}
+	for k := range a {
+		fields[k] = true
+	}
+	delete(fields, "version")
+
+	var changes []models.FieldChange
+	for field := range fields {
+		bv, av := b[field], a[field]
+		bj, _ := json.Marshal(bv)
+		aj, _ := json.Marshal(av)
+		if string(bj) != string(aj) {
+			changes = append(changes, models.FieldChange{Field: field, Before: bv, After: av})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// History returns every entry for one record, oldest first.
+func (a *AuditLog) History(entity string, id int) []models.AuditEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	indices := a.byRecord[recordKey{entity, id}]
+	result := make([]models.AuditEntry, len(indices))
+	for i, idx := range indices {
+		result[i] = a.entries[idx]
+	}
+	return result
+}
+
+// AuditFilter narrows a Query. Zero values match everything; Since is
+// inclusive and Until exclusive.
+type AuditFilter struct {
+	Entity    string
+	EntityID  int
+	Actor     string
+	Operation string
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+}
+
+// ParseAuditFilter reads entity, entityId, actor, operation, requestId, since
+// and until (RFC 3339) from query parameters.
+func ParseAuditFilter(q url.Values) (AuditFilter, error) {
+	f := AuditFilter{
+		Entity:    q.Get("entity"),
+		Actor:     q.Get("actor"),
+		Operation: q.Get("operation"),
+		RequestID: q.Get("requestId"),
+	}
+	var err error
+	if f.EntityID, err = intParam(q, "entityId"); err != nil {
+		return f, err
+	}
+	if f.Since, err = timeParam(q, "since"); err != nil {
+		return f, err
+	}
+	if f.Until, err = timeParam(q, "until"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+func (f AuditFilter) matches(e models.AuditEntry) bool {
+	switch {
+	case f.Entity != "" && e.Entity != f.Entity,
+		f.EntityID != 0 && e.EntityID != f.EntityID,
+		f.Actor != "" && e.Actor != f.Actor,
+		f.Operation != "" && e.Operation != f.Operation,
+		f.RequestID != "" && e.RequestID != f.RequestID,
+		!f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+var auditSortFields = sortFields[models.AuditEntry]{
+	"id": func(e models.AuditEntry) any { return e.ID },
+}
+
+// Query returns the page of entries matching filter selected by opts.
+// Entries are numbered in the order they were recorded, so sorting by id is
+// chronological.
+func (a *AuditLog) Query(filter AuditFilter, opts ListOptions) (Page[models.AuditEntry], error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var matched []models.AuditEntry
+	for _, e := range a.entries {
+		if filter.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	return paginate(matched, func(e models.AuditEntry) int { return e.ID }, auditSortFields, opts)
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+// ctx is the context the tests pass to service writes.
+var ctx = context.Background()
+
+var errStorage = errors.New("storage failed")
+
+// failingRepo is a Memory whose writes fail while fail is set, and whose
+// writes of failID always fail.
+type failingRepo[T any] struct {
+	*storage.Memory[T]
+	fail   bool
+	failID int
+}
+
+func (r *failingRepo[T]) Put(id int, value T) error {
+	if r.fail || (id != 0 && id == r.failID) {
+		return errStorage
+	}
+	return r.Memory.Put(id, value)
+}
+
+func (r *failingRepo[T]) Delete(id int) error {
+	if r.fail || (id != 0 && id == r.failID) {
+		return errStorage
+	}
+	return r.Memory.Delete(id)
+}
+
+// failingJournal is a MemoryJournal whose appends fail while fail is set,
+// or if they would take it past limit entries when that is set.
+type failingJournal[T any] struct {
+	*storage.MemoryJournal[T]
+	fail  bool
+	limit int
+}
+
+func (j *failingJournal[T]) Append(values ...T) error {
+	if j.fail || (j.limit != 0 && len(j.Entries())+len(values) > j.limit) {
+		return errStorage
+	}
+	return j.MemoryJournal.Append(values...)
+}
+
+func newAuditedServices() (*EmployeeService, *DepartmentService, *AuditLog) {
+	emps, depts := newLinkedServices()
+	log := NewAuditLog()
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+	return emps, depts, log
+}
+
+func TestAuditLog_RecordsEmployeeHistory(t *testing.T) {
+	emps, depts, log := newAuditedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Sales"})
+
+	actx := WithRequestID(WithActor(ctx, "alice"), "req-1")
+	emp, _ := emps.Create(actx, testEmployee(0, 1))
+	emp.DepartmentID = 2
+	emps.Update(actx, emp)
+	emps.Delete(actx, emp.ID)
+
+	history := log.History(EntityEmployee, emp.ID)
+	if len(history) != 3 {
+		t.Fatalf("History() returned %d entries, want 3", len(history))
+	}
+	ops := []string{history[0].Operation, history[1].Operation, history[2].Operation}
+	if ops[0] != OperationCreate || ops[1] != OperationUpdate || ops[2] != OperationDelete {
+		t.Errorf("History() operations = %v", ops)
+	}
+	update := history[1]
+	if update.Actor != "alice" || update.RequestID != "req-1" {
+		t.Errorf("update entry actor = %q, request id = %q", update.Actor, update.RequestID)
+	}
+	if len(update.Changes) != 1 || update.Changes[0].Field != "departmentId" {
+		t.Fatalf("update entry changes = %+v, want only departmentId", update.Changes)
+	}
+	if update.Changes[0].Before != float64(1) || update.Changes[0].After != float64(2) {
+		t.Errorf("departmentId change = %+v, want 1 -> 2", update.Changes[0])
+	}
+	if len(history[2].Changes) != 1 || history[2].Changes[0].Field != "deletedAt" {
+		t.Errorf("delete entry changes = %+v, want only deletedAt", history[2].Changes)
+	}
+}
+
+func TestAuditLog_CascadeIsAttributedToCaller(t *testing.T) {
+	emps, depts, log := newAuditedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	depts.DeleteWithOptions(WithActor(ctx, "bob"), 1, DeleteOptions{Policy: DeleteCascade})
+
+	page, err := log.Query(AuditFilter{Actor: "bob"}, ListOptions{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if page.Total != 2 {
+		t.Fatalf("Query() total = %d, want 2", page.Total)
+	}
+	if page.Items[0].Entity != EntityEmployee || page.Items[1].Entity != EntityDepartment {
+		t.Errorf("Query() entities = %s, %s", page.Items[0].Entity, page.Items[1].Entity)
+	}
+}
+
+func TestAuditLog_DefaultsToAnonymousActor(t *testing.T) {
+	_, depts, log := newAuditedServices()
+	depts.Create(ctx, models.Department{Name: "Engineering"})
+
+	if got := log.History(EntityDepartment, 1)[0].Actor; got != AnonymousActor {
+		t.Errorf("Actor = %q, want %q", got, AnonymousActor)
+	}
+}
+
+func TestAuditLog_QueryByTime(t *testing.T) {
+	_, depts, log := newAuditedServices()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	log.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		depts.Create(ctx, models.Department{Name: "Engineering"})
+		now = now.Add(time.Hour)
+	}
+
+	page, _ := log.Query(AuditFilter{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}, ListOptions{})
+	if page.Total != 1 || page.Items[0].EntityID != 2 {
+		t.Errorf("Query() = %+v, want only department 2", page.Items)
+	}
+}
+
+func TestAuditLog_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	journal, err := storage.OpenJournal[models.AuditEntry](path)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	log := NewAuditLogWithJournal(journal)
+	depts := NewDepartmentService()
+	depts.SetAuditLog(log)
+	depts.Create(ctx, models.Department{Name: "Engineering"})
+	journal.Close()
+
+	journal, err = storage.OpenJournal[models.AuditEntry](path)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	defer journal.Close()
+	reopened := NewAuditLogWithJournal(journal)
+	history := reopened.History(EntityDepartment, 1)
+	if len(history) != 1 || history[0].ID != 1 {
+		t.Fatalf("History() after reopen = %+v", history)
+	}
+	depts.SetAuditLog(reopened)
+	depts.Update(ctx, models.Department{ID: 1, Name: "R&D"})
+	if got := reopened.History(EntityDepartment, 1); len(got) != 2 || got[1].ID != 2 {
+		t.Errorf("History() after update = %+v, want entry 2 appended", got)
+	}
+}
+
+func TestAuditLog_RecordsOnlyStoredChanges(t *testing.T) {
+	repo := &failingRepo[models.Employee]{Memory: storage.NewMemory[models.Employee]()}
+	journal := &failingJournal[models.AuditEntry]{MemoryJournal: storage.NewMemoryJournal[models.AuditEntry]()}
+	emps, depts := NewEmployeeServiceWithRepository(repo), NewDepartmentService()
+	Link(emps, depts)
+	log := NewAuditLogWithJournal(journal)
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emp, _ := emps.Create(ctx, testEmployee(1, 1))
+
+	repo.fail = true
+	emp.FirstName = "Jane"
+	if _, err := emps.Update(ctx, emp); !errors.Is(err, errStorage) {
+		t.Fatalf("Update(failing repository) error = %v, want %v", err, errStorage)
+	}
+	if n := len(log.History(EntityEmployee, 1)); n != 1 {
+		t.Errorf("history has %d entries, want only the create", n)
+	}
+
+	repo.fail, journal.fail = false, true
+	if _, err := emps.Update(ctx, emp); !errors.Is(err, errStorage) {
+		t.Fatalf("Update(failing journal) error = %v, want %v", err, errStorage)
+	}
+	if got, _ := emps.Retrieve(1); got.FirstName != "John" || got.Version != 1 {
+		t.Errorf("employee = %+v, want the unrecorded update undone", got)
+	}
+}
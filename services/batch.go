@@ -0,0 +1,383 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"employee-maintenance/models"
+)
+
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// MaxBatchOperations caps the number of operations in one batch.
+const MaxBatchOperations = 1000
+
+// Batch operations.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchPatch  = "patch"
+	BatchDelete = "delete"
+)
+
+// BatchID names the record an operation applies to: a stored ID, or the
+// ref of a record created earlier in the same batch. In JSON it is a number
+// or a string, with refs written as "$ref".
+type BatchID struct {
+	ID  int
+	Ref string
+}
+
+func (b BatchID) MarshalJSON() ([]byte, error) {
+	if b.Ref != "" {
+		return json.Marshal("$" + b.Ref)
+	}
+	return json.Marshal(b.ID)
+}
+
+func (b *BatchID) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	id, ok := parseBatchID(v)
+	if !ok {
+		return fmt.Errorf("%w: id must be a positive integer or a \"$ref\"", ErrInvalidBatch)
+	}
+	*b = id
+	return nil
+}
+
+func parseBatchID(v any) (BatchID, bool) {
+	switch v := v.(type) {
+	case float64:
+		if v > 0 && v == float64(int(v)) {
+			return BatchID{ID: int(v)}, true
+		}
+	case json.Number:
+		if id, err := strconv.Atoi(v.String()); err == nil && id > 0 {
+			return BatchID{ID: id}, true
+		}
+	case string:
+		if ref, ok := strings.CutPrefix(v, "$"); ok && ref != "" {
+			return BatchID{Ref: ref}, true
+		}
+		if id, err := strconv.Atoi(v); err == nil && id > 0 {
+			return BatchID{ID: id}, true
+		}
+	}
+	return BatchID{}, false
+}
+
+// BatchOperation is one step of a batch. Body is the record for a create or
+// update, a merge patch (an object) or JSON patch (an array) for a patch,
+// and optional delete options for a delete: policy and reassignTo for a
+// department, reassignReportsTo for an employee. A create may name its
+// record with Ref so that later operations can use "$ref" wherever an ID
+// goes: as ID, and in body fields named id, ending in Id, or reassignTo and
+// reassignReportsTo.
+type BatchOperation struct {
+	Op        string          `json:"op"`
+	Entity    string          `json:"entity"`
+	ID        BatchID         `json:"id,omitzero"`
+	Ref       string          `json:"ref,omitempty"`
+	IfVersion int             `json:"ifVersion,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResult is the outcome of one operation. Record is the employee or
+// department as written, and is nil for a delete.
+type BatchResult struct {
+	Op     string `json:"op"`
+	Entity string `json:"entity"`
+	ID     int    `json:"id"`
+	Ref    string `json:"ref,omitempty"`
+	Record any    `json:"record,omitempty"`
+}
+
+// BatchError reports the operation that failed a batch, which then had no
+// effect. It wraps that operation's error.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch runs operations on employees and departments as a unit: they are
+// applied in order, each seeing the effect of the ones before, and either
+// all of them take effect or none do. Both services are locked for writing
+// while a batch runs, so no other change interleaves and readers never see
+// part of one. Either service may be nil if no operation needs it.
+type Batch struct {
+	Employees   *EmployeeService
+	Departments *DepartmentService
+}
+
+// Run applies ops and returns a result for each. If one fails, nothing is
+// changed and the error is a *BatchError naming it.
+func (b Batch) Run(ctx context.Context, ops []BatchOperation) ([]BatchResult, error) {
+	if len(ops) > MaxBatchOperations {
+		return nil, fmt.Errorf("%w: at most %d operations are allowed", ErrInvalidBatch, MaxBatchOperations)
+	}
+	unlock := lockPair(b.Employees, b.Departments)
+	defer unlock()
+
+	st := newStaging(b.Employees, b.Departments)
+	results, err := st.run(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.commit(b.Employees, b.Departments); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// batchRef is a record created earlier in a batch.
+type batchRef struct {
+	entity string
+	id     int
+}
+
+// run applies ops to the staged services.
+func (st *staging) run(ctx context.Context, ops []BatchOperation) ([]BatchResult, error) {
+	refs := make(map[string]batchRef)
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		result, err := st.apply(ctx, op, refs)
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+func (st *staging) apply(ctx context.Context, op BatchOperation, refs map[string]batchRef) (BatchResult, error) {
+	result := BatchResult{Op: op.Op, Entity: op.Entity}
+	switch {
+	case op.Entity == EntityEmployee && st.employees == nil,
+		op.Entity == EntityDepartment && st.departments == nil,
+		op.Entity != EntityEmployee && op.Entity != EntityDepartment:
+		return result, fmt.Errorf("%w: entity must be employee or department", ErrInvalidBatch)
+	}
+
+	body, err := resolveRefs(op.Body, refs)
+	if err != nil {
+		return result, err
+	}
+	if op.Op == BatchCreate {
+		if op.ID != (BatchID{}) {
+			return result, fmt.Errorf("%w: create takes no id; set it in the body", ErrInvalidBatch)
+		}
+		if _, taken := refs[op.Ref]; taken && op.Ref != "" {
+			return result, fmt.Errorf("%w: ref %q is already defined", ErrInvalidBatch, op.Ref)
+		}
+		result.Record, result.ID, err = st.create(ctx, op.Entity, body)
+		if err == nil && op.Ref != "" {
+			refs[op.Ref] = batchRef{op.Entity, result.ID}
+			result.Ref = op.Ref
+		}
+		return result, err
+	}
+
+	if op.Ref != "" {
+		return result, fmt.Errorf("%w: only a create can define a ref", ErrInvalidBatch)
+	}
+	result.ID, err = lookupRef(op.ID, op.Entity, refs)
+	if err != nil {
+		return result, err
+	}
+	switch op.Op {
+	case BatchUpdate:
+		result.Record, err = st.update(ctx, op.Entity, result.ID, op.IfVersion, body)
+	case BatchPatch:
+		result.Record, err = st.patch(ctx, op.Entity, result.ID, op.IfVersion, body)
+	case BatchDelete:
+		err = st.delete(ctx, op.Entity, result.ID, op.IfVersion, body)
+	default:
+		err = fmt.Errorf("%w: op must be create, update, patch or delete", ErrInvalidBatch)
+	}
+	return result, err
+}
+
+// lookupRef returns the stored ID an operation's id names.
+func lookupRef(id BatchID, entity string, refs map[string]batchRef) (int, error) {
+	if id.Ref == "" {
+		if id.ID == 0 {
+			return 0, fmt.Errorf("%w: id is required", ErrInvalidBatch)
+		}
+		return id.ID, nil
+	}
+	ref, ok := refs[id.Ref]
+	if !ok {
+		return 0, fmt.Errorf("%w: ref %q is not defined by an earlier create", ErrInvalidBatch, id.Ref)
+	}
+	if ref.entity != entity {
+		return 0, fmt.Errorf("%w: ref %q is a %s", ErrInvalidBatch, id.Ref, ref.entity)
+	}
+	return ref.id, nil
+}
+
+// resolveRefs replaces "$ref" strings in the ID fields of body with the IDs
+// they name. In a JSON patch, the value of an operation whose path ends in
+// such a field is resolved too.
+func resolveRefs(body json.RawMessage, refs map[string]batchRef) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(body)) == 0 || !bytes.Contains(body, []byte(`"$`)) {
+		return body, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: body: %v", ErrInvalidBatch, err)
+	}
+	if err := resolveValue(v, refs); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func resolveValue(v any, refs map[string]batchRef) error {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if err := resolveValue(item, refs); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		if path, ok := v["path"].(string); ok {
+			if _, hasValue := v["value"]; hasValue && isIDField(path[strings.LastIndex(path, "/")+1:]) {
+				if err := resolveField(v, "value", refs); err != nil {
+					return err
+				}
+			}
+		}
+		for key, child := range v {
+			if isIDField(key) {
+				if err := resolveField(v, key, refs); err != nil {
+					return err
+				}
+			} else if err := resolveValue(child, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resolveField(obj map[string]any, key string, refs map[string]batchRef) error {
+	s, ok := obj[key].(string)
+	if !ok {
+		return nil
+	}
+	ref, isRef := strings.CutPrefix(s, "$")
+	if !isRef {
+		return nil
+	}
+	target, ok := refs[ref]
+	if !ok {
+		return fmt.Errorf("%w: ref %q is not defined by an earlier create", ErrInvalidBatch, ref)
+	}
+	obj[key] = target.id
+	return nil
+}
+
+func isIDField(name string) bool {
+	return name == "id" || strings.HasSuffix(name, "Id") || name == "reassignTo" || name == "reassignReportsTo"
+}
+
+func decodeBody(body json.RawMessage, v any) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return fmt.Errorf("%w: body is required", ErrInvalidBatch)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: body: %v", ErrInvalidBatch, err)
+	}
+	return nil
+}
+
+func (st *staging) create(ctx context.Context, entity string, body json.RawMessage) (any, int, error) {
+	if entity == EntityEmployee {
+		var emp models.Employee
+		if err := decodeBody(body, &emp); err != nil {
+			return nil, 0, err
+		}
+		created, err := st.employees.Create(ctx, emp)
+		return created, created.ID, err
+	}
+	var dept models.Department
+	if err := decodeBody(body, &dept); err != nil {
+		return nil, 0, err
+	}
+	created, err := st.departments.Create(ctx, dept)
+	return created, created.ID, err
+}
+
+func (st *staging) update(ctx context.Context, entity string, id, ifVersion int, body json.RawMessage) (any, error) {
+	if entity == EntityEmployee {
+		var emp models.Employee
+		if err := decodeBody(body, &emp); err != nil {
+			return nil, err
+		}
+		if emp.ID != 0 && emp.ID != id {
+			return nil, fmt.Errorf("%w: body id %d does not match %d", ErrInvalidBatch, emp.ID, id)
+		}
+		emp.ID, emp.Version = id, ifVersion
+		return st.employees.Update(ctx, emp)
+	}
+	var dept models.Department
+	if err := decodeBody(body, &dept); err != nil {
+		return nil, err
+	}
+	if dept.ID != 0 && dept.ID != id {
+		return nil, fmt.Errorf("%w: body id %d does not match %d", ErrInvalidBatch, dept.ID, id)
+	}
+	dept.ID, dept.Version = id, ifVersion
+	return st.departments.Update(ctx, dept)
+}
+
+func (st *staging) patch(ctx context.Context, entity string, id, ifVersion int, body json.RawMessage) (any, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidBatch)
+	}
+	var patch Patch = MergePatch(body)
+	if body[0] == '[' {
+		patch = JSONPatch(body)
+	}
+	if entity == EntityEmployee {
+		return st.employees.Patch(ctx, id, patch, ifVersion)
+	}
+	return st.departments.Patch(ctx, id, patch, ifVersion)
+}
+
+func (st *staging) delete(ctx context.Context, entity string, id, ifVersion int, body json.RawMessage) error {
+	var opts struct {
+		Policy            DeletePolicy `json:"policy"`
+		ReassignTo        int          `json:"reassignTo"`
+		ReassignReportsTo int          `json:"reassignReportsTo"`
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := decodeBody(body, &opts); err != nil {
+			return err
+		}
+	}
+	if entity == EntityEmployee {
+		return st.employees.DeleteWithOptions(ctx, id, EmployeeDeleteOptions{IfVersion: ifVersion, ReassignReportsTo: opts.ReassignReportsTo})
+	}
+	return st.departments.DeleteWithOptions(ctx, id, DeleteOptions{Policy: opts.Policy, ReassignTo: opts.ReassignTo, IfVersion: ifVersion})
+}
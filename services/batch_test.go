@@ -0,0 +1,145 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+func newBatch() (Batch, *AuditLog) {
+	emps, depts := newLinkedServices()
+	log := NewAuditLog()
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+	return Batch{Employees: emps, Departments: depts}, log
+}
+
+func TestBatch_RunResolvesRefs(t *testing.T) {
+	b, log := newBatch()
+	ops := []BatchOperation{
+		{Op: BatchCreate, Entity: EntityDepartment, Ref: "eng", Body: json.RawMessage(`{"name":"Engineering"}`)},
+		{Op: BatchCreate, Entity: EntityEmployee, Ref: "boss", Body: json.RawMessage(`{"firstName":"Ada","lastName":"Lovelace","email":"ada@example.com","departmentId":"$eng"}`)},
+		{Op: BatchCreate, Entity: EntityEmployee, Body: json.RawMessage(`{"firstName":"Alan","lastName":"Turing","email":"alan@example.com","departmentId":"$eng","managerId":"$boss"}`)},
+		{Op: BatchPatch, Entity: EntityEmployee, ID: BatchID{Ref: "boss"}, Body: json.RawMessage(`{"lastName":"King"}`)},
+	}
+
+	results, err := b.Run(ctx, ops)
+	if err != nil {
+		t.Fatalf("Run() error = %v, want nil", err)
+	}
+	if len(results) != len(ops) {
+		t.Fatalf("Run() returned %d results, want %d", len(results), len(ops))
+	}
+	report := results[2].Record.(models.Employee)
+	if report.DepartmentID != results[0].ID || report.ManagerID != results[1].ID {
+		t.Errorf("report = %+v, want department %d and manager %d", report, results[0].ID, results[1].ID)
+	}
+	boss, err := b.Employees.Retrieve(results[1].ID)
+	if err != nil || boss.LastName != "King" {
+		t.Errorf("Retrieve(boss) = %+v, %v, want last name King", boss, err)
+	}
+	if found, _ := b.Employees.Search("turing", 10); len(found) != 1 {
+		t.Errorf("Search(turing) found %d, want 1", len(found))
+	}
+	page, _ := log.Query(AuditFilter{}, ListOptions{})
+	if page.Total != len(ops) {
+		t.Errorf("audit log has %d entries, want %d", page.Total, len(ops))
+	}
+}
+
+func TestBatch_RunRollsBackOnFailure(t *testing.T) {
+	b, log := newBatch()
+	b.Departments.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	before, _ := log.Query(AuditFilter{}, ListOptions{})
+
+	ops := []BatchOperation{
+		{Op: BatchCreate, Entity: EntityEmployee, Body: json.RawMessage(`{"firstName":"Ada","lastName":"Lovelace","email":"ada@example.com","departmentId":1}`)},
+		{Op: BatchUpdate, Entity: EntityDepartment, ID: BatchID{ID: 1}, Body: json.RawMessage(`{"name":"Research"}`)},
+		{Op: BatchDelete, Entity: EntityEmployee, ID: BatchID{ID: 99}},
+	}
+	_, err := b.Run(ctx, ops)
+
+	var berr *BatchError
+	if !errors.As(err, &berr) || berr.Index != 2 || !errors.Is(err, ErrEmployeeNotFound) {
+		t.Fatalf("Run() error = %v, want operation 2 not found", err)
+	}
+	if all := b.Employees.RetrieveAll(); len(all) != 0 {
+		t.Errorf("employees = %+v, want none", all)
+	}
+	if dept, _ := b.Departments.Retrieve(1); dept.Name != "Engineering" {
+		t.Errorf("department name = %q, want Engineering", dept.Name)
+	}
+	if after, _ := log.Query(AuditFilter{}, ListOptions{}); after.Total != before.Total {
+		t.Errorf("audit log has %d entries, want %d", after.Total, before.Total)
+	}
+}
+
+func TestBatch_RunRejectsUnknownRef(t *testing.T) {
+	b, _ := newBatch()
+	ops := []BatchOperation{
+		{Op: BatchDelete, Entity: EntityDepartment, ID: BatchID{Ref: "missing"}},
+	}
+	if _, err := b.Run(ctx, ops); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("Run() error = %v, want %v", err, ErrInvalidBatch)
+	}
+}
+
+func TestBatchID_UnmarshalJSON(t *testing.T) {
+	for input, want := range map[string]BatchID{
+		`7`:      {ID: 7},
+		`"7"`:    {ID: 7},
+		`"$eng"`: {Ref: "eng"},
+	} {
+		var got BatchID
+		if err := json.Unmarshal([]byte(input), &got); err != nil || got != want {
+			t.Errorf("Unmarshal(%s) = %+v, %v, want %+v", input, got, err, want)
+		}
+	}
+	var id BatchID
+	if err := json.Unmarshal([]byte(`-1`), &id); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("Unmarshal(-1) error = %v, want %v", err, ErrInvalidBatch)
+	}
+}
+
+func TestBatch_RunCommitFailureLeavesNoTrace(t *testing.T) {
+	for _, failing := range []string{"repository", "journal", "journal part way"} {
+		t.Run(failing, func(t *testing.T) {
+			repo := &failingRepo[models.Employee]{Memory: storage.NewMemory[models.Employee]()}
+			journal := &failingJournal[models.AuditEntry]{MemoryJournal: storage.NewMemoryJournal[models.AuditEntry]()}
+			emps, depts := NewEmployeeServiceWithRepository(repo), NewDepartmentService()
+			Link(emps, depts)
+			log := NewAuditLogWithJournal(journal)
+			emps.SetAuditLog(log)
+			depts.SetAuditLog(log)
+			depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+			repo.fail = failing == "repository"
+			journal.fail = failing == "journal"
+			if failing == "journal part way" {
+				journal.limit = 2
+			}
+			ops := []BatchOperation{
+				{Op: BatchUpdate, Entity: EntityDepartment, ID: BatchID{ID: 1}, Body: json.RawMessage(`{"name":"Research"}`)},
+				{Op: BatchCreate, Entity: EntityEmployee, Body: json.RawMessage(`{"firstName":"Ada","lastName":"Lovelace","email":"ada@example.com","departmentId":1}`)},
+			}
+			if _, err := (Batch{Employees: emps, Departments: depts}).Run(ctx, ops); !errors.Is(err, errStorage) {
+				t.Fatalf("Run() error = %v, want %v", err, errStorage)
+			}
+			if all := emps.RetrieveAll(); len(all) != 0 {
+				t.Errorf("employees = %+v, want none", all)
+			}
+			if dept, _ := depts.Retrieve(1); dept.Name != "Engineering" {
+				t.Errorf("department name = %q, want Engineering", dept.Name)
+			}
+			if n := len(journal.Entries()); n != 1 {
+				t.Errorf("journal has %d entries, want only the department create", n)
+			}
+			if n := len(log.History(EntityDepartment, 1)); n != 1 {
+				t.Errorf("department history has %d entries, want only the create", n)
+			}
+		})
+	}
+}
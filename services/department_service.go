@@ -1,40 +1,111 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"employee-maintenance/models"
+	"employee-maintenance/storage"
 )
 
 var (
 	ErrDepartmentNotFound = errors.New("department not found")
+	ErrDepartmentInUse    = errors.New("department has employees")
 )
 
+// DeletePolicy decides what happens to a department's employees when the
+// department is deleted.
+type DeletePolicy string
+
+const (
+	// DeleteRestrict refuses to delete a department that still has employees.
+	DeleteRestrict DeletePolicy = "restrict"
+	// DeleteCascade deletes the department's employees along with it.
+	DeleteCascade DeletePolicy = "cascade"
+	// DeleteReassign moves the department's employees to another department.
+	DeleteReassign DeletePolicy = "reassign"
+)
+
+type DeleteOptions struct {
+	Policy DeletePolicy
+	// ReassignTo is the department that receives the employees when Policy
+	// is DeleteReassign.
+	ReassignTo int
+	// IfVersion, when set, must match the department's stored version.
+	IfVersion int
+}
+
+// DependentsError is returned when a department cannot be deleted because
+// employees still belong to it. It matches ErrDepartmentInUse.
+type DependentsError struct {
+	DepartmentID int
+	EmployeeIDs  []int
+}
+
+func (e *DependentsError) Error() string {
+	return fmt.Sprintf("department %d has employees %v", e.DepartmentID, e.EmployeeIDs)
+}
+
+func (e *DependentsError) Unwrap() error {
+	return ErrDepartmentInUse
+}
+
 type DepartmentService struct {
-	mu          sync.RWMutex
-	departments map[int]models.Department
+	mu          *sync.RWMutex
+	departments storage.Repository[models.Department]
+	employees   *EmployeeService
+	// positions, if set, keeps departments that have positions from being
+	// deleted.
+	positions *PositionService
+	audit     *AuditLog
+	now       func() time.Time
+	// ended is set for the services of a transaction once it has ended.
+	ended *atomic.Bool
 }
 
 func NewDepartmentService() *DepartmentService {
+	return NewDepartmentServiceWithRepository(storage.NewMemory[models.Department]())
+}
+
+func NewDepartmentServiceWithRepository(repo storage.Repository[models.Department]) *DepartmentService {
 	return &DepartmentService{
-		departments: make(map[int]models.Department),
+		mu:          &sync.RWMutex{},
+		departments: repo,
+		now:         time.Now,
 	}
 }
 
-func (s *DepartmentService) Create(dept models.Department) models.Department {
+func (s *DepartmentService) Create(ctx context.Context, dept models.Department) (models.Department, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.check(&dept); err != nil {
+		return models.Department{}, err
+	}
 	if dept.ID == 0 {
 		dept.ID = s.nextID()
 	}
-	s.departments[dept.ID] = dept
-	return dept
+	return s.put(ctx, dept)
+}
+
+// check validates dept's fields and its parent before a write. Deletion is
+// not something a caller can set directly.
+func (s *DepartmentService) check(dept *models.Department) error {
+	dept.DeletedAt = nil
+	if err := validateDepartment(dept); err != nil {
+		return err
+	}
+	return s.checkParent(dept)
 }
 
 func (s *DepartmentService) nextID() int {
 	maxID := 0
-	for _, d := range s.departments {
+	for _, d := range s.departments.List() {
 		if d.ID > maxID {
 			maxID = d.ID
 		}
@@ -45,39 +116,419 @@ func (s *DepartmentService) nextID() int {
 func (s *DepartmentService) Retrieve(id int) (models.Department, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	dept, exists := s.departments[id]
+	dept, exists := s.live(id)
 	if !exists {
 		return models.Department{}, ErrDepartmentNotFound
 	}
 	return dept, nil
 }
 
+// live returns the stored department unless it does not exist or is deleted.
+func (s *DepartmentService) live(id int) (models.Department, bool) {
+	dept, exists := s.departments.Get(id)
+	if !exists || dept.DeletedAt != nil {
+		return models.Department{}, false
+	}
+	return dept, true
+}
+
+// list returns the departments that are not deleted.
+func (s *DepartmentService) list() []models.Department {
+	var result []models.Department
+	for _, d := range s.departments.List() {
+		if d.DeletedAt == nil {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// RetrieveAsOf returns a department as it was at the given point in time,
+// reconstructed from the audit log.
+func (s *DepartmentService) RetrieveAsOf(id int, at AsOf) (models.Department, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dept, exists, err := s.get(id, at)
+	if err != nil {
+		return models.Department{}, err
+	}
+	if !exists {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	return dept, nil
+}
+
+// get looks up a department now or, for a non-zero at, in the past.
+func (s *DepartmentService) get(id int, at AsOf) (models.Department, bool, error) {
+	current, exists := s.live(id)
+	if at.IsZero() {
+		return current, exists, nil
+	}
+	return recordAsOf(s.audit, EntityDepartment, id, at, current, exists)
+}
+
+// listAsOf returns the departments that existed at the given point in time.
+// Deleted departments are left out unless includeDeleted is set, which only
+// applies to the current state.
+func (s *DepartmentService) listAsOf(at AsOf, includeDeleted bool) ([]models.Department, error) {
+	if at.IsZero() && includeDeleted {
+		return s.departments.List(), nil
+	}
+	if at.IsZero() {
+		return s.list(), nil
+	}
+	return recordsAsOf(s.audit, EntityDepartment, at, s.list(), departmentID)
+}
+
 func (s *DepartmentService) RetrieveAll() []models.Department {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	result := make([]models.Department, 0, len(s.departments))
-	for _, dept := range s.departments {
-		result = append(result, dept)
+	return s.list()
+}
+
+// DepartmentFilter narrows a List. NamePrefix matches the start of the name,
+// ignoring case; ParentID selects the children of one department; an empty
+// filter matches everything. A non-zero AsOf lists the departments as they
+// were at that point in time. IncludeDeleted adds tombstones of deleted
+// departments to the current state.
+type DepartmentFilter struct {
+	NamePrefix     string
+	ParentID       int
+	AsOf           AsOf
+	IncludeDeleted bool
+}
+
+// ParseDepartmentFilter reads namePrefix, parentId, asOf, knownAt and
+// includeDeleted from query parameters.
+func ParseDepartmentFilter(q url.Values) (DepartmentFilter, error) {
+	at, err := ParseAsOf(q)
+	if err != nil {
+		return DepartmentFilter{}, err
 	}
-	return result
+	parentID, err := intParam(q, "parentId")
+	if err != nil {
+		return DepartmentFilter{}, err
+	}
+	includeDeleted, err := boolParam(q, "includeDeleted")
+	if err != nil {
+		return DepartmentFilter{}, err
+	}
+	return DepartmentFilter{
+		NamePrefix:     q.Get("namePrefix"),
+		ParentID:       parentID,
+		AsOf:           at,
+		IncludeDeleted: includeDeleted,
+	}, nil
+}
+
+// Encode writes the filter into q in the form ParseDepartmentFilter reads.
+func (f DepartmentFilter) Encode(q url.Values) {
+	f.AsOf.Encode(q)
+	setBool(q, "includeDeleted", f.IncludeDeleted)
+	setInt(q, "parentId", f.ParentID)
+	if f.NamePrefix != "" {
+		q.Set("namePrefix", f.NamePrefix)
+	}
+}
+
+func (f DepartmentFilter) matches(dept models.Department) bool {
+	if f.ParentID != 0 && dept.ParentID != f.ParentID {
+		return false
+	}
+	return f.NamePrefix == "" || strings.HasPrefix(strings.ToLower(dept.Name), strings.ToLower(f.NamePrefix))
+}
+
+var departmentSortFields = sortFields[models.Department]{
+	"id":       func(d models.Department) any { return d.ID },
+	"name":     func(d models.Department) any { return d.Name },
+	"parentId": func(d models.Department) any { return d.ParentID },
+}
+
+// List returns the page of departments matching filter selected by opts.
+func (s *DepartmentService) List(filter DepartmentFilter, opts ListOptions) (Page[models.Department], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all, err := s.listAsOf(filter.AsOf, filter.IncludeDeleted)
+	if err != nil {
+		return Page[models.Department]{}, err
+	}
+	var matched []models.Department
+	for _, d := range all {
+		if filter.matches(d) {
+			matched = append(matched, d)
+		}
+	}
+	return paginate(matched, departmentID, departmentSortFields, opts)
+}
+
+func departmentID(d models.Department) int {
+	return d.ID
+}
+
+// Update replaces a department. If dept.Version is set, the update only
+// succeeds if it matches the stored version (ErrVersionConflict otherwise).
+func (s *DepartmentService) Update(ctx context.Context, dept models.Department) (models.Department, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, exists := s.live(dept.ID)
+	if !exists {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	if err := checkVersion(dept.Version, current.Version); err != nil {
+		return models.Department{}, err
+	}
+	if err := s.check(&dept); err != nil {
+		return models.Department{}, err
+	}
+	return s.put(ctx, dept)
 }
 
-func (s *DepartmentService) Update(dept models.Department) (models.Department, error) {
+// Patch applies patch to the stored department and saves the result under
+// the write lock. The patched department is validated like an update; its ID
+// cannot change and its version is managed by the service. A non-zero
+// ifVersion must match the stored version.
+func (s *DepartmentService) Patch(ctx context.Context, id int, patch Patch, ifVersion int) (models.Department, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, exists := s.departments[dept.ID]; !exists {
+	current, exists := s.live(id)
+	if !exists {
 		return models.Department{}, ErrDepartmentNotFound
 	}
-	s.departments[dept.ID] = dept
+	if err := checkVersion(ifVersion, current.Version); err != nil {
+		return models.Department{}, err
+	}
+	dept, err := applyPatch(current, patch)
+	if err != nil {
+		return models.Department{}, err
+	}
+	if dept.ID != id {
+		return models.Department{}, &ValidationError{Fields: []FieldError{{Field: "id", Reason: "cannot be changed"}}}
+	}
+	if err := s.check(&dept); err != nil {
+		return models.Department{}, err
+	}
+	return s.put(ctx, dept)
+}
+
+// SetAuditLog makes the service record every change in log. Like Link, it
+// must be called before the service is used.
+func (s *DepartmentService) SetAuditLog(log *AuditLog) {
+	s.audit = log
+}
+
+// SetPositionService makes the service refuse to delete a department that
+// still has positions in svc. Like Link, it must be called before the
+// service is used.
+func (s *DepartmentService) SetPositionService(svc *PositionService) {
+	s.positions = svc
+}
+
+// put stores dept with the next version number and records the change in the
+// audit log once it is stored, undoing it if it cannot be recorded. Every
+// write to the repository goes through put or remove.
+func (s *DepartmentService) put(ctx context.Context, dept models.Department) (models.Department, error) {
+	if err := checkOpen(s.ended); err != nil {
+		return models.Department{}, err
+	}
+	var before any
+	var wasDeleted *time.Time
+	prev, existed := s.departments.Get(dept.ID)
+	dept.Version = 1
+	if existed {
+		dept.Version = prev.Version + 1
+		before = prev
+		wasDeleted = prev.DeletedAt
+	}
+	op := writeOperation(existed, wasDeleted, dept.DeletedAt)
+	if err := s.departments.Put(dept.ID, dept); err != nil {
+		return models.Department{}, err
+	}
+	if err := s.audit.record(ctx, op, EntityDepartment, dept.ID, before, dept); err != nil {
+		restore(s.departments, dept.ID, prev, existed)
+		return models.Department{}, err
+	}
 	return dept, nil
 }
 
-func (s *DepartmentService) Delete(id int) error {
+// Delete removes a department, refusing if employees still belong to it.
+func (s *DepartmentService) Delete(ctx context.Context, id int) error {
+	return s.DeleteWithOptions(ctx, id, DeleteOptions{Policy: DeleteRestrict})
+}
+
+// DeleteWithOptions removes a department, handling its employees and child
+// departments according to opts.Policy: DeleteRestrict refuses if there are
+// any, DeleteCascade deletes the whole subtree with its employees, and
+// DeleteReassign moves both to opts.ReassignTo. An empty policy behaves like
+// DeleteRestrict. Under every policy, a department that still has positions
+// is not deleted. Like deleted employees, departments are kept as tombstones
+// until purged; restoring one does not restore what a cascade deleted.
+func (s *DepartmentService) DeleteWithOptions(ctx context.Context, id int, opts DeleteOptions) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, exists := s.departments[id]; !exists {
+	current, exists := s.live(id)
+	if !exists {
 		return ErrDepartmentNotFound
 	}
-	delete(s.departments, id)
+	if err := checkVersion(opts.IfVersion, current.Version); err != nil {
+		return err
+	}
+
+	group := []models.Department{current}
+	switch opts.Policy {
+	case DeleteRestrict, "":
+		if children := s.children(id); len(children) > 0 {
+			ids := make([]int, len(children))
+			for i, d := range children {
+				ids[i] = d.ID
+			}
+			return &ChildrenError{DepartmentID: id, ChildIDs: ids}
+		}
+	case DeleteCascade:
+		group = s.subtree(current)
+	case DeleteReassign:
+		if _, exists := s.live(opts.ReassignTo); !exists || s.within(opts.ReassignTo, id) {
+			return fmt.Errorf("%w: %d", ErrInvalidDepartment, opts.ReassignTo)
+		}
+	default:
+		return fmt.Errorf("unknown delete policy %q", opts.Policy)
+	}
+
+	// The group may take many writes; stage them so that a failure part way
+	// leaves nothing half deleted.
+	st := newStaging(s.employees, s)
+	if err := st.departments.deleteGroup(ctx, id, group, opts); err != nil {
+		return err
+	}
+	return st.commit(s.employees, s)
+}
+
+// deleteGroup releases the employees of group, moves the children of the
+// department id when reassigning, and tombstones the departments of group.
+func (s *DepartmentService) deleteGroup(ctx context.Context, id int, group []models.Department, opts DeleteOptions) error {
+	if s.employees != nil {
+		if err := s.releaseEmployees(ctx, group, opts); err != nil {
+			return err
+		}
+	}
+	if opts.Policy == DeleteReassign {
+		for _, child := range s.children(id) {
+			child.ParentID = opts.ReassignTo
+			if _, err := s.put(ctx, child); err != nil {
+				return err
+			}
+		}
+	}
+	deletedAt := s.now().UTC()
+	for _, d := range group {
+		d.DeletedAt = &deletedAt
+		if _, err := s.put(ctx, d); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// Restore brings back a deleted department.
+func (s *DepartmentService) Restore(ctx context.Context, id int) (models.Department, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dept, exists := s.departments.Get(id)
+	if !exists {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	if dept.DeletedAt == nil {
+		return models.Department{}, ErrNotDeleted
+	}
+	if err := s.check(&dept); err != nil {
+		return models.Department{}, err
+	}
+	return s.put(ctx, dept)
+}
+
+// Purge permanently removes the departments deleted before cutoff and
+// returns how many it removed.
+func (s *DepartmentService) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for _, d := range s.departments.List() {
+		if d.DeletedAt != nil && d.DeletedAt.Before(cutoff) {
+			if err := s.remove(ctx, d); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// remove permanently deletes a department.
+func (s *DepartmentService) remove(ctx context.Context, dept models.Department) error {
+	if err := checkOpen(s.ended); err != nil {
+		return err
+	}
+	if err := s.departments.Delete(dept.ID); err != nil {
+		return err
+	}
+	if err := s.audit.record(ctx, OperationPurge, EntityDepartment, dept.ID, dept, nil); err != nil {
+		s.departments.Put(dept.ID, dept)
+		return err
+	}
+	return nil
+}
+
+// releaseEmployees deals with the employees of the departments in group, all
+// of which are about to be deleted. The reassign target has been checked by
+// the caller. Terminated employees never hold up a delete: the restrict
+// policy detaches them from the department instead, so that no record is
+// left pointing at a deleted department.
+func (s *DepartmentService) releaseEmployees(ctx context.Context, group []models.Department, opts DeleteOptions) error {
+	var dependents []models.Employee
+	for _, d := range group {
+		dependents = append(dependents, s.employees.inDepartment(d.ID)...)
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	switch opts.Policy {
+	case DeleteCascade:
+		if err := s.employees.checkReportsWithin(dependents); err != nil {
+			return err
+		}
+		for _, e := range dependents {
+			if err := s.employees.tombstone(ctx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case DeleteReassign:
+		for _, e := range dependents {
+			e.DepartmentID = opts.ReassignTo
+			if _, err := s.employees.put(ctx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		var ids []int
+		var leavers []models.Employee
+		for _, e := range dependents {
+			if e.Status == models.StatusTerminated {
+				leavers = append(leavers, e)
+			} else {
+				ids = append(ids, e.ID)
+			}
+		}
+		if len(ids) > 0 {
+			return &DependentsError{DepartmentID: group[0].ID, EmployeeIDs: ids}
+		}
+		for _, e := range leavers {
+			e.DepartmentID = 0
+			if _, err := s.employees.put(ctx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -10,7 +10,10 @@ func TestDepartmentService_Create(t *testing.T) {
 	service := NewDepartmentService()
 	dept := models.Department{ID: 1, Name: "Engineering"}
 
-	created := service.Create(dept)
+	created, err := service.Create(ctx, dept)
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
 
 	if created.ID != dept.ID || created.Name != dept.Name {
 		t.Errorf("Create() returned %v, want %v", created, dept)
@@ -20,17 +23,17 @@ func TestDepartmentService_Create(t *testing.T) {
 func TestDepartmentService_Create_AutoGenerateID(t *testing.T) {
 	service := NewDepartmentService()
 
-	dept1 := service.Create(models.Department{Name: "Engineering"})
+	dept1, _ := service.Create(ctx, models.Department{Name: "Engineering"})
 	if dept1.ID != 1 {
 		t.Errorf("First auto-generated ID = %v, want 1", dept1.ID)
 	}
 
-	dept2 := service.Create(models.Department{Name: "Marketing"})
+	dept2, _ := service.Create(ctx, models.Department{Name: "Marketing"})
 	if dept2.ID != 2 {
 		t.Errorf("Second auto-generated ID = %v, want 2", dept2.ID)
 	}
 
-	dept3 := service.Create(models.Department{Name: "Sales"})
+	dept3, _ := service.Create(ctx, models.Department{Name: "Sales"})
 	if dept3.ID != 3 {
 		t.Errorf("Third auto-generated ID = %v, want 3", dept3.ID)
 	}
@@ -39,16 +42,16 @@ func TestDepartmentService_Create_AutoGenerateID(t *testing.T) {
 func TestDepartmentService_Create_AutoGenerateID_AfterDelete(t *testing.T) {
 	service := NewDepartmentService()
 
-	service.Create(models.Department{Name: "Engineering"})
-	service.Create(models.Department{Name: "Marketing"})
-	dept3 := service.Create(models.Department{Name: "Sales"})
+	service.Create(ctx, models.Department{Name: "Engineering"})
+	service.Create(ctx, models.Department{Name: "Marketing"})
+	dept3, _ := service.Create(ctx, models.Department{Name: "Sales"})
 	if dept3.ID != 3 {
 		t.Errorf("Third ID = %v, want 3", dept3.ID)
 	}
 
-	service.Delete(2)
+	service.Delete(ctx, 2)
 
-	dept4 := service.Create(models.Department{Name: "HR"})
+	dept4, _ := service.Create(ctx, models.Department{Name: "HR"})
 	if dept4.ID != 4 {
 		t.Errorf("After delete, new ID = %v, want 4 (should use max+1, not fill gaps)", dept4.ID)
 	}
@@ -57,7 +60,7 @@ func TestDepartmentService_Create_AutoGenerateID_AfterDelete(t *testing.T) {
 func TestDepartmentService_Retrieve(t *testing.T) {
 	service := NewDepartmentService()
 	dept := models.Department{ID: 1, Name: "Engineering"}
-	service.Create(dept)
+	service.Create(ctx, dept)
 
 	retrieved, err := service.Retrieve(1)
 	if err != nil {
@@ -81,8 +84,8 @@ func TestDepartmentService_RetrieveAll(t *testing.T) {
 	service := NewDepartmentService()
 	dept1 := models.Department{ID: 1, Name: "Engineering"}
 	dept2 := models.Department{ID: 2, Name: "Marketing"}
-	service.Create(dept1)
-	service.Create(dept2)
+	service.Create(ctx, dept1)
+	service.Create(ctx, dept2)
 
 	all := service.RetrieveAll()
 	if len(all) != 2 {
@@ -93,10 +96,10 @@ func TestDepartmentService_RetrieveAll(t *testing.T) {
 func TestDepartmentService_Update(t *testing.T) {
 	service := NewDepartmentService()
 	dept := models.Department{ID: 1, Name: "Engineering"}
-	service.Create(dept)
+	service.Create(ctx, dept)
 
 	updated := models.Department{ID: 1, Name: "Software Engineering"}
-	result, err := service.Update(updated)
+	result, err := service.Update(ctx, updated)
 	if err != nil {
 		t.Errorf("Update() error = %v, want nil", err)
 	}
@@ -109,7 +112,7 @@ func TestDepartmentService_Update_NotFound(t *testing.T) {
 	service := NewDepartmentService()
 	dept := models.Department{ID: 999, Name: "Test"}
 
-	_, err := service.Update(dept)
+	_, err := service.Update(ctx, dept)
 	if err != ErrDepartmentNotFound {
 		t.Errorf("Update() error = %v, want %v", err, ErrDepartmentNotFound)
 	}
@@ -118,9 +121,9 @@ func TestDepartmentService_Update_NotFound(t *testing.T) {
 func TestDepartmentService_Delete(t *testing.T) {
 	service := NewDepartmentService()
 	dept := models.Department{ID: 1, Name: "Engineering"}
-	service.Create(dept)
+	service.Create(ctx, dept)
 
-	err := service.Delete(1)
+	err := service.Delete(ctx, 1)
 	if err != nil {
 		t.Errorf("Delete() error = %v, want nil", err)
 	}
@@ -134,7 +137,7 @@ func TestDepartmentService_Delete(t *testing.T) {
 func TestDepartmentService_Delete_NotFound(t *testing.T) {
 	service := NewDepartmentService()
 
-	err := service.Delete(999)
+	err := service.Delete(ctx, 999)
 	if err != ErrDepartmentNotFound {
 		t.Errorf("Delete() error = %v, want %v", err, ErrDepartmentNotFound)
 	}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"employee-maintenance/models"
+)
+
+var (
+	ErrInvalidParent         = errors.New("parent department does not exist")
+	ErrDepartmentCycle       = errors.New("department cycle")
+	ErrDepartmentHasChildren = errors.New("department has child departments")
+)
+
+// ChildrenError is returned when a department cannot be deleted because
+// other departments belong to it. It matches ErrDepartmentHasChildren.
+type ChildrenError struct {
+	DepartmentID int
+	ChildIDs     []int
+}
+
+func (e *ChildrenError) Error() string {
+	return fmt.Sprintf("department %d has child departments %v", e.DepartmentID, e.ChildIDs)
+}
+
+func (e *ChildrenError) Unwrap() error {
+	return ErrDepartmentHasChildren
+}
+
+// checkParent checks that dept's parent exists and is not dept itself or one
+// of its descendants.
+func (s *DepartmentService) checkParent(dept *models.Department) error {
+	if dept.ParentID == 0 {
+		return nil
+	}
+	if dept.ParentID == dept.ID {
+		return fmt.Errorf("%w: department %d cannot be its own parent", ErrDepartmentCycle, dept.ID)
+	}
+	if _, exists := s.live(dept.ParentID); !exists {
+		return fmt.Errorf("%w: %d", ErrInvalidParent, dept.ParentID)
+	}
+	if dept.ID != 0 && s.within(dept.ParentID, dept.ID) {
+		return fmt.Errorf("%w: department %d is inside %d", ErrDepartmentCycle, dept.ParentID, dept.ID)
+	}
+	return nil
+}
+
+// within reports whether department id is ancestor or one of its
+// descendants, by following parents up from id.
+func (s *DepartmentService) within(id, ancestor int) bool {
+	seen := make(map[int]bool)
+	for id != 0 && !seen[id] {
+		if id == ancestor {
+			return true
+		}
+		seen[id] = true
+		dept, exists := s.live(id)
+		if !exists {
+			return false
+		}
+		id = dept.ParentID
+	}
+	return false
+}
+
+// children returns the departments directly below id, sorted by ID.
+func (s *DepartmentService) children(id int) []models.Department {
+	var result []models.Department
+	for _, d := range s.list() {
+		if d.ParentID == id {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// subtree returns root followed by every department below it.
+func (s *DepartmentService) subtree(root models.Department) []models.Department {
+	byParent := s.byParent()
+	result := []models.Department{root}
+	for i := 0; i < len(result); i++ {
+		result = append(result, byParent[result[i].ID]...)
+	}
+	return result
+}
+
+func (s *DepartmentService) byParent() map[int][]models.Department {
+	byParent := make(map[int][]models.Department)
+	for _, d := range s.list() {
+		if d.ParentID != 0 {
+			byParent[d.ParentID] = append(byParent[d.ParentID], d)
+		}
+	}
+	return byParent
+}
+
+// Move re-parents a department, taking its whole subtree with it. A parentID
+// of zero moves it to the top of the hierarchy. A non-zero ifVersion must
+// match the stored version.
+func (s *DepartmentService) Move(ctx context.Context, id, parentID, ifVersion int) (models.Department, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dept, exists := s.live(id)
+	if !exists {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	if err := checkVersion(ifVersion, dept.Version); err != nil {
+		return models.Department{}, err
+	}
+	dept.ParentID = parentID
+	if err := s.check(&dept); err != nil {
+		return models.Department{}, err
+	}
+	return s.put(ctx, dept)
+}
+
+// DepartmentNode is one department in a tree returned by Tree. Headcount
+// counts the department's own employees who have not been terminated,
+// TotalHeadcount adds those of every department below it.
+type DepartmentNode struct {
+	models.Department
+	Headcount      int              `json:"headcount"`
+	TotalHeadcount int              `json:"totalHeadcount"`
+	Children       []DepartmentNode `json:"children"`
+}
+
+// Tree returns the department with every department below it, children
+// sorted by ID, and their headcounts from the linked EmployeeService.
+func (s *DepartmentService) Tree(id int) (DepartmentNode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	root, exists := s.live(id)
+	if !exists {
+		return DepartmentNode{}, ErrDepartmentNotFound
+	}
+	headcounts := make(map[int]int)
+	if s.employees != nil {
+		for _, e := range s.employees.staff() {
+			headcounts[e.DepartmentID]++
+		}
+	}
+	return buildTree(root, s.byParent(), headcounts, map[int]bool{}), nil
+}
+
+func buildTree(dept models.Department, byParent map[int][]models.Department, headcounts map[int]int, seen map[int]bool) DepartmentNode {
+	seen[dept.ID] = true
+	node := DepartmentNode{
+		Department:     dept,
+		Headcount:      headcounts[dept.ID],
+		TotalHeadcount: headcounts[dept.ID],
+		Children:       []DepartmentNode{},
+	}
+	children := byParent[dept.ID]
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+	for _, child := range children {
+		if seen[child.ID] {
+			continue
+		}
+		childNode := buildTree(child, byParent, headcounts, seen)
+		node.TotalHeadcount += childNode.TotalHeadcount
+		node.Children = append(node.Children, childNode)
+	}
+	return node
+}
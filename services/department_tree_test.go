@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+// newDivisions builds 1 Company > 2 Engineering > 3 Platform, with 4 Sales
+// also under 1, and employees 1-2 in Engineering, 3 in Platform, 4 in Sales.
+func newDivisions(t *testing.T) (*EmployeeService, *DepartmentService) {
+	t.Helper()
+	emps, depts := newLinkedServices()
+	for _, d := range []models.Department{
+		{ID: 1, Name: "Company"},
+		{ID: 2, Name: "Engineering", ParentID: 1},
+		{ID: 3, Name: "Platform", ParentID: 2},
+		{ID: 4, Name: "Sales", ParentID: 1},
+	} {
+		if _, err := depts.Create(ctx, d); err != nil {
+			t.Fatalf("Create(%s) error = %v", d.Name, err)
+		}
+	}
+	for id, dept := range map[int]int{1: 2, 2: 2, 3: 3, 4: 4} {
+		emps.Create(ctx, testEmployee(id, dept))
+	}
+	return emps, depts
+}
+
+func TestDepartmentService_ParentMustExist(t *testing.T) {
+	_, depts := newLinkedServices()
+
+	_, err := depts.Create(ctx, models.Department{Name: "Team", ParentID: 7})
+	if !errors.Is(err, ErrInvalidParent) {
+		t.Errorf("Create() error = %v, want %v", err, ErrInvalidParent)
+	}
+}
+
+func TestDepartmentService_PreventsCycles(t *testing.T) {
+	_, depts := newDivisions(t)
+
+	if _, err := depts.Move(ctx, 1, 3, 0); !errors.Is(err, ErrDepartmentCycle) {
+		t.Errorf("Move(under descendant) error = %v, want %v", err, ErrDepartmentCycle)
+	}
+	if _, err := depts.Patch(ctx, 2, MergePatch(`{"parentId":2}`), 0); !errors.Is(err, ErrDepartmentCycle) {
+		t.Errorf("Patch(own parent) error = %v, want %v", err, ErrDepartmentCycle)
+	}
+}
+
+func TestDepartmentService_Tree(t *testing.T) {
+	_, depts := newDivisions(t)
+
+	tree, err := depts.Tree(1)
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if tree.Headcount != 0 || tree.TotalHeadcount != 4 || len(tree.Children) != 2 {
+		t.Fatalf("Tree() root = %d/%d with %d children, want 0/4 with 2", tree.Headcount, tree.TotalHeadcount, len(tree.Children))
+	}
+	eng := tree.Children[0]
+	if eng.ID != 2 || eng.Headcount != 2 || eng.TotalHeadcount != 3 {
+		t.Errorf("Engineering node = %d %d/%d, want 2 2/3", eng.ID, eng.Headcount, eng.TotalHeadcount)
+	}
+	if len(eng.Children) != 1 || eng.Children[0].Name != "Platform" {
+		t.Errorf("Engineering children = %+v, want Platform", eng.Children)
+	}
+}
+
+func TestDepartmentService_MoveSubtree(t *testing.T) {
+	_, depts := newDivisions(t)
+
+	moved, err := depts.Move(ctx, 2, 4, 0)
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if moved.ParentID != 4 {
+		t.Errorf("Move() parent = %d, want 4", moved.ParentID)
+	}
+	tree, _ := depts.Tree(4)
+	if tree.TotalHeadcount != 4 || tree.Children[0].Children[0].ID != 3 {
+		t.Errorf("Tree(4) = %+v, want Engineering and Platform below Sales", tree)
+	}
+	if _, err := depts.Move(ctx, 2, 0, 0); err != nil {
+		t.Errorf("Move(to top) error = %v", err)
+	}
+}
+
+func TestDepartmentService_DeleteWithChildren(t *testing.T) {
+	emps, depts := newDivisions(t)
+
+	err := depts.Delete(ctx, 2)
+	var cerr *ChildrenError
+	if !errors.As(err, &cerr) || !equalInts(cerr.ChildIDs, []int{3}) {
+		t.Fatalf("Delete() error = %v, want children [3]", err)
+	}
+
+	if err := depts.DeleteWithOptions(ctx, 2, DeleteOptions{Policy: DeleteReassign, ReassignTo: 3}); !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("DeleteWithOptions(reassign into subtree) error = %v, want %v", err, ErrInvalidDepartment)
+	}
+	if err := depts.DeleteWithOptions(ctx, 2, DeleteOptions{Policy: DeleteReassign, ReassignTo: 4}); err != nil {
+		t.Fatalf("DeleteWithOptions(reassign) error = %v", err)
+	}
+	if platform, _ := depts.Retrieve(3); platform.ParentID != 4 {
+		t.Errorf("Platform parent = %d, want 4", platform.ParentID)
+	}
+	if emp, _ := emps.Retrieve(1); emp.DepartmentID != 4 {
+		t.Errorf("employee 1 department = %d, want 4", emp.DepartmentID)
+	}
+}
+
+func TestDepartmentService_CascadeSubtree(t *testing.T) {
+	emps, depts := newDivisions(t)
+
+	if err := depts.DeleteWithOptions(ctx, 2, DeleteOptions{Policy: DeleteCascade}); err != nil {
+		t.Fatalf("DeleteWithOptions(cascade) error = %v", err)
+	}
+	if got := len(depts.RetrieveAll()); got != 2 {
+		t.Errorf("departments left = %d, want 2", got)
+	}
+	if got := ids(emps.RetrieveAll()); !equalInts(got, []int{4}) {
+		t.Errorf("employees left = %v, want [4]", got)
+	}
+}
@@ -1,40 +1,82 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"employee-maintenance/models"
+	"employee-maintenance/storage"
 )
 
 var (
-	ErrEmployeeNotFound = errors.New("employee not found")
+	ErrEmployeeNotFound  = errors.New("employee not found")
+	ErrInvalidDepartment = errors.New("department does not exist")
+	ErrNotDeleted        = errors.New("record is not deleted")
+	ErrEmployeeExists    = errors.New("employee already exists")
 )
 
 type EmployeeService struct {
-	mu        sync.RWMutex
-	employees map[int]models.Employee
+	mu          *sync.RWMutex
+	employees   storage.Repository[models.Employee]
+	departments *DepartmentService
+	// index is nil in staged copies until searchIndex builds it.
+	index     *searchIndex
+	indexOnce sync.Once
+	audit     *AuditLog
+	now       func() time.Time
+	// ended is set for the services of a transaction once it has ended.
+	ended *atomic.Bool
 }
 
 func NewEmployeeService() *EmployeeService {
-	return &EmployeeService{
-		employees: make(map[int]models.Employee),
+	return NewEmployeeServiceWithRepository(storage.NewMemory[models.Employee]())
+}
+
+func NewEmployeeServiceWithRepository(repo storage.Repository[models.Employee]) *EmployeeService {
+	s := &EmployeeService{
+		mu:        &sync.RWMutex{},
+		employees: repo,
+		index:     newSearchIndex(),
+		now:       time.Now,
+	}
+	for _, emp := range s.list() {
+		s.index.add(emp)
 	}
+	return s
 }
 
-func (s *EmployeeService) Create(emp models.Employee) models.Employee {
+// Create stores a new employee, with the next free ID unless emp has one.
+// An ID that is already taken, even by a deleted employee, is refused:
+// existing employees are changed through Update, Terminate, Rehire and
+// Restore.
+func (s *EmployeeService) Create(ctx context.Context, emp models.Employee) (models.Employee, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, exists := s.employees.Get(emp.ID); emp.ID != 0 && exists {
+		return models.Employee{}, fmt.Errorf("%w: %d", ErrEmployeeExists, emp.ID)
+	}
+	if err := s.checkStatus(nil, &emp); err != nil {
+		return models.Employee{}, err
+	}
+	if err := s.check(&emp); err != nil {
+		return models.Employee{}, err
+	}
 	if emp.ID == 0 {
 		emp.ID = s.nextID()
 	}
-	s.employees[emp.ID] = emp
-	return emp
+	return s.put(ctx, emp)
 }
 
 func (s *EmployeeService) nextID() int {
 	maxID := 0
-	for _, e := range s.employees {
+	for _, e := range s.employees.List() {
 		if e.ID > maxID {
 			maxID = e.ID
 		}
@@ -42,42 +84,509 @@ func (s *EmployeeService) nextID() int {
 	return maxID + 1
 }
 
+// check validates emp's fields and its department and manager references
+// before a write. Deletion is not something a caller can set directly.
+func (s *EmployeeService) check(emp *models.Employee) error {
+	*emp = normalize(*emp)
+	emp.DeletedAt = nil
+	if err := validateEmployee(emp); err != nil {
+		return err
+	}
+	if err := s.checkDepartment(emp); err != nil {
+		return err
+	}
+	return s.checkManager(emp)
+}
+
+// checkDepartment normalizes the employee's department reference and checks
+// that the department exists. An ID of zero means no department. Without a
+// linked DepartmentService the reference is taken as given.
+func (s *EmployeeService) checkDepartment(emp *models.Employee) error {
+	*emp = normalize(*emp)
+	if s.departments == nil || emp.DepartmentID == 0 {
+		return nil
+	}
+	if _, exists := s.departments.live(emp.DepartmentID); !exists {
+		return fmt.Errorf("%w: %d", ErrInvalidDepartment, emp.DepartmentID)
+	}
+	return nil
+}
+
+// normalize turns an embedded department, as sent by older clients and kept
+// in data written before departments were stored by reference, into a
+// DepartmentID. Employees stored before statuses existed are active.
+func normalize(emp models.Employee) models.Employee {
+	if emp.Status == "" {
+		emp.Status = models.StatusActive
+	}
+	if emp.Department != nil {
+		if emp.DepartmentID == 0 {
+			emp.DepartmentID = emp.Department.ID
+		}
+		emp.Department = nil
+	}
+	return emp
+}
+
+// ExpandDepartments returns copies of emps with Department filled in from the
+// current state of the linked DepartmentService.
+func (s *EmployeeService) ExpandDepartments(emps ...models.Employee) []models.Employee {
+	result, _ := s.ExpandDepartmentsAsOf(AsOf{}, emps...)
+	return result
+}
+
+// ExpandDepartmentsAsOf is like ExpandDepartments but uses the departments as
+// they were at the given point in time.
+func (s *EmployeeService) ExpandDepartmentsAsOf(at AsOf, emps ...models.Employee) ([]models.Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]models.Employee, len(emps))
+	for i, emp := range emps {
+		if s.departments != nil && emp.DepartmentID != 0 {
+			dept, exists, err := s.departments.get(emp.DepartmentID, at)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				emp.Department = &dept
+			}
+		}
+		result[i] = emp
+	}
+	return result, nil
+}
+
 func (s *EmployeeService) Retrieve(id int) (models.Employee, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	emp, exists := s.employees[id]
+	emp, exists := s.live(id)
 	if !exists {
 		return models.Employee{}, ErrEmployeeNotFound
 	}
 	return emp, nil
 }
 
+// live returns the stored employee unless it does not exist or is deleted.
+func (s *EmployeeService) live(id int) (models.Employee, bool) {
+	emp, exists := s.employees.Get(id)
+	if !exists || emp.DeletedAt != nil {
+		return models.Employee{}, false
+	}
+	return normalize(emp), true
+}
+
+// RetrieveAsOf returns an employee as it was at the given point in time,
+// reconstructed from the audit log.
+func (s *EmployeeService) RetrieveAsOf(id int, at AsOf) (models.Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	current, exists := s.live(id)
+	emp, exists, err := recordAsOf(s.audit, EntityEmployee, id, at, current, exists)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	if !exists {
+		return models.Employee{}, ErrEmployeeNotFound
+	}
+	return normalize(emp), nil
+}
+
 func (s *EmployeeService) RetrieveAll() []models.Employee {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	result := make([]models.Employee, 0, len(s.employees))
-	for _, emp := range s.employees {
-		result = append(result, emp)
+	return s.list()
+}
+
+// RetrieveStaff returns the employees who are neither deleted nor
+// terminated.
+func (s *EmployeeService) RetrieveStaff() []models.Employee {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.staff()
+}
+
+// staff returns the employees who are neither deleted nor terminated, the
+// ones that count towards reporting lines and headcounts.
+func (s *EmployeeService) staff() []models.Employee {
+	var result []models.Employee
+	for _, e := range s.list() {
+		if e.Status != models.StatusTerminated {
+			result = append(result, e)
+		}
 	}
 	return result
 }
 
-func (s *EmployeeService) Update(emp models.Employee) (models.Employee, error) {
+// list returns the employees that are not deleted.
+func (s *EmployeeService) list() []models.Employee {
+	var result []models.Employee
+	for _, e := range s.listAll() {
+		if e.DeletedAt == nil {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// listAll returns every stored employee, including tombstones.
+func (s *EmployeeService) listAll() []models.Employee {
+	all := s.employees.List()
+	for i, e := range all {
+		all[i] = normalize(e)
+	}
+	return all
+}
+
+// listAsOf returns the employees that existed at the given point in time.
+// Deleted employees are left out unless includeDeleted is set, which only
+// applies to the current state.
+func (s *EmployeeService) listAsOf(at AsOf, includeDeleted bool) ([]models.Employee, error) {
+	if at.IsZero() && includeDeleted {
+		return s.listAll(), nil
+	}
+	if at.IsZero() {
+		return s.list(), nil
+	}
+	all, err := recordsAsOf(s.audit, EntityEmployee, at, s.list(), employeeID)
+	for i, e := range all {
+		all[i] = normalize(e)
+	}
+	return all, err
+}
+
+// Diff compares the employees that existed at from with those at to.
+func (s *EmployeeService) Diff(from, to time.Time) (Diff[models.Employee], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	before, err := s.listAsOf(AsOf{Time: from}, false)
+	if err != nil {
+		return Diff[models.Employee]{}, err
+	}
+	after, err := s.listAsOf(AsOf{Time: to}, false)
+	if err != nil {
+		return Diff[models.Employee]{}, err
+	}
+	return diffRecords(from, to, before, after, employeeID)
+}
+
+// EmployeeFilter narrows a List. Zero values match everything. LastName is
+// matched case-insensitively; EmailPrefix matches the start of the email.
+// Statuses lists the employment statuses to include.
+// A non-zero AsOf lists the employees as they were at that point in time.
+// IncludeDeleted adds tombstones of deleted employees to the current state.
+type EmployeeFilter struct {
+	DepartmentID   int
+	ManagerID      int
+	LastName       string
+	EmailPrefix    string
+	Statuses       []models.EmploymentStatus
+	AsOf           AsOf
+	IncludeDeleted bool
+}
+
+// ParseEmployeeFilter reads departmentId, managerId, lastName, emailPrefix,
+// status, asOf, knownAt and includeDeleted from query parameters. Without a
+// status only active employees are listed; status=all lists everyone.
+func ParseEmployeeFilter(q url.Values) (EmployeeFilter, error) {
+	deptID, err := intParam(q, "departmentId")
+	if err != nil {
+		return EmployeeFilter{}, err
+	}
+	at, err := ParseAsOf(q)
+	if err != nil {
+		return EmployeeFilter{}, err
+	}
+	managerID, err := intParam(q, "managerId")
+	if err != nil {
+		return EmployeeFilter{}, err
+	}
+	includeDeleted, err := boolParam(q, "includeDeleted")
+	if err != nil {
+		return EmployeeFilter{}, err
+	}
+	statuses, err := parseStatuses(q)
+	if err != nil {
+		return EmployeeFilter{}, err
+	}
+	return EmployeeFilter{
+		DepartmentID:   deptID,
+		ManagerID:      managerID,
+		LastName:       q.Get("lastName"),
+		EmailPrefix:    q.Get("emailPrefix"),
+		Statuses:       statuses,
+		AsOf:           at,
+		IncludeDeleted: includeDeleted,
+	}, nil
+}
+
+// Encode writes the filter into q in the form ParseEmployeeFilter reads.
+func (f EmployeeFilter) Encode(q url.Values) {
+	f.AsOf.Encode(q)
+	setBool(q, "includeDeleted", f.IncludeDeleted)
+	setInt(q, "departmentId", f.DepartmentID)
+	setInt(q, "managerId", f.ManagerID)
+	if f.LastName != "" {
+		q.Set("lastName", f.LastName)
+	}
+	if f.EmailPrefix != "" {
+		q.Set("emailPrefix", f.EmailPrefix)
+	}
+	setStatuses(q, f.Statuses)
+}
+
+func (f EmployeeFilter) matches(emp models.Employee) bool {
+	if f.DepartmentID != 0 && emp.DepartmentID != f.DepartmentID {
+		return false
+	}
+	if f.ManagerID != 0 && emp.ManagerID != f.ManagerID {
+		return false
+	}
+	if f.LastName != "" && !strings.EqualFold(emp.LastName, f.LastName) {
+		return false
+	}
+	if f.EmailPrefix != "" && !strings.HasPrefix(strings.ToLower(emp.Email), strings.ToLower(f.EmailPrefix)) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, emp.Status) {
+		return false
+	}
+	return true
+}
+
+var employeeSortFields = sortFields[models.Employee]{
+	"id":           func(e models.Employee) any { return e.ID },
+	"firstName":    func(e models.Employee) any { return e.FirstName },
+	"lastName":     func(e models.Employee) any { return e.LastName },
+	"email":        func(e models.Employee) any { return e.Email },
+	"departmentId": func(e models.Employee) any { return e.DepartmentID },
+	"managerId":    func(e models.Employee) any { return e.ManagerID },
+	"hireDate":     func(e models.Employee) any { return e.HireDate.String() },
+	"status":       func(e models.Employee) any { return string(e.Status) },
+}
+
+// List returns the page of employees matching filter selected by opts.
+func (s *EmployeeService) List(filter EmployeeFilter, opts ListOptions) (Page[models.Employee], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all, err := s.listAsOf(filter.AsOf, filter.IncludeDeleted)
+	if err != nil {
+		return Page[models.Employee]{}, err
+	}
+	var matched []models.Employee
+	for _, e := range all {
+		if filter.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	return paginate(matched, employeeID, employeeSortFields, opts)
+}
+
+func employeeID(e models.Employee) int {
+	return e.ID
+}
+
+// Update replaces an employee. If emp.Version is set, the update only
+// succeeds if it matches the stored version (ErrVersionConflict otherwise).
+func (s *EmployeeService) Update(ctx context.Context, emp models.Employee) (models.Employee, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, exists := s.employees[emp.ID]; !exists {
+	current, exists := s.live(emp.ID)
+	if !exists {
 		return models.Employee{}, ErrEmployeeNotFound
 	}
-	s.employees[emp.ID] = emp
-	return emp, nil
+	if err := checkVersion(emp.Version, current.Version); err != nil {
+		return models.Employee{}, err
+	}
+	if err := s.checkStatus(&current, &emp); err != nil {
+		return models.Employee{}, err
+	}
+	if err := s.check(&emp); err != nil {
+		return models.Employee{}, err
+	}
+	return s.put(ctx, emp)
+}
+
+// Patch applies patch to the stored employee and saves the result, all under
+// the write lock so no other write can interleave. The patched employee is
+// validated like an update; its ID cannot change and its version is managed
+// by the service. A non-zero ifVersion must match the stored version.
+func (s *EmployeeService) Patch(ctx context.Context, id int, patch Patch, ifVersion int) (models.Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, exists := s.live(id)
+	if !exists {
+		return models.Employee{}, ErrEmployeeNotFound
+	}
+	if err := checkVersion(ifVersion, current.Version); err != nil {
+		return models.Employee{}, err
+	}
+	emp, err := applyPatch(current, patch)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	if emp.ID != id {
+		return models.Employee{}, &ValidationError{Fields: []FieldError{{Field: "id", Reason: "cannot be changed"}}}
+	}
+	if err := s.checkStatus(&current, &emp); err != nil {
+		return models.Employee{}, err
+	}
+	if err := s.check(&emp); err != nil {
+		return models.Employee{}, err
+	}
+	return s.put(ctx, emp)
+}
+
+func (s *EmployeeService) Delete(ctx context.Context, id int) error {
+	return s.DeleteIfVersion(ctx, id, 0)
 }
 
-func (s *EmployeeService) Delete(id int) error {
+// DeleteIfVersion deletes an employee if its stored version matches version.
+// A version of zero deletes unconditionally.
+func (s *EmployeeService) DeleteIfVersion(ctx context.Context, id, version int) error {
+	return s.DeleteWithOptions(ctx, id, EmployeeDeleteOptions{IfVersion: version})
+}
+
+type EmployeeDeleteOptions struct {
+	// IfVersion, when set, must match the employee's stored version.
+	IfVersion int
+	// ReassignReportsTo is the employee that takes over the deleted
+	// employee's direct reports. Without it, an employee with reports cannot
+	// be deleted.
+	ReassignReportsTo int
+}
+
+// DeleteWithOptions deletes an employee. The employee is kept as a tombstone
+// that Restore can bring back until Purge removes it.
+func (s *EmployeeService) DeleteWithOptions(ctx context.Context, id int, opts EmployeeDeleteOptions) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, exists := s.employees[id]; !exists {
+	current, exists := s.live(id)
+	if !exists {
 		return ErrEmployeeNotFound
 	}
-	delete(s.employees, id)
+	if err := checkVersion(opts.IfVersion, current.Version); err != nil {
+		return err
+	}
+	// Moving the reports and the delete are staged together, so that a
+	// failure part way moves no one.
+	st := newStaging(s, nil)
+	if err := st.employees.releaseReports(ctx, id, opts.ReassignReportsTo); err != nil {
+		return err
+	}
+	if err := st.employees.tombstone(ctx, current); err != nil {
+		return err
+	}
+	return st.commit(s, nil)
+}
+
+func (s *EmployeeService) tombstone(ctx context.Context, emp models.Employee) error {
+	deletedAt := s.now().UTC()
+	emp.DeletedAt = &deletedAt
+	_, err := s.put(ctx, emp)
+	return err
+}
+
+// Restore brings back a deleted employee. Its department must still exist.
+func (s *EmployeeService) Restore(ctx context.Context, id int) (models.Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emp, exists := s.employees.Get(id)
+	if !exists {
+		return models.Employee{}, ErrEmployeeNotFound
+	}
+	if emp.DeletedAt == nil {
+		return models.Employee{}, ErrNotDeleted
+	}
+	if err := s.check(&emp); err != nil {
+		return models.Employee{}, err
+	}
+	return s.put(ctx, emp)
+}
+
+// Purge permanently removes the employees deleted before cutoff and returns
+// how many it removed.
+func (s *EmployeeService) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for _, e := range s.listAll() {
+		if e.DeletedAt != nil && e.DeletedAt.Before(cutoff) {
+			if err := s.remove(ctx, e.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// SetAuditLog makes the service record every change in log. Like Link, it
+// must be called before the service is used.
+func (s *EmployeeService) SetAuditLog(log *AuditLog) {
+	s.audit = log
+}
+
+// put stores emp with the next version number, records the change in the
+// audit log and keeps the search index in step. The change is recorded once
+// it is stored, and undone if it cannot be recorded. Every write to the
+// repository goes through put or remove.
+func (s *EmployeeService) put(ctx context.Context, emp models.Employee) (models.Employee, error) {
+	if err := checkOpen(s.ended); err != nil {
+		return models.Employee{}, err
+	}
+	var before any
+	var wasDeleted *time.Time
+	prev, existed := s.employees.Get(emp.ID)
+	emp.Version = 1
+	if existed {
+		emp.Version = prev.Version + 1
+		before = normalize(prev)
+		wasDeleted = prev.DeletedAt
+	}
+	op := writeOperation(existed, wasDeleted, emp.DeletedAt)
+	if err := s.employees.Put(emp.ID, emp); err != nil {
+		return models.Employee{}, err
+	}
+	if err := s.audit.record(ctx, op, EntityEmployee, emp.ID, before, emp); err != nil {
+		restore(s.employees, emp.ID, prev, existed)
+		return models.Employee{}, err
+	}
+	if emp.DeletedAt != nil {
+		s.index.remove(emp.ID)
+	} else {
+		s.index.add(emp)
+	}
+	return emp, nil
+}
+
+// remove permanently deletes an employee.
+func (s *EmployeeService) remove(ctx context.Context, id int) error {
+	if err := checkOpen(s.ended); err != nil {
+		return err
+	}
+	prev, exists := s.employees.Get(id)
+	if err := s.employees.Delete(id); err != nil {
+		return err
+	}
+	if exists {
+		if err := s.audit.record(ctx, OperationPurge, EntityEmployee, id, normalize(prev), nil); err != nil {
+			restore(s.employees, id, prev, exists)
+			return err
+		}
+	}
+	s.index.remove(id)
 	return nil
 }
+
+// inDepartment returns the employees assigned to the given department,
+// leaving out deleted ones. Terminated employees keep the department they
+// left from, so they are included.
+func (s *EmployeeService) inDepartment(deptID int) []models.Employee {
+	var result []models.Employee
+	for _, e := range s.list() {
+		if e.DepartmentID == deptID {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -9,17 +9,17 @@ import (
 func TestEmployeeService_Create(t *testing.T) {
 	service := NewEmployeeService()
 	emp := models.Employee{
-		ID:        1,
-		FirstName: "John",
-		LastName:  "Doe",
-		Email:     "john.doe@example.com",
-		Department: models.Department{
-			ID:   1,
-			Name: "Engineering",
-		},
+		ID:           1,
+		FirstName:    "John",
+		LastName:     "Doe",
+		Email:        "john.doe@example.com",
+		DepartmentID: 1,
 	}
 
-	created := service.Create(emp)
+	created, err := service.Create(ctx, emp)
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
 
 	if created.ID != emp.ID || created.FirstName != emp.FirstName {
 		t.Errorf("Create() returned %v, want %v", created, emp)
@@ -28,19 +28,18 @@ func TestEmployeeService_Create(t *testing.T) {
 
 func TestEmployeeService_Create_AutoGenerateID(t *testing.T) {
 	service := NewEmployeeService()
-	dept := models.Department{ID: 1, Name: "Engineering"}
 
-	emp1 := service.Create(models.Employee{FirstName: "John", LastName: "Doe", Email: "john@example.com", Department: dept})
+	emp1, _ := service.Create(ctx, models.Employee{FirstName: "John", LastName: "Doe", Email: "john@example.com", DepartmentID: 1})
 	if emp1.ID != 1 {
 		t.Errorf("First auto-generated ID = %v, want 1", emp1.ID)
 	}
 
-	emp2 := service.Create(models.Employee{FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", Department: dept})
+	emp2, _ := service.Create(ctx, models.Employee{FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", DepartmentID: 1})
 	if emp2.ID != 2 {
 		t.Errorf("Second auto-generated ID = %v, want 2", emp2.ID)
 	}
 
-	emp3 := service.Create(models.Employee{FirstName: "Bob", LastName: "Wilson", Email: "bob@example.com", Department: dept})
+	emp3, _ := service.Create(ctx, models.Employee{FirstName: "Bob", LastName: "Wilson", Email: "bob@example.com", DepartmentID: 1})
 	if emp3.ID != 3 {
 		t.Errorf("Third auto-generated ID = %v, want 3", emp3.ID)
 	}
@@ -48,18 +47,17 @@ func TestEmployeeService_Create_AutoGenerateID(t *testing.T) {
 
 func TestEmployeeService_Create_AutoGenerateID_AfterDelete(t *testing.T) {
 	service := NewEmployeeService()
-	dept := models.Department{ID: 1, Name: "Engineering"}
 
-	service.Create(models.Employee{FirstName: "John", LastName: "Doe", Email: "john@example.com", Department: dept})
-	service.Create(models.Employee{FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", Department: dept})
-	emp3 := service.Create(models.Employee{FirstName: "Bob", LastName: "Wilson", Email: "bob@example.com", Department: dept})
+	service.Create(ctx, models.Employee{FirstName: "John", LastName: "Doe", Email: "john@example.com", DepartmentID: 1})
+	service.Create(ctx, models.Employee{FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", DepartmentID: 1})
+	emp3, _ := service.Create(ctx, models.Employee{FirstName: "Bob", LastName: "Wilson", Email: "bob@example.com", DepartmentID: 1})
 	if emp3.ID != 3 {
 		t.Errorf("Third ID = %v, want 3", emp3.ID)
 	}
 
-	service.Delete(2)
+	service.Delete(ctx, 2)
 
-	emp4 := service.Create(models.Employee{FirstName: "Alice", LastName: "Brown", Email: "alice@example.com", Department: dept})
+	emp4, _ := service.Create(ctx, models.Employee{FirstName: "Alice", LastName: "Brown", Email: "alice@example.com", DepartmentID: 1})
 	if emp4.ID != 4 {
 		t.Errorf("After delete, new ID = %v, want 4 (should use max+1, not fill gaps)", emp4.ID)
 	}
@@ -68,16 +66,13 @@ func TestEmployeeService_Create_AutoGenerateID_AfterDelete(t *testing.T) {
 func TestEmployeeService_Retrieve(t *testing.T) {
 	service := NewEmployeeService()
 	emp := models.Employee{
-		ID:        1,
-		FirstName: "John",
-		LastName:  "Doe",
-		Email:     "john.doe@example.com",
-		Department: models.Department{
-			ID:   1,
-			Name: "Engineering",
-		},
+		ID:           1,
+		FirstName:    "John",
+		LastName:     "Doe",
+		Email:        "john.doe@example.com",
+		DepartmentID: 1,
 	}
-	service.Create(emp)
+	service.Create(ctx, emp)
 
 	retrieved, err := service.Retrieve(1)
 	if err != nil {
@@ -99,11 +94,10 @@ func TestEmployeeService_Retrieve_NotFound(t *testing.T) {
 
 func TestEmployeeService_RetrieveAll(t *testing.T) {
 	service := NewEmployeeService()
-	dept := models.Department{ID: 1, Name: "Engineering"}
-	emp1 := models.Employee{ID: 1, FirstName: "John", LastName: "Doe", Email: "john@example.com", Department: dept}
-	emp2 := models.Employee{ID: 2, FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", Department: dept}
-	service.Create(emp1)
-	service.Create(emp2)
+	emp1 := models.Employee{ID: 1, FirstName: "John", LastName: "Doe", Email: "john@example.com", DepartmentID: 1}
+	emp2 := models.Employee{ID: 2, FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", DepartmentID: 1}
+	service.Create(ctx, emp1)
+	service.Create(ctx, emp2)
 
 	all := service.RetrieveAll()
 	if len(all) != 2 {
@@ -114,36 +108,30 @@ func TestEmployeeService_RetrieveAll(t *testing.T) {
 func TestEmployeeService_Update(t *testing.T) {
 	service := NewEmployeeService()
 	emp := models.Employee{
-		ID:        1,
-		FirstName: "John",
-		LastName:  "Doe",
-		Email:     "john.doe@example.com",
-		Department: models.Department{
-			ID:   1,
-			Name: "Engineering",
-		},
+		ID:           1,
+		FirstName:    "John",
+		LastName:     "Doe",
+		Email:        "john.doe@example.com",
+		DepartmentID: 1,
 	}
-	service.Create(emp)
+	service.Create(ctx, emp)
 
 	updated := models.Employee{
-		ID:        1,
-		FirstName: "John",
-		LastName:  "Doe",
-		Email:     "john.updated@example.com",
-		Department: models.Department{
-			ID:   2,
-			Name: "Marketing",
-		},
-	}
-	result, err := service.Update(updated)
+		ID:           1,
+		FirstName:    "John",
+		LastName:     "Doe",
+		Email:        "john.updated@example.com",
+		DepartmentID: 2,
+	}
+	result, err := service.Update(ctx, updated)
 	if err != nil {
 		t.Errorf("Update() error = %v, want nil", err)
 	}
 	if result.Email != "john.updated@example.com" {
 		t.Errorf("Update() Email = %v, want john.updated@example.com", result.Email)
 	}
-	if result.Department.Name != "Marketing" {
-		t.Errorf("Update() Department.Name = %v, want Marketing", result.Department.Name)
+	if result.DepartmentID != 2 {
+		t.Errorf("Update() DepartmentID = %v, want 2", result.DepartmentID)
 	}
 }
 
@@ -151,7 +139,7 @@ func TestEmployeeService_Update_NotFound(t *testing.T) {
 	service := NewEmployeeService()
 	emp := models.Employee{ID: 999, FirstName: "Test", LastName: "User", Email: "test@example.com"}
 
-	_, err := service.Update(emp)
+	_, err := service.Update(ctx, emp)
 	if err != ErrEmployeeNotFound {
 		t.Errorf("Update() error = %v, want %v", err, ErrEmployeeNotFound)
 	}
@@ -160,18 +148,15 @@ func TestEmployeeService_Update_NotFound(t *testing.T) {
 func TestEmployeeService_Delete(t *testing.T) {
 	service := NewEmployeeService()
 	emp := models.Employee{
-		ID:        1,
-		FirstName: "John",
-		LastName:  "Doe",
-		Email:     "john.doe@example.com",
-		Department: models.Department{
-			ID:   1,
-			Name: "Engineering",
-		},
-	}
-	service.Create(emp)
-
-	err := service.Delete(1)
+		ID:           1,
+		FirstName:    "John",
+		LastName:     "Doe",
+		Email:        "john.doe@example.com",
+		DepartmentID: 1,
+	}
+	service.Create(ctx, emp)
+
+	err := service.Delete(ctx, 1)
 	if err != nil {
 		t.Errorf("Delete() error = %v, want nil", err)
 	}
@@ -185,7 +170,7 @@ func TestEmployeeService_Delete(t *testing.T) {
 func TestEmployeeService_Delete_NotFound(t *testing.T) {
 	service := NewEmployeeService()
 
-	err := service.Delete(999)
+	err := service.Delete(ctx, 999)
 	if err != ErrEmployeeNotFound {
 		t.Errorf("Delete() error = %v, want %v", err, ErrEmployeeNotFound)
 	}
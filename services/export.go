@@ -0,0 +1,84 @@
+package services
+
+import (
+	"sync"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+// exportBatch is how many records an export reads under the lock at a time.
+const exportBatch = 500
+
+// Export calls fn with every employee matching filter, in ID order, stopping
+// at the first error fn returns. Employees are read a batch at a time and
+// the lock is not held while fn runs, so a slow consumer does not hold up
+// writers; a record changed during an export appears as it was when its
+// batch was read. Exports with an AsOf are reconstructed from the audit log
+// in one go.
+func (s *EmployeeService) Export(filter EmployeeFilter, fn func(models.Employee) error) error {
+	if !filter.AsOf.IsZero() {
+		s.mu.RLock()
+		all, err := s.listAsOf(filter.AsOf, filter.IncludeDeleted)
+		s.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		return each(all, filter.matches, fn)
+	}
+	keep := func(e models.Employee) bool {
+		return (filter.IncludeDeleted || e.DeletedAt == nil) && filter.matches(e)
+	}
+	return scan(s.mu, s.employees, employeeID, normalize, keep, fn)
+}
+
+// Export calls fn with every department matching filter, in ID order, in
+// the same way as EmployeeService.Export.
+func (s *DepartmentService) Export(filter DepartmentFilter, fn func(models.Department) error) error {
+	if !filter.AsOf.IsZero() {
+		s.mu.RLock()
+		all, err := s.listAsOf(filter.AsOf, filter.IncludeDeleted)
+		s.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		return each(all, filter.matches, fn)
+	}
+	keep := func(d models.Department) bool {
+		return (filter.IncludeDeleted || d.DeletedAt == nil) && filter.matches(d)
+	}
+	unchanged := func(d models.Department) models.Department { return d }
+	return scan(s.mu, s.departments, departmentID, unchanged, keep, fn)
+}
+
+func each[T any](items []T, keep func(T) bool, fn func(T) error) error {
+	for _, v := range items {
+		if !keep(v) {
+			continue
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scan walks repo in batches of exportBatch, holding mu only while a batch
+// is read.
+func scan[T any](mu *sync.RWMutex, repo storage.Repository[T], id func(T) int, prepare func(T) T, keep func(T) bool, fn func(T) error) error {
+	for after := 0; ; {
+		mu.RLock()
+		batch := repo.Scan(after, exportBatch)
+		mu.RUnlock()
+		if len(batch) == 0 {
+			return nil
+		}
+		after = id(batch[len(batch)-1])
+		for i := range batch {
+			batch[i] = prepare(batch[i])
+		}
+		if err := each(batch, keep, fn); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+func TestEmployeeService_ExportWalksEveryBatch(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Sales"})
+	total := exportBatch*2 + 7
+	for i := 1; i <= total; i++ {
+		emp := testEmployee(0, 1+i%2)
+		emp.Email = fmt.Sprintf("e%d@example.com", i)
+		if _, err := emps.Create(ctx, emp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	emps.Delete(ctx, 2)
+
+	var seen []int
+	err := emps.Export(EmployeeFilter{DepartmentID: 1}, func(e models.Employee) error {
+		seen = append(seen, e.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(seen) != exportBatch+2 {
+		t.Errorf("exported %d employees, want %d", len(seen), exportBatch+2)
+	}
+	for i, id := range seen {
+		if id%2 != 0 || id == 2 || (i > 0 && id <= seen[i-1]) {
+			t.Fatalf("exported IDs %v, want even IDs without 2 in order", seen[:i+1])
+		}
+	}
+}
+
+func TestDepartmentService_ExportIncludeDeleted(t *testing.T) {
+	_, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Sales"})
+	depts.Delete(ctx, 2)
+
+	count := func(filter DepartmentFilter) int {
+		n := 0
+		depts.Export(filter, func(models.Department) error { n++; return nil })
+		return n
+	}
+	if got := count(DepartmentFilter{}); got != 1 {
+		t.Errorf("Export() visited %d departments, want 1", got)
+	}
+	if got := count(DepartmentFilter{IncludeDeleted: true}); got != 2 {
+		t.Errorf("Export(includeDeleted) visited %d departments, want 2", got)
+	}
+}
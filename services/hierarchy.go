@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"employee-maintenance/models"
+)
+
+var (
+	ErrInvalidManager = errors.New("manager does not exist")
+	ErrManagerCycle   = errors.New("reporting cycle")
+	ErrHasReports     = errors.New("employee has reports")
+)
+
+// ReportsError is returned when an employee cannot be deleted because other
+// employees still report to them. It matches ErrHasReports.
+type ReportsError struct {
+	ManagerID   int
+	EmployeeIDs []int
+}
+
+func (e *ReportsError) Error() string {
+	return fmt.Sprintf("employee %d has reports %v", e.ManagerID, e.EmployeeIDs)
+}
+
+func (e *ReportsError) Unwrap() error {
+	return ErrHasReports
+}
+
+// checkManager checks that emp's manager is an existing employee who has
+// not been terminated and that following the chain of managers up from it
+// never leads back to emp.
+func (s *EmployeeService) checkManager(emp *models.Employee) error {
+	if emp.ManagerID == 0 {
+		return nil
+	}
+	if emp.ManagerID == emp.ID {
+		return fmt.Errorf("%w: employee %d cannot manage themselves", ErrManagerCycle, emp.ID)
+	}
+	manager, exists := s.live(emp.ManagerID)
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrInvalidManager, emp.ManagerID)
+	}
+	if manager.Status == models.StatusTerminated {
+		return fmt.Errorf("%w: %d has been terminated", ErrInvalidManager, emp.ManagerID)
+	}
+	seen := map[int]bool{manager.ID: true}
+	for manager.ManagerID != 0 {
+		if manager.ManagerID == emp.ID {
+			return fmt.Errorf("%w: employee %d already reports to %d", ErrManagerCycle, emp.ManagerID, emp.ID)
+		}
+		if seen[manager.ManagerID] {
+			break
+		}
+		seen[manager.ManagerID] = true
+		if manager, exists = s.live(manager.ManagerID); !exists {
+			break
+		}
+	}
+	return nil
+}
+
+// reportsByManager groups the staff by the ID of their manager. Terminated
+// employees are no one's report.
+func (s *EmployeeService) reportsByManager() map[int][]models.Employee {
+	byManager := make(map[int][]models.Employee)
+	for _, e := range s.staff() {
+		if e.ManagerID != 0 {
+			byManager[e.ManagerID] = append(byManager[e.ManagerID], e)
+		}
+	}
+	return byManager
+}
+
+// collectReports walks down from id breadth first, up to depth levels (all
+// levels if depth is zero), and returns the employees found and the number of
+// levels below id.
+func collectReports(byManager map[int][]models.Employee, id, depth int) ([]models.Employee, int) {
+	result := []models.Employee{}
+	levels := 0
+	seen := map[int]bool{id: true}
+	frontier := []int{id}
+	for len(frontier) > 0 && (depth == 0 || levels < depth) {
+		var next []int
+		for _, m := range frontier {
+			for _, e := range byManager[m] {
+				if !seen[e.ID] {
+					seen[e.ID] = true
+					result = append(result, e)
+					next = append(next, e.ID)
+				}
+			}
+		}
+		if len(next) > 0 {
+			levels++
+		}
+		frontier = next
+	}
+	return result, levels
+}
+
+// Reports returns the employees below id in the reporting hierarchy, down to
+// depth levels: 1 for direct reports, 0 for every level. Results are sorted
+// by ID.
+func (s *EmployeeService) Reports(id, depth int) ([]models.Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, exists := s.live(id); !exists {
+		return nil, ErrEmployeeNotFound
+	}
+	if depth < 0 {
+		return nil, fmt.Errorf("%w: depth must not be negative", ErrInvalidQuery)
+	}
+	reports, _ := collectReports(s.reportsByManager(), id, depth)
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID < reports[j].ID })
+	return reports, nil
+}
+
+// Chain returns the employee's managers, starting with their direct manager
+// and ending with someone who reports to no one.
+func (s *EmployeeService) Chain(id int) ([]models.Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	emp, exists := s.live(id)
+	if !exists {
+		return nil, ErrEmployeeNotFound
+	}
+	chain := []models.Employee{}
+	seen := map[int]bool{id: true}
+	for emp.ManagerID != 0 && !seen[emp.ManagerID] {
+		seen[emp.ManagerID] = true
+		if emp, exists = s.live(emp.ManagerID); !exists {
+			break
+		}
+		chain = append(chain, emp)
+	}
+	return chain, nil
+}
+
+// SpanOfControl describes the part of the hierarchy below one employee.
+type SpanOfControl struct {
+	EmployeeID    int `json:"employeeId"`
+	DirectReports int `json:"directReports"`
+	TotalReports  int `json:"totalReports"`
+	// Depth is the number of levels of reports below the employee.
+	Depth int `json:"depth"`
+}
+
+// SpanOfControl counts the employee's direct and indirect reports.
+func (s *EmployeeService) SpanOfControl(id int) (SpanOfControl, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, exists := s.live(id); !exists {
+		return SpanOfControl{}, ErrEmployeeNotFound
+	}
+	byManager := s.reportsByManager()
+	all, depth := collectReports(byManager, id, 0)
+	return SpanOfControl{
+		EmployeeID:    id,
+		DirectReports: len(byManager[id]),
+		TotalReports:  len(all),
+		Depth:         depth,
+	}, nil
+}
+
+// SpanStats summarizes span of control across everyone who has at least one
+// direct report.
+type SpanStats struct {
+	Managers             int     `json:"managers"`
+	MinDirectReports     int     `json:"minDirectReports"`
+	MaxDirectReports     int     `json:"maxDirectReports"`
+	AverageDirectReports float64 `json:"averageDirectReports"`
+	// Layers is the number of levels in the deepest reporting chain.
+	Layers int `json:"layers"`
+}
+
+// SpanStats computes span-of-control statistics for the whole organization.
+func (s *EmployeeService) SpanStats() SpanStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byManager := s.reportsByManager()
+	var stats SpanStats
+	total := 0
+	for _, reports := range byManager {
+		n := len(reports)
+		if stats.Managers == 0 || n < stats.MinDirectReports {
+			stats.MinDirectReports = n
+		}
+		if n > stats.MaxDirectReports {
+			stats.MaxDirectReports = n
+		}
+		stats.Managers++
+		total += n
+	}
+	if stats.Managers > 0 {
+		stats.AverageDirectReports = float64(total) / float64(stats.Managers)
+	}
+	for _, e := range s.staff() {
+		if e.ManagerID != 0 {
+			continue
+		}
+		if _, depth := collectReports(byManager, e.ID, 0); depth+1 > stats.Layers {
+			stats.Layers = depth + 1
+		}
+	}
+	return stats
+}
+
+// releaseReports handles the direct reports of an employee about to be
+// deleted: without a reassignTo they block the delete, otherwise they move
+// to the reassignTo employee.
+func (s *EmployeeService) releaseReports(ctx context.Context, id, reassignTo int) error {
+	reports := s.reportsByManager()[id]
+	if len(reports) == 0 {
+		return nil
+	}
+	if reassignTo == 0 {
+		ids := make([]int, len(reports))
+		for i, e := range reports {
+			ids[i] = e.ID
+		}
+		return &ReportsError{ManagerID: id, EmployeeIDs: ids}
+	}
+	target, exists := s.live(reassignTo)
+	if !exists || target.ID == id {
+		return fmt.Errorf("%w: %d", ErrInvalidManager, reassignTo)
+	}
+	for i := range reports {
+		reports[i].ManagerID = target.ID
+		if err := s.checkManager(&reports[i]); err != nil {
+			return err
+		}
+	}
+	for _, e := range reports {
+		if _, err := s.put(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkReportsWithin fails if anyone outside group reports to a member of
+// group, so that deleting the group as a whole leaves no one without a
+// manager.
+func (s *EmployeeService) checkReportsWithin(group []models.Employee) error {
+	members := make(map[int]bool, len(group))
+	for _, e := range group {
+		members[e.ID] = true
+	}
+	byManager := s.reportsByManager()
+	for _, e := range group {
+		var outside []int
+		for _, r := range byManager[e.ID] {
+			if !members[r.ID] {
+				outside = append(outside, r.ID)
+			}
+		}
+		if len(outside) > 0 {
+			return &ReportsError{ManagerID: e.ID, EmployeeIDs: outside}
+		}
+	}
+	return nil
+}
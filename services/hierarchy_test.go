@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+// newOrg builds the hierarchy 1 <- 2 <- 3 <- 4 with 5 also reporting to 1.
+func newOrg(t *testing.T) *EmployeeService {
+	t.Helper()
+	emps := NewEmployeeService()
+	for _, e := range []struct{ id, manager int }{{1, 0}, {2, 1}, {3, 2}, {4, 3}, {5, 1}} {
+		emp := testEmployee(e.id, 0)
+		emp.ManagerID = e.manager
+		if _, err := emps.Create(ctx, emp); err != nil {
+			t.Fatalf("Create(%d) error = %v", e.id, err)
+		}
+	}
+	return emps
+}
+
+func TestEmployeeService_ManagerMustExist(t *testing.T) {
+	emps := newOrg(t)
+	emp := testEmployee(0, 0)
+	emp.ManagerID = 42
+
+	if _, err := emps.Create(ctx, emp); !errors.Is(err, ErrInvalidManager) {
+		t.Errorf("Create() error = %v, want %v", err, ErrInvalidManager)
+	}
+}
+
+func TestEmployeeService_ManagerCycle(t *testing.T) {
+	emps := newOrg(t)
+
+	_, err := emps.Patch(ctx, 1, MergePatch(`{"managerId":4}`), 0)
+	if !errors.Is(err, ErrManagerCycle) {
+		t.Errorf("Patch() error = %v, want %v", err, ErrManagerCycle)
+	}
+	_, err = emps.Patch(ctx, 2, MergePatch(`{"managerId":2}`), 0)
+	if !errors.Is(err, ErrManagerCycle) {
+		t.Errorf("Patch(self) error = %v, want %v", err, ErrManagerCycle)
+	}
+	if _, err := emps.Patch(ctx, 4, MergePatch(`{"managerId":5}`), 0); err != nil {
+		t.Errorf("Patch(sideways) error = %v", err)
+	}
+}
+
+func TestEmployeeService_Reports(t *testing.T) {
+	emps := newOrg(t)
+
+	direct, err := emps.Reports(1, 1)
+	if err != nil {
+		t.Fatalf("Reports() error = %v", err)
+	}
+	if got := ids(direct); !equalInts(got, []int{2, 5}) {
+		t.Errorf("Reports(depth=1) = %v, want [2 5]", got)
+	}
+	all, _ := emps.Reports(1, 0)
+	if got := ids(all); !equalInts(got, []int{2, 3, 4, 5}) {
+		t.Errorf("Reports(all) = %v, want [2 3 4 5]", got)
+	}
+	// An employee without reports gets an empty list, which encodes as [].
+	if none, err := emps.Reports(4, 0); err != nil || none == nil || len(none) != 0 {
+		t.Errorf("Reports(no reports) = %#v, %v, want an empty list", none, err)
+	}
+	if _, err := emps.Reports(9, 1); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("Reports(unknown) error = %v, want %v", err, ErrEmployeeNotFound)
+	}
+}
+
+func TestEmployeeService_Chain(t *testing.T) {
+	emps := newOrg(t)
+
+	chain, err := emps.Chain(4)
+	if err != nil {
+		t.Fatalf("Chain() error = %v", err)
+	}
+	if got := ids(chain); !equalInts(got, []int{3, 2, 1}) {
+		t.Errorf("Chain(4) = %v, want [3 2 1]", got)
+	}
+	if chain, _ := emps.Chain(1); len(chain) != 0 {
+		t.Errorf("Chain(1) = %v, want empty", ids(chain))
+	}
+}
+
+func TestEmployeeService_SpanOfControl(t *testing.T) {
+	emps := newOrg(t)
+
+	span, _ := emps.SpanOfControl(1)
+	want := SpanOfControl{EmployeeID: 1, DirectReports: 2, TotalReports: 4, Depth: 3}
+	if span != want {
+		t.Errorf("SpanOfControl(1) = %+v, want %+v", span, want)
+	}
+	stats := emps.SpanStats()
+	if stats.Managers != 3 || stats.MinDirectReports != 1 || stats.MaxDirectReports != 2 || stats.Layers != 4 {
+		t.Errorf("SpanStats() = %+v", stats)
+	}
+	if stats.AverageDirectReports != 4.0/3 {
+		t.Errorf("AverageDirectReports = %v, want %v", stats.AverageDirectReports, 4.0/3)
+	}
+}
+
+func TestEmployeeService_DeleteManager(t *testing.T) {
+	emps := newOrg(t)
+
+	err := emps.Delete(ctx, 2)
+	var rerr *ReportsError
+	if !errors.As(err, &rerr) || !equalInts(rerr.EmployeeIDs, []int{3}) {
+		t.Fatalf("Delete() error = %v, want reports [3]", err)
+	}
+	if err := emps.DeleteWithOptions(ctx, 2, EmployeeDeleteOptions{ReassignReportsTo: 3}); !errors.Is(err, ErrManagerCycle) {
+		t.Errorf("DeleteWithOptions(to own report) error = %v, want %v", err, ErrManagerCycle)
+	}
+	if err := emps.DeleteWithOptions(ctx, 2, EmployeeDeleteOptions{ReassignReportsTo: 1}); err != nil {
+		t.Fatalf("DeleteWithOptions() error = %v", err)
+	}
+	if emp, _ := emps.Retrieve(3); emp.ManagerID != 1 {
+		t.Errorf("employee 3 manager = %d, want 1", emp.ManagerID)
+	}
+}
+
+func TestEmployeeService_ReassignReportsFailureMovesNoOne(t *testing.T) {
+	leave := map[string]func(*EmployeeService) error{
+		"delete": func(emps *EmployeeService) error {
+			return emps.DeleteWithOptions(ctx, 1, EmployeeDeleteOptions{ReassignReportsTo: 4})
+		},
+		"terminate": func(emps *EmployeeService) error {
+			_, err := emps.Terminate(ctx, 1, TerminateOptions{Reason: "Resigned", ReassignReportsTo: 4})
+			return err
+		},
+	}
+	for name, leave := range leave {
+		t.Run(name, func(t *testing.T) {
+			repo := &failingRepo[models.Employee]{Memory: storage.NewMemory[models.Employee]()}
+			emps := NewEmployeeServiceWithRepository(repo)
+			for _, e := range []struct{ id, manager int }{{1, 0}, {2, 1}, {3, 1}, {4, 0}} {
+				emp := testEmployee(e.id, 0)
+				emp.ManagerID = e.manager
+				emps.Create(ctx, emp)
+			}
+
+			repo.failID = 3
+			if err := leave(emps); !errors.Is(err, errStorage) {
+				t.Fatalf("error = %v, want %v", err, errStorage)
+			}
+			for _, id := range []int{2, 3} {
+				if emp, _ := emps.Retrieve(id); emp.ManagerID != 1 {
+					t.Errorf("employee %d manager = %d, want 1", id, emp.ManagerID)
+				}
+			}
+			if emp, err := emps.Retrieve(1); err != nil || emp.Status != models.StatusActive {
+				t.Errorf("Retrieve(1) = %+v, %v, want the manager still active", emp, err)
+			}
+		})
+	}
+}
+
+func TestDepartmentService_CascadeKeepsManagersOfOutsiders(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Sales"})
+	emps.Create(ctx, testEmployee(1, 1))
+	report := testEmployee(2, 2)
+	report.ManagerID = 1
+	emps.Create(ctx, report)
+
+	err := depts.DeleteWithOptions(ctx, 1, DeleteOptions{Policy: DeleteCascade})
+	if !errors.Is(err, ErrHasReports) {
+		t.Errorf("DeleteWithOptions(cascade) error = %v, want %v", err, ErrHasReports)
+	}
+	if _, err := emps.Retrieve(1); err != nil {
+		t.Errorf("Retrieve() after refused cascade error = %v", err)
+	}
+}
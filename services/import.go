@@ -0,0 +1,508 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"employee-maintenance/models"
+)
+
+var (
+	ErrInvalidImport = errors.New("invalid import file")
+	ErrImportFailed  = errors.New("import failed")
+)
+
+// HeaderMode says whether the first row of an import names its columns.
+type HeaderMode int
+
+const (
+	// HeaderDetect treats the first row as a header if any of its cells
+	// names a known column.
+	HeaderDetect HeaderMode = iota
+	HeaderPresent
+	HeaderAbsent
+)
+
+// The columns an import understands. department holds either a department ID
+// or a department name; managerEmail may name an employee elsewhere in the
+// same file.
+const (
+	columnFirstName    = "firstName"
+	columnLastName     = "lastName"
+	columnEmail        = "email"
+	columnDepartment   = "department"
+	columnManagerID    = "managerId"
+	columnManagerEmail = "managerEmail"
+)
+
+var importColumns = []string{columnFirstName, columnLastName, columnEmail, columnDepartment, columnManagerID, columnManagerEmail}
+
+// DefaultImportColumns is the column order assumed for a file without a
+// header when ImportOptions.Columns is empty.
+var DefaultImportColumns = []string{columnFirstName, columnLastName, columnEmail, columnDepartment}
+
+// headerAliases lets headers name the department column the way people tend
+// to. Headers are compared after normalizeHeader.
+var headerAliases = map[string]string{
+	"departmentid":   columnDepartment,
+	"departmentname": columnDepartment,
+	"dept":           columnDepartment,
+}
+
+// ImportOptions controls how Import reads a CSV file. Columns gives the
+// column for each position when the file has no header. Mapping renames
+// header cells to columns, for example "Given Name" to firstName; headers
+// that match a column name, ignoring case, spaces, underscores and hyphens,
+// need no mapping, and columns that match nothing are ignored.
+// CreateDepartments creates departments named in the file that do not exist
+// yet. DryRun checks every row and reports what would be created without
+// writing anything.
+type ImportOptions struct {
+	Header            HeaderMode
+	Columns           []string
+	Mapping           map[string]string
+	CreateDepartments bool
+	DryRun            bool
+}
+
+// ParseImportOptions reads header (true or false; detected if absent),
+// columns (comma-separated), map (repeatable, "Header:column"),
+// createDepartments and dryRun from query parameters.
+func ParseImportOptions(q url.Values) (ImportOptions, error) {
+	var opts ImportOptions
+	if q.Get("header") != "" {
+		present, err := boolParam(q, "header")
+		if err != nil {
+			return opts, err
+		}
+		opts.Header = HeaderAbsent
+		if present {
+			opts.Header = HeaderPresent
+		}
+	}
+	if v := q.Get("columns"); v != "" {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.TrimSpace(c)
+			if c != "" && !isImportColumn(c) {
+				return opts, fmt.Errorf("%w: unknown column %q", ErrInvalidQuery, c)
+			}
+			opts.Columns = append(opts.Columns, c)
+		}
+	}
+	for _, m := range q["map"] {
+		i := strings.LastIndex(m, ":")
+		if i < 0 {
+			return opts, fmt.Errorf("%w: map must be Header:column", ErrInvalidQuery)
+		}
+		header, column := strings.TrimSpace(m[:i]), strings.TrimSpace(m[i+1:])
+		if !isImportColumn(column) {
+			return opts, fmt.Errorf("%w: unknown column %q", ErrInvalidQuery, column)
+		}
+		if opts.Mapping == nil {
+			opts.Mapping = make(map[string]string)
+		}
+		opts.Mapping[header] = column
+	}
+	var err error
+	if opts.CreateDepartments, err = boolParam(q, "createDepartments"); err != nil {
+		return opts, err
+	}
+	if opts.DryRun, err = boolParam(q, "dryRun"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func isImportColumn(name string) bool {
+	for _, c := range importColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportRow reports the problems with one row of an import. Line is the
+// line of the file the row starts on.
+type ImportRow struct {
+	Line   int          `json:"line"`
+	Errors []FieldError `json:"errors"`
+}
+
+// ImportError is returned when any row of an import is invalid; nothing is
+// written. It matches ErrImportFailed.
+type ImportError struct {
+	Rows []ImportRow
+}
+
+func (e *ImportError) Error() string {
+	first := e.Rows[0]
+	return fmt.Sprintf("%d invalid rows, first at line %d: %s: %s",
+		len(e.Rows), first.Line, first.Errors[0].Field, first.Errors[0].Reason)
+}
+
+func (e *ImportError) Unwrap() error {
+	return ErrImportFailed
+}
+
+// ImportResult lists the employees an import created, in file order, and
+// the departments it created for them. In a dry run nothing was stored but
+// the records carry the IDs they would have been given.
+type ImportResult struct {
+	DryRun      bool                `json:"dryRun"`
+	Employees   []models.Employee   `json:"employees"`
+	Departments []models.Department `json:"departments"`
+}
+
+// Import creates an employee for every row of a CSV file. Every row is
+// checked before anything is written, and either all of them are created or,
+// if any is invalid, none are and the error is an *ImportError listing the
+// problems row by row. A UTF-8 byte order mark at the start is ignored.
+func (s *EmployeeService) Import(ctx context.Context, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	records, err := readImport(r, opts)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, err := s.planImport(records, opts)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if opts.DryRun {
+		return ImportResult{DryRun: true, Employees: plan.employees, Departments: plan.departments}, nil
+	}
+	return s.commitImport(ctx, plan)
+}
+
+// importRecord is one data row of an import, keyed by column.
+type importRecord struct {
+	line   int
+	values map[string]string
+}
+
+func readImport(r io.Reader, opts ImportOptions) ([]importRecord, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var rows [][]string
+	var lines []int
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultImportColumns
+	}
+	header := opts.Header == HeaderPresent || (opts.Header == HeaderDetect && looksLikeHeader(rows[0], opts.Mapping))
+	if header {
+		columns = make([]string, len(rows[0]))
+		for i, cell := range rows[0] {
+			columns[i] = headerColumn(cell, opts.Mapping)
+		}
+		rows, lines = rows[1:], lines[1:]
+	}
+	for _, required := range []string{columnFirstName, columnLastName, columnEmail} {
+		if !contains(columns, required) {
+			return nil, fmt.Errorf("%w: no %s column", ErrInvalidImport, required)
+		}
+	}
+
+	records := make([]importRecord, len(rows))
+	for i, row := range rows {
+		values := make(map[string]string)
+		for j, cell := range row {
+			if j < len(columns) && columns[j] != "" {
+				values[columns[j]] = strings.TrimSpace(cell)
+			}
+		}
+		records[i] = importRecord{line: lines[i], values: values}
+	}
+	return records, nil
+}
+
+func looksLikeHeader(row []string, mapping map[string]string) bool {
+	for _, cell := range row {
+		if headerColumn(cell, mapping) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// headerColumn returns the column a header cell names, or "" if it names
+// none.
+func headerColumn(cell string, mapping map[string]string) string {
+	key := normalizeHeader(cell)
+	for header, column := range mapping {
+		if normalizeHeader(header) == key {
+			return column
+		}
+	}
+	for _, c := range importColumns {
+		if normalizeHeader(c) == key {
+			return c
+		}
+	}
+	return headerAliases[key]
+}
+
+func normalizeHeader(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// importPlan is a checked import, ready to be written. employees is in file
+// order; order lists their indices with managers before their reports.
+type importPlan struct {
+	employees   []models.Employee
+	order       []int
+	departments []models.Department
+}
+
+// planImport checks every record against the current state and assigns the
+// IDs the new records will get.
+func (s *EmployeeService) planImport(records []importRecord, opts ImportOptions) (importPlan, error) {
+	var plan importPlan
+	var failed []ImportRow
+	rowErrors := make([]validator, len(records))
+	depts := newDepartmentResolver(s.departments, opts.CreateDepartments)
+
+	firstID := s.nextID()
+	byEmail := make(map[string]int)
+	for _, e := range s.list() {
+		byEmail[strings.ToLower(e.Email)] = e.ID
+	}
+	for i, rec := range records {
+		email := strings.ToLower(strings.TrimSpace(rec.values[columnEmail]))
+		if _, exists := byEmail[email]; !exists && email != "" {
+			byEmail[email] = firstID + i
+		}
+	}
+
+	pending := make(map[int]int) // employee ID to index, for rows not yet ordered
+	for i, rec := range records {
+		v := &rowErrors[i]
+		emp := models.Employee{
+			ID:        firstID + i,
+			FirstName: rec.values[columnFirstName],
+			LastName:  rec.values[columnLastName],
+			Email:     rec.values[columnEmail],
+		}
+		// Rows are new employees, so they get the lifecycle defaults a
+		// create does.
+		var verr *ValidationError
+		if err := s.checkStatus(nil, &emp); errors.As(err, &verr) {
+			v.fields = append(v.fields, verr.Fields...)
+		}
+		if err := validateEmployee(&emp); errors.As(err, &verr) {
+			v.fields = append(v.fields, verr.Fields...)
+		}
+		emp.DepartmentID = depts.resolve(v, rec.values[columnDepartment])
+		emp.ManagerID = s.resolveManager(v, rec.values, byEmail)
+		plan.employees = append(plan.employees, emp)
+		pending[emp.ID] = i
+	}
+
+	// Order the rows so that everyone's manager is written before them.
+	for len(pending) > 0 {
+		progress := false
+		for i, emp := range plan.employees {
+			if _, waiting := pending[emp.ID]; !waiting {
+				continue
+			}
+			if _, managerWaiting := pending[emp.ManagerID]; managerWaiting && emp.ManagerID != emp.ID {
+				continue
+			}
+			if emp.ManagerID == emp.ID {
+				rowErrors[i].fail(columnManagerEmail, "must not be the employee themselves")
+			}
+			plan.order = append(plan.order, i)
+			delete(pending, emp.ID)
+			progress = true
+		}
+		if !progress {
+			for _, i := range pending {
+				rowErrors[i].fail(columnManagerEmail, "forms a reporting cycle with other rows")
+			}
+			break
+		}
+	}
+
+	for i, rec := range records {
+		if len(rowErrors[i].fields) > 0 {
+			failed = append(failed, ImportRow{Line: rec.line, Errors: rowErrors[i].fields})
+		}
+	}
+	if len(failed) > 0 {
+		return importPlan{}, &ImportError{Rows: failed}
+	}
+	plan.departments = depts.created
+	return plan, nil
+}
+
+// resolveManager reads the managerId or managerEmail of a row. An email may
+// belong to an existing employee or to another row.
+func (s *EmployeeService) resolveManager(v *validator, values map[string]string, byEmail map[string]int) int {
+	idText, email := values[columnManagerID], values[columnManagerEmail]
+	switch {
+	case idText != "" && email != "":
+		v.fail(columnManagerID, "must not be combined with managerEmail")
+	case idText != "":
+		id, err := strconv.Atoi(idText)
+		if err != nil || id < 0 {
+			v.fail(columnManagerID, "must be a positive integer")
+			return 0
+		}
+		if _, exists := s.live(id); id != 0 && !exists {
+			v.fail(columnManagerID, fmt.Sprintf("employee %d does not exist", id))
+		}
+		return id
+	case email != "":
+		id, exists := byEmail[strings.ToLower(email)]
+		if !exists {
+			v.fail(columnManagerEmail, "no employee has this email")
+		}
+		return id
+	}
+	return 0
+}
+
+// departmentResolver turns the department column into IDs, planning new
+// departments when allowed. Names match live departments ignoring case.
+type departmentResolver struct {
+	service *DepartmentService
+	create  bool
+	nextID  int
+	byName  map[string][]int
+	created []models.Department
+}
+
+func newDepartmentResolver(service *DepartmentService, create bool) *departmentResolver {
+	r := &departmentResolver{service: service, create: create, byName: make(map[string][]int), created: []models.Department{}}
+	if service != nil {
+		r.nextID = service.nextID()
+		for _, d := range service.list() {
+			key := strings.ToLower(d.Name)
+			r.byName[key] = append(r.byName[key], d.ID)
+		}
+	}
+	return r
+}
+
+func (r *departmentResolver) resolve(v *validator, value string) int {
+	if value == "" {
+		return 0
+	}
+	if id, err := strconv.Atoi(value); err == nil {
+		if id < 0 {
+			v.fail(columnDepartment, "must not be negative")
+			return 0
+		}
+		if r.service != nil && id != 0 {
+			if _, exists := r.service.live(id); !exists {
+				v.fail(columnDepartment, fmt.Sprintf("department %d does not exist", id))
+			}
+		}
+		return id
+	}
+	if r.service == nil {
+		v.fail(columnDepartment, "must be a department ID")
+		return 0
+	}
+	ids := r.byName[strings.ToLower(value)]
+	switch {
+	case len(ids) == 1:
+		return ids[0]
+	case len(ids) > 1:
+		v.fail(columnDepartment, fmt.Sprintf("%q matches more than one department", value))
+		return 0
+	case !r.create:
+		v.fail(columnDepartment, fmt.Sprintf("no department is named %q", value))
+		return 0
+	}
+	dept := models.Department{ID: r.nextID, Name: value}
+	var verr *ValidationError
+	if err := validateDepartment(&dept); errors.As(err, &verr) {
+		for _, f := range verr.Fields {
+			v.fail(columnDepartment, f.Reason)
+		}
+		return 0
+	}
+	r.nextID++
+	r.created = append(r.created, dept)
+	r.byName[strings.ToLower(value)] = []int{dept.ID}
+	return dept.ID
+}
+
+// commitImport writes a checked plan. The plan was checked under the same
+// lock, so only a storage failure can stop it; the writes are staged and
+// committed together so that such a failure leaves nothing behind, not even
+// audit entries.
+func (s *EmployeeService) commitImport(ctx context.Context, plan importPlan) (ImportResult, error) {
+	st := newStaging(s, s.departments)
+	depts := []models.Department{}
+	for _, d := range plan.departments {
+		created, err := st.departments.put(ctx, d)
+		if err != nil {
+			return ImportResult{}, err
+		}
+		depts = append(depts, created)
+	}
+	written := make([]models.Employee, len(plan.employees))
+	for _, i := range plan.order {
+		emp := plan.employees[i]
+		if err := st.employees.check(&emp); err != nil {
+			return ImportResult{}, err
+		}
+		created, err := st.employees.put(ctx, emp)
+		if err != nil {
+			return ImportResult{}, err
+		}
+		written[i] = created
+	}
+	if err := st.commit(s, s.departments); err != nil {
+		return ImportResult{}, err
+	}
+	return ImportResult{Employees: written, Departments: depts}, nil
+}
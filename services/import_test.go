@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+func TestImport_HeaderBOMAndDepartments(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	file := "\ufeffFirst Name,last_name,Email,Department,Manager Email,Phone\n" +
+		"Ada,Lovelace,ada@example.com,engineering,,555-0100\n" +
+		"Grace,Hopper,grace@example.com,1,ada@example.com,\n" +
+		"Linus,Torvalds,linus@example.com,Kernel,grace@example.com,\n"
+	result, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{CreateDepartments: true})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Employees) != 3 || len(result.Departments) != 1 || result.Departments[0].Name != "Kernel" {
+		t.Fatalf("Import() = %+v, want 3 employees and a Kernel department", result)
+	}
+	ada, grace, linus := result.Employees[0], result.Employees[1], result.Employees[2]
+	if ada.FirstName != "Ada" || ada.DepartmentID != 1 {
+		t.Errorf("first row = %+v, want Ada in department 1", ada)
+	}
+	if grace.ManagerID != ada.ID || linus.ManagerID != grace.ID {
+		t.Errorf("managers = %d, %d, want %d, %d", grace.ManagerID, linus.ManagerID, ada.ID, grace.ID)
+	}
+	if linus.DepartmentID != result.Departments[0].ID {
+		t.Errorf("Linus's department = %d, want the created %d", linus.DepartmentID, result.Departments[0].ID)
+	}
+	if got := len(emps.RetrieveAll()); got != 3 {
+		t.Errorf("stored %d employees, want 3", got)
+	}
+}
+
+func TestImport_ManagerLaterInFile(t *testing.T) {
+	emps, _ := newLinkedServices()
+	file := "firstName,lastName,email,managerEmail\n" +
+		"Grace,Hopper,grace@example.com,ada@example.com\n" +
+		"Ada,Lovelace,ada@example.com,\n"
+	result, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.Employees[0].ManagerID != result.Employees[1].ID {
+		t.Errorf("Grace's manager = %d, want %d", result.Employees[0].ManagerID, result.Employees[1].ID)
+	}
+}
+
+func TestImport_InvalidRowsWriteNothing(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	file := "Ada,Lovelace,ada@example.com,Engineering\n" +
+		"Grace,Hopper,not-an-email,Sales\n" +
+		"Linus,Torvalds,linus@example.com,7\n"
+	_, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{})
+	var ierr *ImportError
+	if !errors.As(err, &ierr) || !errors.Is(err, ErrImportFailed) {
+		t.Fatalf("Import() error = %v, want an ImportError", err)
+	}
+	if len(ierr.Rows) != 2 || ierr.Rows[0].Line != 2 || ierr.Rows[1].Line != 3 {
+		t.Fatalf("rows = %+v, want lines 2 and 3", ierr.Rows)
+	}
+	if fields := ierr.Rows[0].Errors; len(fields) != 2 || fields[0].Field != "email" || fields[1].Field != "department" {
+		t.Errorf("line 2 errors = %+v, want email and department", fields)
+	}
+	if got := len(emps.RetrieveAll()); got != 0 {
+		t.Errorf("stored %d employees, want 0", got)
+	}
+}
+
+func TestImport_DryRun(t *testing.T) {
+	emps, depts := newLinkedServices()
+	file := "Ada,Lovelace,ada@example.com,Research\n"
+	result, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{DryRun: true, CreateDepartments: true})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if !result.DryRun || len(result.Employees) != 1 || len(result.Departments) != 1 {
+		t.Errorf("Import() = %+v, want one planned employee and department", result)
+	}
+	if len(emps.RetrieveAll()) != 0 || len(depts.RetrieveAll()) != 0 {
+		t.Error("dry run stored records")
+	}
+}
+
+func TestImport_AppliesLifecycleDefaults(t *testing.T) {
+	emps := newLifecycleService()
+	file := "Ada,Lovelace,ada@example.com,1\n"
+	for _, dryRun := range []bool{true, false} {
+		result, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{DryRun: dryRun})
+		if err != nil {
+			t.Fatalf("Import(dryRun=%t) error = %v", dryRun, err)
+		}
+		emp := result.Employees[0]
+		if emp.Status != models.StatusActive || emp.HireDate.String() != "2024-06-15" {
+			t.Errorf("Import(dryRun=%t) status %q hired %s, want active hired 2024-06-15", dryRun, emp.Status, emp.HireDate)
+		}
+	}
+}
+
+func TestImport_ReportingCycle(t *testing.T) {
+	emps, _ := newLinkedServices()
+	file := "firstName,lastName,email,managerEmail\n" +
+		"Ada,Lovelace,ada@example.com,grace@example.com\n" +
+		"Grace,Hopper,grace@example.com,ada@example.com\n"
+	var ierr *ImportError
+	if _, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{}); !errors.As(err, &ierr) || len(ierr.Rows) != 2 {
+		t.Errorf("Import() error = %v, want both rows reported", err)
+	}
+}
+
+func TestImport_Mapping(t *testing.T) {
+	emps, _ := newLinkedServices()
+	opts, err := ParseImportOptions(url.Values{"map": {"Given:firstName", "Family:lastName", "Mail:email"}})
+	if err != nil {
+		t.Fatalf("ParseImportOptions() error = %v", err)
+	}
+	result, err := emps.Import(ctx, strings.NewReader("Given,Family,Mail\nAda,Lovelace,ada@example.com\n"), opts)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.Employees[0].LastName != "Lovelace" {
+		t.Errorf("Import() = %+v, want Lovelace", result.Employees[0])
+	}
+}
+
+func TestImport_InvalidFile(t *testing.T) {
+	emps, _ := newLinkedServices()
+	for _, file := range []string{"", "Ada,\"Lovelace\n", "name,email\nAda,ada@example.com\n"} {
+		if _, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{}); !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("Import(%q) error = %v, want %v", file, err, ErrInvalidImport)
+		}
+	}
+	// A read error stays in the chain, so the server can tell a body that
+	// was too large from a malformed one.
+	body := io.MultiReader(strings.NewReader("firstName,lastName,email\n"), iotest.ErrReader(errStorage))
+	if _, err := emps.Import(ctx, body, ImportOptions{}); !errors.Is(err, ErrInvalidImport) || !errors.Is(err, errStorage) {
+		t.Errorf("Import(read error) error = %v, want %v wrapping %v", err, ErrInvalidImport, errStorage)
+	}
+	if _, err := ParseImportOptions(url.Values{"columns": {"firstName,salary"}}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("ParseImportOptions(unknown column) error = %v, want %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestImport_StorageFailureLeavesNoTrace(t *testing.T) {
+	repo := &failingRepo[models.Employee]{Memory: storage.NewMemory[models.Employee]()}
+	emps, depts := NewEmployeeServiceWithRepository(repo), NewDepartmentService()
+	Link(emps, depts)
+	log := NewAuditLog()
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+
+	repo.fail = true
+	file := "firstName,lastName,email,department\nAda,Lovelace,ada@example.com,Engineering\n"
+	if _, err := emps.Import(ctx, strings.NewReader(file), ImportOptions{CreateDepartments: true}); !errors.Is(err, errStorage) {
+		t.Fatalf("Import() error = %v, want %v", err, errStorage)
+	}
+	if all := depts.RetrieveAll(); len(all) != 0 {
+		t.Errorf("departments = %+v, want none", all)
+	}
+	if page, _ := log.Query(AuditFilter{}, ListOptions{}); page.Total != 0 {
+		t.Errorf("audit log has %d entries, want none", page.Total)
+	}
+}
@@ -0,0 +1,12 @@
+package services
+
+// Link connects the two services so that employees can only reference
+// departments that exist, and department deletes take the department's
+// employees into account. The services share a single lock afterwards, which
+// keeps checks that span both of them consistent. Link must be called before
+// either service is used.
+func Link(emps *EmployeeService, depts *DepartmentService) {
+	emps.mu = depts.mu
+	emps.departments = depts
+	depts.employees = emps
+}
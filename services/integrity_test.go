@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+func newLinkedServices() (*EmployeeService, *DepartmentService) {
+	emps := NewEmployeeService()
+	depts := NewDepartmentService()
+	Link(emps, depts)
+	return emps, depts
+}
+
+func testEmployee(id, deptID int) models.Employee {
+	return models.Employee{
+		ID:           id,
+		FirstName:    "John",
+		LastName:     "Doe",
+		Email:        "john.doe@example.com",
+		DepartmentID: deptID,
+	}
+}
+
+func TestLink_CreateEmployeeWithUnknownDepartment(t *testing.T) {
+	emps, _ := newLinkedServices()
+
+	_, err := emps.Create(ctx, testEmployee(0, 42))
+	if !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Create() error = %v, want %v", err, ErrInvalidDepartment)
+	}
+}
+
+func TestLink_CreateEmployeeWithEmbeddedDepartment(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	emp := testEmployee(0, 0)
+	emp.Department = &models.Department{ID: 1, Name: "Stale"}
+	created, err := emps.Create(ctx, emp)
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
+	if created.DepartmentID != 1 || created.Department != nil {
+		t.Errorf("Create() = %+v, want DepartmentID 1 and no embedded department", created)
+	}
+}
+
+func TestLink_ExpandDepartmentsFollowsRename(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	depts.Update(ctx, models.Department{ID: 1, Name: "Software Engineering"})
+
+	emp, _ := emps.Retrieve(1)
+	expanded := emps.ExpandDepartments(emp)
+	if expanded[0].Department == nil || expanded[0].Department.Name != "Software Engineering" {
+		t.Errorf("ExpandDepartments() Department = %v, want Software Engineering", expanded[0].Department)
+	}
+}
+
+func TestLink_UpdateEmployeeWithUnknownDepartment(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	_, err := emps.Update(ctx, testEmployee(1, 2))
+	if !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Update() error = %v, want %v", err, ErrInvalidDepartment)
+	}
+}
+
+func TestLink_DeleteDepartmentWithEmployees_Restrict(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Create(ctx, testEmployee(2, 1))
+
+	err := depts.Delete(ctx, 1)
+	var dependents *DependentsError
+	if !errors.As(err, &dependents) || !errors.Is(err, ErrDepartmentInUse) {
+		t.Fatalf("Delete() error = %v, want DependentsError", err)
+	}
+	if len(dependents.EmployeeIDs) != 2 {
+		t.Errorf("DependentsError.EmployeeIDs = %v, want 2 employees", dependents.EmployeeIDs)
+	}
+	if _, err := depts.Retrieve(1); err != nil {
+		t.Errorf("Retrieve() after refused delete error = %v, want nil", err)
+	}
+}
+
+func TestLink_DeleteDepartmentWithEmployees_Cascade(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Marketing"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Create(ctx, testEmployee(2, 2))
+
+	if err := depts.DeleteWithOptions(ctx, 1, DeleteOptions{Policy: DeleteCascade}); err != nil {
+		t.Fatalf("DeleteWithOptions() error = %v, want nil", err)
+	}
+	if _, err := emps.Retrieve(1); err != ErrEmployeeNotFound {
+		t.Errorf("Retrieve(1) error = %v, want %v", err, ErrEmployeeNotFound)
+	}
+	if _, err := emps.Retrieve(2); err != nil {
+		t.Errorf("Retrieve(2) error = %v, want nil", err)
+	}
+}
+
+func TestLink_DeleteDepartmentWithEmployees_Reassign(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Marketing"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	if err := depts.DeleteWithOptions(ctx, 1, DeleteOptions{Policy: DeleteReassign, ReassignTo: 2}); err != nil {
+		t.Fatalf("DeleteWithOptions() error = %v, want nil", err)
+	}
+	emp, _ := emps.Retrieve(1)
+	if emp.DepartmentID != 2 {
+		t.Errorf("DepartmentID = %v, want 2", emp.DepartmentID)
+	}
+}
+
+func TestLink_DeleteDepartmentWithEmployees_ReassignToUnknown(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	err := depts.DeleteWithOptions(ctx, 1, DeleteOptions{Policy: DeleteReassign, ReassignTo: 9})
+	if !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("DeleteWithOptions() error = %v, want %v", err, ErrInvalidDepartment)
+	}
+}
+
+func TestLink_DeleteDepartmentStorageFailureLeavesEmployees(t *testing.T) {
+	for _, policy := range []DeletePolicy{DeleteCascade, DeleteReassign} {
+		t.Run(string(policy), func(t *testing.T) {
+			repo := &failingRepo[models.Department]{Memory: storage.NewMemory[models.Department]()}
+			emps, depts := NewEmployeeService(), NewDepartmentServiceWithRepository(repo)
+			Link(emps, depts)
+			depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+			depts.Create(ctx, models.Department{ID: 2, Name: "Marketing"})
+			emps.Create(ctx, testEmployee(1, 1))
+			emps.Create(ctx, testEmployee(2, 1))
+
+			repo.fail = true
+			err := depts.DeleteWithOptions(ctx, 1, DeleteOptions{Policy: policy, ReassignTo: 2})
+			if !errors.Is(err, errStorage) {
+				t.Fatalf("DeleteWithOptions() error = %v, want %v", err, errStorage)
+			}
+			for _, id := range []int{1, 2} {
+				if emp, err := emps.Retrieve(id); err != nil || emp.DepartmentID != 1 {
+					t.Errorf("Retrieve(%d) = %+v, %v, want the employee still in department 1", id, emp, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"employee-maintenance/models"
+)
+
+var ErrInvalidTransition = errors.New("status change not allowed")
+
+const maxReasonLength = 500
+
+// statuses lists the employment statuses in the order an employee usually
+// moves through them.
+var statuses = []models.EmploymentStatus{
+	models.StatusCandidate,
+	models.StatusActive,
+	models.StatusOnLeave,
+	models.StatusTerminated,
+}
+
+// transitions lists the statuses an employee can move to from each status.
+// Moves to and from terminated are made by Terminate and Rehire; the others
+// by Update or Patch.
+var transitions = map[models.EmploymentStatus][]models.EmploymentStatus{
+	models.StatusCandidate:  {models.StatusActive},
+	models.StatusActive:     {models.StatusOnLeave, models.StatusTerminated},
+	models.StatusOnLeave:    {models.StatusActive, models.StatusTerminated},
+	models.StatusTerminated: {models.StatusActive},
+}
+
+// TransitionError is returned when an employee cannot move from one status
+// to another, or not by the operation attempted. It matches
+// ErrInvalidTransition.
+type TransitionError struct {
+	From, To models.EmploymentStatus
+	// Allowed lists the statuses the employee can move to.
+	Allowed []models.EmploymentStatus
+	// Hint names the operation that makes the change, if there is one.
+	Hint string
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("%v: %s to %s", ErrInvalidTransition, e.From, e.To)
+	if e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+func canTransition(from, to models.EmploymentStatus) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func transitionError(from, to models.EmploymentStatus) *TransitionError {
+	err := &TransitionError{From: from, To: to, Allowed: transitions[from]}
+	switch {
+	case to == models.StatusTerminated && canTransition(from, to):
+		err.Hint = "use terminate"
+	case from == models.StatusTerminated && canTransition(from, to):
+		err.Hint = "use rehire"
+	}
+	return err
+}
+
+func knownStatus(status models.EmploymentStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStatus fills in and checks the lifecycle fields of emp before a
+// create (current is nil) or an update of current. A missing status keeps
+// the current one, or is active for a new employee, and a missing hire date
+// keeps the current one, or is today for anyone past candidate. The
+// termination fields are only changed by Terminate and Rehire.
+func (s *EmployeeService) checkStatus(current *models.Employee, emp *models.Employee) error {
+	var v validator
+	from := models.EmploymentStatus("")
+	if current != nil {
+		from = current.Status
+		if emp.Status == "" {
+			emp.Status = current.Status
+		}
+		if emp.HireDate.IsZero() {
+			emp.HireDate = current.HireDate
+		}
+		if emp.TerminationDate.IsZero() {
+			emp.TerminationDate = current.TerminationDate
+		} else if !emp.TerminationDate.Equal(current.TerminationDate.Time) {
+			v.fail("terminationDate", "is set by terminating the employee")
+		}
+		if emp.TerminationReason == "" {
+			emp.TerminationReason = current.TerminationReason
+		} else if emp.TerminationReason != current.TerminationReason {
+			v.fail("terminationReason", "is set by terminating the employee")
+		}
+	} else {
+		if emp.Status == "" {
+			emp.Status = models.StatusActive
+		}
+		if !emp.TerminationDate.IsZero() {
+			v.fail("terminationDate", "is set by terminating the employee")
+		}
+		if emp.TerminationReason != "" {
+			v.fail("terminationReason", "is set by terminating the employee")
+		}
+	}
+	if !knownStatus(emp.Status) {
+		v.fail("status", "must be one of candidate, active, on_leave or terminated")
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	switch {
+	case current == nil && emp.Status == models.StatusTerminated:
+		return &TransitionError{To: emp.Status, Allowed: []models.EmploymentStatus{models.StatusCandidate, models.StatusActive, models.StatusOnLeave}, Hint: "new employees cannot be terminated"}
+	case current != nil && emp.Status != from:
+		if !canTransition(from, emp.Status) || emp.Status == models.StatusTerminated || from == models.StatusTerminated {
+			return transitionError(from, emp.Status)
+		}
+	}
+	if emp.HireDate.IsZero() && emp.Status != models.StatusCandidate {
+		emp.HireDate = s.today()
+	}
+	return nil
+}
+
+func (s *EmployeeService) today() models.Date {
+	return models.NewDate(s.now().UTC())
+}
+
+type TerminateOptions struct {
+	// Date is the last day of employment; today when zero. It cannot be in
+	// the future or before the hire date.
+	Date   models.Date
+	Reason string
+	// IfVersion, when set, must match the employee's stored version.
+	IfVersion int
+	// ReassignReportsTo is the employee that takes over the terminated
+	// employee's direct reports. Without it, an employee with reports cannot
+	// be terminated.
+	ReassignReportsTo int
+}
+
+// Terminate ends the employment of an active or on-leave employee. The
+// employee stays on record with status terminated and no manager.
+func (s *EmployeeService) Terminate(ctx context.Context, id int, opts TerminateOptions) (models.Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emp, exists := s.live(id)
+	if !exists {
+		return models.Employee{}, ErrEmployeeNotFound
+	}
+	if err := checkVersion(opts.IfVersion, emp.Version); err != nil {
+		return models.Employee{}, err
+	}
+	if !canTransition(emp.Status, models.StatusTerminated) {
+		return models.Employee{}, transitionError(emp.Status, models.StatusTerminated)
+	}
+
+	var v validator
+	today := s.today()
+	if opts.Date.IsZero() {
+		opts.Date = today
+	}
+	if opts.Date.After(today.Time) {
+		v.fail("date", "must not be in the future")
+	} else if opts.Date.Before(emp.HireDate.Time) {
+		v.fail("date", "must not be before the hire date "+emp.HireDate.String())
+	}
+	v.text("reason", &opts.Reason, maxReasonLength)
+	if err := v.err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	// As for a delete, the reports move in the same commit as the
+	// termination.
+	st := newStaging(s, nil)
+	if err := st.employees.releaseReports(ctx, id, opts.ReassignReportsTo); err != nil {
+		return models.Employee{}, err
+	}
+	emp.Status = models.StatusTerminated
+	emp.TerminationDate = opts.Date
+	emp.TerminationReason = opts.Reason
+	emp.ManagerID = 0
+	terminated, err := st.employees.put(ctx, emp)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	if err := st.commit(s, nil); err != nil {
+		return models.Employee{}, err
+	}
+	return terminated, nil
+}
+
+type RehireOptions struct {
+	// HireDate starts the new period of employment; today when zero. It
+	// cannot be before the termination date.
+	HireDate models.Date
+	// DepartmentID and ManagerID, when set, replace the ones the employee
+	// had when they left.
+	DepartmentID int
+	ManagerID    int
+	// IfVersion, when set, must match the employee's stored version.
+	IfVersion int
+}
+
+// Rehire makes a terminated employee active again and clears their
+// termination. The earlier employment remains in the audit trail.
+func (s *EmployeeService) Rehire(ctx context.Context, id int, opts RehireOptions) (models.Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emp, exists := s.live(id)
+	if !exists {
+		return models.Employee{}, ErrEmployeeNotFound
+	}
+	if err := checkVersion(opts.IfVersion, emp.Version); err != nil {
+		return models.Employee{}, err
+	}
+	if emp.Status != models.StatusTerminated {
+		return models.Employee{}, &TransitionError{From: emp.Status, To: models.StatusActive, Allowed: transitions[emp.Status], Hint: "only terminated employees can be rehired"}
+	}
+	if opts.HireDate.IsZero() {
+		opts.HireDate = s.today()
+	}
+	if opts.HireDate.Before(emp.TerminationDate.Time) {
+		return models.Employee{}, &ValidationError{Fields: []FieldError{{Field: "hireDate", Reason: "must not be before the termination date " + emp.TerminationDate.String()}}}
+	}
+
+	emp.Status = models.StatusActive
+	emp.HireDate = opts.HireDate
+	emp.TerminationDate = models.Date{}
+	emp.TerminationReason = ""
+	if opts.DepartmentID != 0 {
+		emp.DepartmentID = opts.DepartmentID
+	}
+	if opts.ManagerID != 0 {
+		emp.ManagerID = opts.ManagerID
+	}
+	if err := s.check(&emp); err != nil {
+		return models.Employee{}, err
+	}
+	return s.put(ctx, emp)
+}
+
+// parseStatuses reads the comma-separated status parameter. Without one the
+// list is of active employees; "all" lists every status.
+func parseStatuses(q url.Values) ([]models.EmploymentStatus, error) {
+	v := q.Get("status")
+	switch v {
+	case "":
+		return []models.EmploymentStatus{models.StatusActive}, nil
+	case "all":
+		return nil, nil
+	}
+	var result []models.EmploymentStatus
+	for _, part := range strings.Split(v, ",") {
+		status := models.EmploymentStatus(strings.TrimSpace(part))
+		if !knownStatus(status) {
+			return nil, fmt.Errorf("%w: status must be all or a list of candidate, active, on_leave and terminated", ErrInvalidQuery)
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// setStatuses writes statuses in the form parseStatuses reads.
+func setStatuses(q url.Values, statuses []models.EmploymentStatus) {
+	if len(statuses) == 0 {
+		q.Set("status", "all")
+		return
+	}
+	parts := make([]string, len(statuses))
+	for i, s := range statuses {
+		parts[i] = string(s)
+	}
+	q.Set("status", strings.Join(parts, ","))
+}
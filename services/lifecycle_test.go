@@ -0,0 +1,285 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+func newLifecycleService() *EmployeeService {
+	emps, depts := newLinkedServices()
+	now := func() time.Time { return time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC) }
+	emps.now, depts.now = now, now
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	return emps
+}
+
+func mustDate(t *testing.T, s string) models.Date {
+	t.Helper()
+	d, err := models.ParseDate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestEmployeeService_CreateDefaultsStatusAndHireDate(t *testing.T) {
+	emps := newLifecycleService()
+
+	emp, err := emps.Create(ctx, testEmployee(0, 1))
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
+	if emp.Status != models.StatusActive || emp.HireDate.String() != "2024-06-15" {
+		t.Errorf("Create() = status %q, hire date %s, want active and 2024-06-15", emp.Status, emp.HireDate)
+	}
+
+	candidate := testEmployee(0, 1)
+	candidate.Status = models.StatusCandidate
+	if emp, _ := emps.Create(ctx, candidate); !emp.HireDate.IsZero() {
+		t.Errorf("candidate hire date = %s, want none", emp.HireDate)
+	}
+
+	terminated := testEmployee(0, 1)
+	terminated.Status = models.StatusTerminated
+	if _, err := emps.Create(ctx, terminated); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Create(terminated) error = %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestEmployeeService_CreateRefusesTakenID(t *testing.T) {
+	emps := newLifecycleService()
+	leaver, _ := emps.Create(ctx, testEmployee(0, 1))
+	emps.Terminate(ctx, leaver.ID, TerminateOptions{Reason: "Resigned"})
+	deleted, _ := emps.Create(ctx, testEmployee(0, 1))
+	emps.Delete(ctx, deleted.ID)
+
+	for _, id := range []int{leaver.ID, deleted.ID} {
+		emp := testEmployee(id, 1)
+		emp.Status = models.StatusActive
+		if _, err := emps.Create(ctx, emp); !errors.Is(err, ErrEmployeeExists) {
+			t.Errorf("Create(id %d) error = %v, want %v", id, err, ErrEmployeeExists)
+		}
+	}
+	if got, _ := emps.Retrieve(leaver.ID); got.Status != models.StatusTerminated {
+		t.Errorf("Status = %q, want %q", got.Status, models.StatusTerminated)
+	}
+}
+
+func TestEmployeeService_UpdateEnforcesTransitions(t *testing.T) {
+	tests := []struct {
+		from, to models.EmploymentStatus
+		wantErr  bool
+	}{
+		{models.StatusCandidate, models.StatusActive, false},
+		{models.StatusCandidate, models.StatusOnLeave, true},
+		{models.StatusActive, models.StatusOnLeave, false},
+		{models.StatusOnLeave, models.StatusActive, false},
+		{models.StatusActive, models.StatusCandidate, true},
+		{models.StatusActive, models.StatusTerminated, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			emps := newLifecycleService()
+			emp := testEmployee(0, 1)
+			emp.Status = tt.from
+			emp, _ = emps.Create(ctx, emp)
+
+			emp.Status = tt.to
+			_, err := emps.Update(ctx, emp)
+			if tt.wantErr != errors.Is(err, ErrInvalidTransition) || !tt.wantErr && err != nil {
+				t.Errorf("Update() error = %v, want transition error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmployeeService_UpdateKeepsLifecycleFields(t *testing.T) {
+	emps := newLifecycleService()
+	emp := testEmployee(0, 1)
+	emp.HireDate = mustDate(t, "2020-01-06")
+	emp.Status = models.StatusOnLeave
+	emp, _ = emps.Create(ctx, emp)
+
+	emp.Status, emp.HireDate = "", models.Date{}
+	updated, err := emps.Update(ctx, emp)
+	if err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+	if updated.Status != models.StatusOnLeave || updated.HireDate.String() != "2020-01-06" {
+		t.Errorf("Update() = status %q, hire date %s, want on_leave and 2020-01-06", updated.Status, updated.HireDate)
+	}
+
+	updated.TerminationReason = "Resigned"
+	if _, err := emps.Update(ctx, updated); !errors.Is(err, ErrValidation) {
+		t.Errorf("Update(terminationReason) error = %v, want %v", err, ErrValidation)
+	}
+}
+
+func TestEmployeeService_TerminateAndRehire(t *testing.T) {
+	emps := newLifecycleService()
+	emp := testEmployee(0, 1)
+	emp.HireDate = mustDate(t, "2020-01-06")
+	emp, _ = emps.Create(ctx, emp)
+
+	if _, err := emps.Terminate(ctx, emp.ID, TerminateOptions{Reason: "Resigned", Date: mustDate(t, "2019-12-31")}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Terminate(before hire) error = %v, want %v", err, ErrValidation)
+	}
+	if _, err := emps.Terminate(ctx, emp.ID, TerminateOptions{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Terminate(no reason) error = %v, want %v", err, ErrValidation)
+	}
+
+	terminated, err := emps.Terminate(ctx, emp.ID, TerminateOptions{Reason: "Resigned", Date: mustDate(t, "2024-05-31")})
+	if err != nil {
+		t.Fatalf("Terminate() error = %v, want nil", err)
+	}
+	if terminated.Status != models.StatusTerminated || terminated.TerminationDate.String() != "2024-05-31" || terminated.TerminationReason != "Resigned" {
+		t.Errorf("Terminate() = %+v, want terminated on 2024-05-31 for Resigned", terminated)
+	}
+	if _, err := emps.Terminate(ctx, emp.ID, TerminateOptions{Reason: "Again"}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("second Terminate() error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	report := testEmployee(0, 1)
+	report.ManagerID = emp.ID
+	if _, err := emps.Create(ctx, report); !errors.Is(err, ErrInvalidManager) {
+		t.Errorf("Create(report of terminated) error = %v, want %v", err, ErrInvalidManager)
+	}
+
+	rehired, err := emps.Rehire(ctx, emp.ID, RehireOptions{})
+	if err != nil {
+		t.Fatalf("Rehire() error = %v, want nil", err)
+	}
+	if rehired.Status != models.StatusActive || rehired.HireDate.String() != "2024-06-15" || !rehired.TerminationDate.IsZero() || rehired.TerminationReason != "" {
+		t.Errorf("Rehire() = %+v, want active from 2024-06-15 with no termination", rehired)
+	}
+	if _, err := emps.Rehire(ctx, emp.ID, RehireOptions{}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Rehire(active) error = %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestEmployeeService_TerminateRequiresReportsReassigned(t *testing.T) {
+	emps := newLifecycleService()
+	manager, _ := emps.Create(ctx, testEmployee(0, 1))
+	other, _ := emps.Create(ctx, testEmployee(0, 1))
+	report := testEmployee(0, 1)
+	report.ManagerID = manager.ID
+	report, _ = emps.Create(ctx, report)
+
+	if _, err := emps.Terminate(ctx, manager.ID, TerminateOptions{Reason: "Retired"}); !errors.Is(err, ErrHasReports) {
+		t.Fatalf("Terminate() error = %v, want %v", err, ErrHasReports)
+	}
+	if _, err := emps.Terminate(ctx, manager.ID, TerminateOptions{Reason: "Retired", ReassignReportsTo: other.ID}); err != nil {
+		t.Fatalf("Terminate(reassign) error = %v, want nil", err)
+	}
+	if got, _ := emps.Retrieve(report.ID); got.ManagerID != other.ID {
+		t.Errorf("report ManagerID = %d, want %d", got.ManagerID, other.ID)
+	}
+}
+
+func TestParseEmployeeFilter_Status(t *testing.T) {
+	emps := newLifecycleService()
+	emps.Create(ctx, testEmployee(0, 1))
+	leaver, _ := emps.Create(ctx, testEmployee(0, 1))
+	emps.Terminate(ctx, leaver.ID, TerminateOptions{Reason: "Resigned"})
+
+	for query, want := range map[string]int{"": 1, "status=terminated": 1, "status=all": 2, "status=active,terminated": 2} {
+		q, _ := url.ParseQuery(query)
+		filter, err := ParseEmployeeFilter(q)
+		if err != nil {
+			t.Fatalf("ParseEmployeeFilter(%q) error = %v", query, err)
+		}
+		page, _ := emps.List(filter, ListOptions{})
+		if page.Total != want {
+			t.Errorf("List(%q) total = %d, want %d", query, page.Total, want)
+		}
+		encoded := url.Values{}
+		filter.Encode(encoded)
+		if again, _ := ParseEmployeeFilter(encoded); len(again.Statuses) != len(filter.Statuses) {
+			t.Errorf("Encode(%q) = %q, does not round-trip", query, encoded.Encode())
+		}
+	}
+	if _, err := ParseEmployeeFilter(url.Values{"status": {"retired"}}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("ParseEmployeeFilter(retired) error = %v, want %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestEmployeeService_TerminatedEmployeesLeaveTheOrganization(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Research"})
+	manager, _ := emps.Create(ctx, testEmployee(0, 1))
+	report := testEmployee(0, 2)
+	report.ManagerID = manager.ID
+	report, _ = emps.Create(ctx, report)
+
+	leaver, err := emps.Terminate(ctx, report.ID, TerminateOptions{Reason: "Resigned"})
+	if err != nil {
+		t.Fatalf("Terminate() error = %v, want nil", err)
+	}
+	if leaver.ManagerID != 0 {
+		t.Errorf("terminated ManagerID = %d, want 0", leaver.ManagerID)
+	}
+	if reports, _ := emps.Reports(manager.ID, 1); len(reports) != 0 {
+		t.Errorf("Reports() = %v, want none", reports)
+	}
+	if span, _ := emps.SpanOfControl(manager.ID); span.DirectReports != 0 {
+		t.Errorf("SpanOfControl().DirectReports = %d, want 0", span.DirectReports)
+	}
+	if stats := emps.SpanStats(); stats.Managers != 0 || stats.Layers != 1 {
+		t.Errorf("SpanStats() = %+v, want no managers and 1 layer", stats)
+	}
+	if staff := emps.RetrieveStaff(); len(staff) != 1 || staff[0].ID != manager.ID {
+		t.Errorf("RetrieveStaff() = %v, want only the manager", staff)
+	}
+	if tree, _ := depts.Tree(2); tree.Headcount != 0 {
+		t.Errorf("Tree().Headcount = %d, want 0", tree.Headcount)
+	}
+	if err := depts.Delete(ctx, 2); err != nil {
+		t.Errorf("Delete(department of leaver) error = %v, want nil", err)
+	}
+	if err := emps.Delete(ctx, manager.ID); err != nil {
+		t.Errorf("Delete(former manager) error = %v, want nil", err)
+	}
+}
+
+func TestDepartmentService_DeleteReleasesTerminatedEmployees(t *testing.T) {
+	tests := []struct {
+		opts     DeleteOptions
+		wantDept int
+		deleted  bool
+	}{
+		{DeleteOptions{Policy: DeleteRestrict}, 0, false},
+		{DeleteOptions{Policy: DeleteReassign, ReassignTo: 1}, 1, false},
+		{DeleteOptions{Policy: DeleteCascade}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.opts.Policy), func(t *testing.T) {
+			emps, depts := newLinkedServices()
+			depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+			depts.Create(ctx, models.Department{ID: 2, Name: "Research"})
+			leaver, _ := emps.Create(ctx, testEmployee(0, 2))
+			emps.Terminate(ctx, leaver.ID, TerminateOptions{Reason: "Resigned"})
+
+			if err := depts.DeleteWithOptions(ctx, 2, tt.opts); err != nil {
+				t.Fatalf("DeleteWithOptions() error = %v, want nil", err)
+			}
+			if tt.deleted {
+				if _, err := emps.Retrieve(leaver.ID); !errors.Is(err, ErrEmployeeNotFound) {
+					t.Errorf("Retrieve() error = %v, want %v", err, ErrEmployeeNotFound)
+				}
+				return
+			}
+			rehired, err := emps.Rehire(ctx, leaver.ID, RehireOptions{})
+			if err != nil {
+				t.Fatalf("Rehire() error = %v, want nil", err)
+			}
+			if rehired.DepartmentID != tt.wantDept {
+				t.Errorf("rehired DepartmentID = %d, want %d", rehired.DepartmentID, tt.wantDept)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch    = errors.New("invalid patch document")
+	ErrPatchFailed     = errors.New("patch cannot be applied")
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+)
+
+// Patch transforms the JSON representation of a record.
+type Patch interface {
+	Apply(doc []byte) ([]byte, error)
+}
+
+// MergePatch is an RFC 7396 JSON Merge Patch: objects are merged recursively
+// and null removes a member.
+type MergePatch []byte
+
+func (p MergePatch) Apply(doc []byte) ([]byte, error) {
+	patch, err := decodeJSON(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, patch))
+}
+
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = mergePatch(targetObj[name], value)
+	}
+	return targetObj
+}
+
+// JSONPatch is an RFC 6902 JSON Patch: an ordered list of add, remove,
+// replace, move, copy and test operations. Operations apply in order and the
+// patch fails as a whole if any of them fails.
+type JSONPatch []byte
+
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(p, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func (op patchOperation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		value, err := decodeJSON(*op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch op.Op {
+		case "add":
+			return addValue(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if _, err := getValue(doc, path); err != nil {
+				return nil, err
+			}
+			doc, _, err = removeValue(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			return addValue(doc, path, value)
+		default:
+			current, err := getValue(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(current, value) {
+				return nil, fmt.Errorf("%w: %s", ErrPatchTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = removeValue(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" && isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", ErrPatchFailed, *op.From)
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if doc, _, err = removeValue(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return addValue(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func getValue(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, exists := node[token]
+			if !exists {
+				return nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+		}
+	}
+	return doc, nil
+}
+
+// addValue returns doc with value added at path, replacing an existing
+// object member or inserting into an array.
+func addValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			node[token] = value
+			return node, nil
+		}
+		child, exists := node[token]
+		if !exists {
+			return nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+		}
+		updated, err := addValue(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = updated
+		return node, nil
+	case []any:
+		if len(path) == 1 {
+			i := len(node)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if node[i], err = addValue(node[i], path[1:], value); err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%w: cannot add below a scalar at %q", ErrPatchFailed, token)
+	}
+}
+
+// removeValue returns doc without the value at path, and the removed value.
+func removeValue(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrPatchFailed)
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]any:
+		child, exists := node[token]
+		if !exists {
+			return nil, nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+		}
+		if len(path) == 1 {
+			delete(node, token)
+			return node, child, nil
+		}
+		updated, removed, err := removeValue(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = updated
+		return node, removed, nil
+	case []any:
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+		updated, removed, err := removeValue(node[i], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = updated
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q does not exist", ErrPatchFailed, token)
+	}
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrPatchFailed, token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrPatchFailed, i)
+	}
+	return i, nil
+}
+
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// jsonEqual compares decoded JSON values, treating numbers as equal when
+// their values are, so 1 and 1.0 match.
+func jsonEqual(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if other, exists := bv[k]; !exists || !jsonEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	default:
+		return a == b
+	}
+}
+
+func deepCopy(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(node))
+		for k, child := range node {
+			c[k] = deepCopy(child)
+		}
+		return c
+	case []any:
+		c := make([]any, len(node))
+		for i, child := range node {
+			c[i] = deepCopy(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// applyPatch runs patch against the JSON form of current and decodes the
+// result into a new value of the same type. Members the type does not know
+// are rejected rather than silently dropped.
+func applyPatch[T any](current T, patch Patch) (T, error) {
+	var result T
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return result, err
+	}
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return result, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&result); err != nil {
+		return result, fmt.Errorf("%w: %v", ErrPatchFailed, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("got %s, want %s", gb, wb)
+	}
+}
+
+func TestMergePatch_Apply(t *testing.T) {
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	patch := MergePatch(`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`)
+
+	got, err := patch.Apply([]byte(doc))
+	if err != nil {
+		t.Fatalf("Apply() error = %v, want nil", err)
+	}
+	assertJSON(t, got, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`)
+}
+
+func TestJSONPatch_Apply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append to array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`},
+		{"remove", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		{"test then replace", `{"n":1}`, `[{"op":"test","path":"/n","value":1.0},{"op":"replace","path":"/n","value":2}]`, `{"n":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch(tt.patch).Apply([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Apply() error = %v, want nil", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name, patch string
+		want        error
+	}{
+		{"malformed", `{"op":"add"}`, ErrInvalidPatch},
+		{"unknown op", `[{"op":"frobnicate","path":"/a"}]`, ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/a"}]`, ErrInvalidPatch},
+		{"remove missing", `[{"op":"remove","path":"/missing"}]`, ErrPatchFailed},
+		{"index out of range", `[{"op":"add","path":"/list/5","value":1}]`, ErrPatchFailed},
+		{"test failed", `[{"op":"test","path":"/a","value":"other"}]`, ErrPatchTestFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := JSONPatch(tt.patch).Apply([]byte(`{"a":"b","list":[1]}`))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Apply() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmployeeService_Patch_MergePatch(t *testing.T) {
+	service := NewEmployeeService()
+	created, _ := service.Create(ctx, testEmployee(0, 3))
+
+	patched, err := service.Patch(ctx, created.ID, MergePatch(`{"email":"  new@example.com "}`), created.Version)
+	if err != nil {
+		t.Fatalf("Patch() error = %v, want nil", err)
+	}
+	if patched.Email != "new@example.com" || patched.FirstName != created.FirstName || patched.DepartmentID != 3 {
+		t.Errorf("Patch() = %+v, want only the email changed", patched)
+	}
+	if patched.Version != created.Version+1 {
+		t.Errorf("Patch() Version = %d, want %d", patched.Version, created.Version+1)
+	}
+}
+
+func TestEmployeeService_Patch_FailedTestLeavesRecordUnchanged(t *testing.T) {
+	service := NewEmployeeService()
+	created, _ := service.Create(ctx, testEmployee(0, 0))
+
+	_, err := service.Patch(ctx, created.ID, JSONPatch(`[
+		{"op":"replace","path":"/firstName","value":"Alice"},
+		{"op":"test","path":"/lastName","value":"Smith"}
+	]`), 0)
+	if !errors.Is(err, ErrPatchTestFailed) {
+		t.Fatalf("Patch() error = %v, want %v", err, ErrPatchTestFailed)
+	}
+	stored, _ := service.Retrieve(created.ID)
+	if stored.FirstName != created.FirstName || stored.Version != created.Version {
+		t.Errorf("stored = %+v, want unchanged", stored)
+	}
+}
+
+func TestEmployeeService_Patch_ValidatesResult(t *testing.T) {
+	service := NewEmployeeService()
+	created, _ := service.Create(ctx, testEmployee(0, 0))
+
+	_, err := service.Patch(ctx, created.ID, MergePatch(`{"email":null}`), 0)
+	if reasons := fieldReasons(t, err); reasons["email"] != "is required" {
+		t.Errorf("reason for email = %q, want %q", reasons["email"], "is required")
+	}
+
+	_, err = service.Patch(ctx, created.ID, MergePatch(`{"id":99}`), 0)
+	if reasons := fieldReasons(t, err); reasons["id"] == "" {
+		t.Errorf("Patch() changing the ID was accepted")
+	}
+
+	if _, err := service.Patch(ctx, created.ID, MergePatch(`{"salary":1}`), 0); !errors.Is(err, ErrPatchFailed) {
+		t.Errorf("Patch() with unknown field error = %v, want %v", err, ErrPatchFailed)
+	}
+}
+
+func TestEmployeeService_Patch_StaleVersion(t *testing.T) {
+	service := NewEmployeeService()
+	created, _ := service.Create(ctx, testEmployee(0, 0))
+	service.Update(ctx, created)
+
+	_, err := service.Patch(ctx, created.ID, MergePatch(`{"firstName":"Alice"}`), created.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Patch() error = %v, want %v", err, ErrVersionConflict)
+	}
+}
+
+func TestDepartmentService_Patch(t *testing.T) {
+	service := NewDepartmentService()
+	created, _ := service.Create(ctx, models.Department{Name: "Engineering"})
+
+	patched, err := service.Patch(ctx, created.ID, JSONPatch(`[{"op":"replace","path":"/name","value":"Platform"}]`), 0)
+	if err != nil {
+		t.Fatalf("Patch() error = %v, want nil", err)
+	}
+	if patched.Name != "Platform" {
+		t.Errorf("Patch() Name = %v, want Platform", patched.Name)
+	}
+
+	if _, err := service.Patch(ctx, 99, MergePatch(`{}`), 0); err != ErrDepartmentNotFound {
+		t.Errorf("Patch() error = %v, want %v", err, ErrDepartmentNotFound)
+	}
+}
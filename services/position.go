@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+var (
+	ErrPositionNotFound       = errors.New("position not found")
+	ErrOverBudget             = errors.New("headcount budget exceeded")
+	ErrDepartmentHasPositions = errors.New("department has positions")
+)
+
+const maxLevelLength = 50
+
+// BudgetError is returned when a position would take its department's
+// positions past the department's headcount budget. It matches
+// ErrOverBudget.
+type BudgetError struct {
+	DepartmentID int
+	Budget       float64
+	// Allocated is the FTE of the department's other positions.
+	Allocated float64
+	Requested float64
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%v: department %d has a budget of %g FTE with %g allocated, and %g more was requested",
+		ErrOverBudget, e.DepartmentID, e.Budget, e.Allocated, e.Requested)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrOverBudget
+}
+
+// PositionsError is returned when departments cannot be deleted because
+// positions still belong to them. It matches ErrDepartmentHasPositions.
+type PositionsError struct {
+	PositionIDs []int
+}
+
+func (e *PositionsError) Error() string {
+	return fmt.Sprintf("%v: positions %v belong to departments being deleted", ErrDepartmentHasPositions, e.PositionIDs)
+}
+
+func (e *PositionsError) Unwrap() error {
+	return ErrDepartmentHasPositions
+}
+
+// PositionService keeps the positions departments plan for, filled or open,
+// and holds them to each department's headcount budget. It takes the locks
+// of the employee and department services, so its checks see the same state
+// as their writes.
+type PositionService struct {
+	positions   storage.Repository[models.Position]
+	employees   *EmployeeService
+	departments *DepartmentService
+	audit       *AuditLog
+}
+
+func NewPositionService(employees *EmployeeService, departments *DepartmentService) *PositionService {
+	return NewPositionServiceWithRepository(storage.NewMemory[models.Position](), employees, departments)
+}
+
+func NewPositionServiceWithRepository(repo storage.Repository[models.Position], employees *EmployeeService, departments *DepartmentService) *PositionService {
+	return &PositionService{
+		positions:   repo,
+		employees:   employees,
+		departments: departments,
+	}
+}
+
+// SetAuditLog makes the service record every change in log. It must be
+// called before the service is used.
+func (s *PositionService) SetAuditLog(log *AuditLog) {
+	s.audit = log
+}
+
+type PositionOptions struct {
+	// AllowOverBudget lets a position be created or grown past its
+	// department's headcount budget.
+	AllowOverBudget bool
+	// IfVersion, when set, must match the position's stored version.
+	IfVersion int
+}
+
+// ParsePositionOptions reads allowOverBudget from query parameters.
+func ParsePositionOptions(q url.Values) (PositionOptions, error) {
+	allow, err := boolParam(q, "allowOverBudget")
+	if err != nil {
+		return PositionOptions{}, err
+	}
+	return PositionOptions{AllowOverBudget: allow}, nil
+}
+
+// Create stores a new position with the next free ID. Unless
+// opts.AllowOverBudget is set, it fails with a BudgetError if the
+// department's positions would then exceed its headcount budget.
+func (s *PositionService) Create(ctx context.Context, pos models.Position, opts PositionOptions) (models.Position, error) {
+	unlock := lockPair(s.employees, s.departments)
+	defer unlock()
+	pos.ID = s.nextID()
+	if err := s.check(nil, &pos, opts); err != nil {
+		return models.Position{}, err
+	}
+	return s.put(ctx, pos)
+}
+
+func (s *PositionService) nextID() int {
+	maxID := 0
+	for _, p := range s.positions.List() {
+		if p.ID > maxID {
+			maxID = p.ID
+		}
+	}
+	return maxID + 1
+}
+
+// check validates pos before it replaces current, or is created if current
+// is nil. The budget is only checked when the position adds FTE to a
+// department, so a position in a department that is already over budget
+// can still be edited.
+func (s *PositionService) check(current *models.Position, pos *models.Position, opts PositionOptions) error {
+	var v validator
+	v.text("title", &pos.Title, maxNameLength)
+	v.optionalText("level", &pos.Level, maxLevelLength)
+	if pos.DepartmentID <= 0 {
+		v.fail("departmentId", "is required")
+	}
+	if !(pos.FTE > 0 && pos.FTE <= 1) {
+		v.fail("fte", "must be greater than 0 and at most 1")
+	}
+	if pos.EmployeeID < 0 {
+		v.fail("employeeId", "must not be negative")
+	} else if pos.EmployeeID != 0 {
+		if emp, exists := s.employees.live(pos.EmployeeID); !exists || emp.Status == models.StatusTerminated {
+			v.fail("employeeId", "must be an employee who has not been terminated")
+		} else if other, ok := s.filledBy(pos.EmployeeID); ok && other.ID != pos.ID {
+			v.fail("employeeId", fmt.Sprintf("already fills position %d", other.ID))
+		}
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	dept, exists := s.departments.live(pos.DepartmentID)
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrInvalidDepartment, pos.DepartmentID)
+	}
+	grows := current == nil || current.DepartmentID != pos.DepartmentID || pos.FTE > current.FTE
+	if dept.HeadcountBudget == nil || !grows || opts.AllowOverBudget {
+		return nil
+	}
+	allocated := 0.0
+	for _, p := range s.positions.List() {
+		if p.DepartmentID == pos.DepartmentID && p.ID != pos.ID {
+			allocated += p.FTE
+		}
+	}
+	if allocated+pos.FTE > *dept.HeadcountBudget+fteTolerance {
+		return &BudgetError{DepartmentID: dept.ID, Budget: *dept.HeadcountBudget, Allocated: roundFTE(allocated), Requested: pos.FTE}
+	}
+	return nil
+}
+
+// fteTolerance absorbs the rounding error of adding up fractional FTE.
+const fteTolerance = 1e-9
+
+func roundFTE(fte float64) float64 {
+	return math.Round(fte*1e6) / 1e6
+}
+
+// filledBy returns the position the employee fills, if any.
+func (s *PositionService) filledBy(employeeID int) (models.Position, bool) {
+	for _, p := range s.positions.List() {
+		if p.EmployeeID == employeeID {
+			return p, true
+		}
+	}
+	return models.Position{}, false
+}
+
+// inDepartments returns the IDs of the positions that belong to any of the
+// given departments. The caller holds the department service's lock.
+func (s *PositionService) inDepartments(deptIDs map[int]bool) []int {
+	var ids []int
+	for _, p := range s.positions.List() {
+		if deptIDs[p.DepartmentID] {
+			ids = append(ids, p.ID)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (s *PositionService) Retrieve(id int) (models.Position, error) {
+	unlock := readLockPair(s.employees, s.departments)
+	defer unlock()
+	pos, exists := s.positions.Get(id)
+	if !exists {
+		return models.Position{}, ErrPositionNotFound
+	}
+	return pos, nil
+}
+
+// PositionFilter narrows a List. Open, when set, selects open (true) or
+// filled (false) positions.
+type PositionFilter struct {
+	DepartmentID int
+	EmployeeID   int
+	Open         *bool
+}
+
+// ParsePositionFilter reads departmentId, employeeId and open from query
+// parameters.
+func ParsePositionFilter(q url.Values) (PositionFilter, error) {
+	var f PositionFilter
+	var err error
+	if f.DepartmentID, err = intParam(q, "departmentId"); err != nil {
+		return f, err
+	}
+	if f.EmployeeID, err = intParam(q, "employeeId"); err != nil {
+		return f, err
+	}
+	if q.Get("open") != "" {
+		open, err := boolParam(q, "open")
+		if err != nil {
+			return f, err
+		}
+		f.Open = &open
+	}
+	return f, nil
+}
+
+func (f PositionFilter) matches(pos models.Position) bool {
+	if f.DepartmentID != 0 && pos.DepartmentID != f.DepartmentID {
+		return false
+	}
+	if f.EmployeeID != 0 && pos.EmployeeID != f.EmployeeID {
+		return false
+	}
+	return f.Open == nil || pos.Open() == *f.Open
+}
+
+var positionSortFields = sortFields[models.Position]{
+	"id":           func(p models.Position) any { return p.ID },
+	"title":        func(p models.Position) any { return p.Title },
+	"level":        func(p models.Position) any { return p.Level },
+	"departmentId": func(p models.Position) any { return p.DepartmentID },
+	"fte":          func(p models.Position) any { return p.FTE },
+}
+
+// List returns the page of positions matching filter selected by opts.
+func (s *PositionService) List(filter PositionFilter, opts ListOptions) (Page[models.Position], error) {
+	unlock := readLockPair(s.employees, s.departments)
+	defer unlock()
+	var matched []models.Position
+	for _, p := range s.positions.List() {
+		if filter.matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	return paginate(matched, positionID, positionSortFields, opts)
+}
+
+func positionID(p models.Position) int {
+	return p.ID
+}
+
+// Update replaces a position. Like Create, it fails with a BudgetError if
+// it adds FTE to a department past its budget, unless opts.AllowOverBudget
+// is set.
+func (s *PositionService) Update(ctx context.Context, pos models.Position, opts PositionOptions) (models.Position, error) {
+	unlock := lockPair(s.employees, s.departments)
+	defer unlock()
+	current, exists := s.positions.Get(pos.ID)
+	if !exists {
+		return models.Position{}, ErrPositionNotFound
+	}
+	if err := checkVersion(opts.IfVersion, current.Version); err != nil {
+		return models.Position{}, err
+	}
+	if err := s.check(&current, &pos, opts); err != nil {
+		return models.Position{}, err
+	}
+	return s.put(ctx, pos)
+}
+
+// put stores pos with the next version number and records the change in the
+// audit log once it is stored, undoing it if it cannot be recorded.
+func (s *PositionService) put(ctx context.Context, pos models.Position) (models.Position, error) {
+	var before any
+	prev, existed := s.positions.Get(pos.ID)
+	pos.Version = 1
+	if existed {
+		pos.Version = prev.Version + 1
+		before = prev
+	}
+	if err := s.positions.Put(pos.ID, pos); err != nil {
+		return models.Position{}, err
+	}
+	if err := s.audit.record(ctx, writeOperation(existed, nil, nil), EntityPosition, pos.ID, before, pos); err != nil {
+		restore(s.positions, pos.ID, prev, existed)
+		return models.Position{}, err
+	}
+	return pos, nil
+}
+
+// Delete removes a position. A non-zero ifVersion must match the stored
+// version. Positions are not kept as tombstones; the audit log keeps the
+// deleted position.
+func (s *PositionService) Delete(ctx context.Context, id, ifVersion int) error {
+	unlock := lockPair(s.employees, s.departments)
+	defer unlock()
+	current, exists := s.positions.Get(id)
+	if !exists {
+		return ErrPositionNotFound
+	}
+	if err := checkVersion(ifVersion, current.Version); err != nil {
+		return err
+	}
+	if err := s.positions.Delete(id); err != nil {
+		return err
+	}
+	if err := s.audit.record(ctx, OperationDelete, EntityPosition, id, current, nil); err != nil {
+		s.positions.Put(id, current)
+		return err
+	}
+	return nil
+}
+
+// Headcount compares a department's headcount budget with its positions.
+// FTE figures cover the department's own positions, not those of its
+// child departments.
+type Headcount struct {
+	DepartmentID int `json:"departmentId"`
+	// Budget is the department's headcount budget; absent if it has none.
+	Budget *float64 `json:"budget,omitempty"`
+	// Allocated is the FTE of all the department's positions, split into
+	// Filled and Open.
+	Allocated       float64 `json:"allocated"`
+	Filled          float64 `json:"filled"`
+	Open            float64 `json:"open"`
+	FilledPositions int     `json:"filledPositions"`
+	OpenPositions   int     `json:"openPositions"`
+	// Remaining is the budget not allocated to positions, negative when the
+	// department is over budget; absent without a budget.
+	Remaining *float64 `json:"remaining,omitempty"`
+}
+
+// Headcount reports a department's budget against its filled and open
+// positions. A position whose employee has since been deleted or terminated
+// counts as open.
+func (s *PositionService) Headcount(departmentID int) (Headcount, error) {
+	unlock := readLockPair(s.employees, s.departments)
+	defer unlock()
+	dept, exists := s.departments.live(departmentID)
+	if !exists {
+		return Headcount{}, ErrDepartmentNotFound
+	}
+	h := Headcount{DepartmentID: dept.ID, Budget: dept.HeadcountBudget}
+	for _, p := range s.positions.List() {
+		if p.DepartmentID != dept.ID {
+			continue
+		}
+		h.Allocated += p.FTE
+		if s.filled(p) {
+			h.Filled += p.FTE
+			h.FilledPositions++
+		} else {
+			h.Open += p.FTE
+			h.OpenPositions++
+		}
+	}
+	h.Allocated, h.Filled, h.Open = roundFTE(h.Allocated), roundFTE(h.Filled), roundFTE(h.Open)
+	if h.Budget != nil {
+		remaining := roundFTE(*h.Budget - h.Allocated)
+		h.Remaining = &remaining
+	}
+	return h, nil
+}
+
+// filled reports whether pos has an employee who still works here.
+func (s *PositionService) filled(pos models.Position) bool {
+	if pos.Open() {
+		return false
+	}
+	emp, exists := s.employees.live(pos.EmployeeID)
+	return exists && emp.Status != models.StatusTerminated
+}
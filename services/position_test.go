@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+func newPositionService(t *testing.T, budget float64) (*PositionService, *EmployeeService) {
+	t.Helper()
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering", HeadcountBudget: &budget})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Research"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Create(ctx, testEmployee(2, 1))
+	return NewPositionService(emps, depts), emps
+}
+
+func TestPositionService_CreateEnforcesBudget(t *testing.T) {
+	positions, _ := newPositionService(t, 2)
+
+	for _, fte := range []float64{1, 0.5} {
+		if _, err := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: fte}, PositionOptions{}); err != nil {
+			t.Fatalf("Create(%g FTE) error = %v, want nil", fte, err)
+		}
+	}
+	_, err := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1}, PositionOptions{})
+	var berr *BudgetError
+	if !errors.As(err, &berr) || berr.Allocated != 1.5 || berr.Budget != 2 {
+		t.Fatalf("Create(over budget) error = %v, want BudgetError with 1.5 of 2 allocated", err)
+	}
+	if _, err := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1}, PositionOptions{AllowOverBudget: true}); err != nil {
+		t.Errorf("Create(allowOverBudget) error = %v, want nil", err)
+	}
+	if _, err := positions.Create(ctx, models.Position{Title: "Researcher", DepartmentID: 2, FTE: 1}, PositionOptions{}); err != nil {
+		t.Errorf("Create(no budget) error = %v, want nil", err)
+	}
+}
+
+func TestPositionService_UpdateOnlyChecksBudgetWhenGrowing(t *testing.T) {
+	positions, _ := newPositionService(t, 1)
+	pos, _ := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1}, PositionOptions{})
+	positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 0.5}, PositionOptions{AllowOverBudget: true})
+
+	pos.Title = "Senior Engineer"
+	pos, err := positions.Update(ctx, pos, PositionOptions{})
+	if err != nil {
+		t.Fatalf("Update(title) error = %v, want nil", err)
+	}
+	if pos.Version != 2 {
+		t.Errorf("Version = %d, want 2", pos.Version)
+	}
+	moved := pos
+	moved.DepartmentID = 2
+	if _, err := positions.Update(ctx, moved, PositionOptions{IfVersion: 1}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Update(stale version) error = %v, want %v", err, ErrVersionConflict)
+	}
+	back := pos
+	back.DepartmentID = 2
+	back, _ = positions.Update(ctx, back, PositionOptions{})
+	back.DepartmentID = 1
+	if _, err := positions.Update(ctx, back, PositionOptions{}); !errors.Is(err, ErrOverBudget) {
+		t.Errorf("Update(move into full department) error = %v, want %v", err, ErrOverBudget)
+	}
+}
+
+func TestPositionService_Validation(t *testing.T) {
+	positions, emps := newPositionService(t, 5)
+	positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1, EmployeeID: 1}, PositionOptions{})
+	emps.Terminate(ctx, 2, TerminateOptions{Reason: "Resigned"})
+
+	tests := []struct {
+		name string
+		pos  models.Position
+		want error
+	}{
+		{"no title", models.Position{DepartmentID: 1, FTE: 1}, ErrValidation},
+		{"zero FTE", models.Position{Title: "Engineer", DepartmentID: 1}, ErrValidation},
+		{"FTE above one", models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1.5}, ErrValidation},
+		{"unknown department", models.Position{Title: "Engineer", DepartmentID: 9, FTE: 1}, ErrInvalidDepartment},
+		{"employee already placed", models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1, EmployeeID: 1}, ErrValidation},
+		{"terminated employee", models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1, EmployeeID: 2}, ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := positions.Create(ctx, tt.pos, PositionOptions{}); !errors.Is(err, tt.want) {
+				t.Errorf("Create() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPositionService_Headcount(t *testing.T) {
+	positions, emps := newPositionService(t, 3)
+	positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1, EmployeeID: 1}, PositionOptions{})
+	positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 0.5, EmployeeID: 2}, PositionOptions{})
+	positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 0.2}, PositionOptions{})
+	emps.Terminate(ctx, 2, TerminateOptions{Reason: "Resigned"})
+
+	h, err := positions.Headcount(1)
+	if err != nil {
+		t.Fatalf("Headcount() error = %v, want nil", err)
+	}
+	if h.Allocated != 1.7 || h.Filled != 1 || h.Open != 0.7 || h.FilledPositions != 1 || h.OpenPositions != 2 {
+		t.Errorf("Headcount() = %+v, want 1.7 allocated, 1 filled in 1 position, 0.7 open in 2", h)
+	}
+	if h.Remaining == nil || *h.Remaining != 1.3 {
+		t.Errorf("Remaining = %v, want 1.3", h.Remaining)
+	}
+	if h, _ := positions.Headcount(2); h.Budget != nil || h.Remaining != nil {
+		t.Errorf("Headcount(no budget) = %+v, want no budget or remaining", h)
+	}
+	if _, err := positions.Headcount(9); !errors.Is(err, ErrDepartmentNotFound) {
+		t.Errorf("Headcount(unknown) error = %v, want %v", err, ErrDepartmentNotFound)
+	}
+}
+
+func TestPositionService_RecordsChanges(t *testing.T) {
+	journal := &failingJournal[models.AuditEntry]{MemoryJournal: storage.NewMemoryJournal[models.AuditEntry]()}
+	log := NewAuditLogWithJournal(journal)
+	positions, _ := newPositionService(t, 2)
+	positions.SetAuditLog(log)
+
+	pos, _ := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1}, PositionOptions{})
+	pos.FTE = 0.5
+	pos, _ = positions.Update(ctx, pos, PositionOptions{})
+	positions.Delete(ctx, pos.ID, 0)
+
+	var ops []string
+	for _, e := range log.History(EntityPosition, pos.ID) {
+		ops = append(ops, e.Operation)
+	}
+	if want := []string{OperationCreate, OperationUpdate, OperationDelete}; !slices.Equal(ops, want) {
+		t.Errorf("history operations = %v, want %v", ops, want)
+	}
+
+	journal.fail = true
+	if _, err := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1}, PositionOptions{}); !errors.Is(err, errStorage) {
+		t.Fatalf("Create(failing journal) error = %v, want %v", err, errStorage)
+	}
+	if page, _ := positions.List(PositionFilter{}, ListOptions{}); page.Total != 0 {
+		t.Errorf("List() = %+v, want the unrecorded create undone", page.Items)
+	}
+}
+
+func TestDepartmentService_DeleteKeepsDepartmentsWithPositions(t *testing.T) {
+	for _, opts := range []DeleteOptions{
+		{Policy: DeleteRestrict},
+		{Policy: DeleteReassign, ReassignTo: 2},
+		{Policy: DeleteCascade},
+	} {
+		t.Run(string(opts.Policy), func(t *testing.T) {
+			emps, depts := newLinkedServices()
+			depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+			depts.Create(ctx, models.Department{ID: 2, Name: "Research"})
+			depts.Create(ctx, models.Department{ID: 3, Name: "Platform", ParentID: 1})
+			positions := NewPositionService(emps, depts)
+			depts.SetPositionService(positions)
+			target := 3
+			if opts.Policy == DeleteCascade {
+				target = 1
+			}
+			pos, _ := positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 3, FTE: 1}, PositionOptions{})
+
+			err := depts.DeleteWithOptions(ctx, target, opts)
+			var perr *PositionsError
+			if !errors.As(err, &perr) || !slices.Equal(perr.PositionIDs, []int{pos.ID}) {
+				t.Fatalf("DeleteWithOptions() error = %v, want PositionsError for position %d", err, pos.ID)
+			}
+			if _, err := depts.Retrieve(3); err != nil {
+				t.Errorf("Retrieve(3) error = %v, want the department kept", err)
+			}
+
+			positions.Delete(ctx, pos.ID, 0)
+			if err := depts.DeleteWithOptions(ctx, target, opts); err != nil {
+				t.Errorf("DeleteWithOptions() without positions error = %v, want nil", err)
+			}
+		})
+	}
+}
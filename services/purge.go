@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// PurgeActor is recorded in the audit log for records removed by a Purger.
+const PurgeActor = "purger"
+
+// Purger permanently removes records that have been deleted for longer than
+// Retention. Either service may be nil.
+type Purger struct {
+	Employees   *EmployeeService
+	Departments *DepartmentService
+	Retention   time.Duration
+}
+
+// PurgeOnce removes every record deleted more than Retention before now and
+// returns how many it removed.
+func (p Purger) PurgeOnce(ctx context.Context, now time.Time) (int, error) {
+	ctx = WithActor(ctx, PurgeActor)
+	cutoff := now.Add(-p.Retention)
+	total := 0
+	if p.Employees != nil {
+		n, err := p.Employees.Purge(ctx, cutoff)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	if p.Departments != nil {
+		n, err := p.Departments.Purge(ctx, cutoff)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Run calls PurgeOnce every interval until ctx is cancelled. Failures are
+// logged and retried on the next tick.
+func (p Purger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := p.PurgeOnce(ctx, now); err != nil {
+				log.Printf("purge failed after removing %d records: %v", n, err)
+			} else if n > 0 {
+				log.Printf("purged %d deleted records", n)
+			}
+		}
+	}
+}
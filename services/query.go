@@ -0,0 +1,276 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// MaxLimit caps the page size a caller can ask for.
+const MaxLimit = 1000
+
+// SortKey orders results by one field, ascending unless Desc is set.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions selects one page of a sorted result. A zero Limit returns
+// everything after Offset (or Cursor). Offset and Cursor are mutually
+// exclusive: Offset counts from the start of the result, while Cursor, taken
+// from a previous Page.NextCursor, continues right after the last item of
+// that page even if records were added or removed in between.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   []SortKey
+}
+
+// Page is one slice of a sorted, filtered result. Total counts every match,
+// not just the items on this page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ParseListOptions reads limit, offset, cursor and sort (for example
+// sort=lastName,-id) from query parameters.
+func ParseListOptions(q url.Values) (ListOptions, error) {
+	var opts ListOptions
+	var err error
+	if opts.Limit, err = intParam(q, "limit"); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = intParam(q, "offset"); err != nil {
+		return opts, err
+	}
+	opts.Cursor = q.Get("cursor")
+	for _, field := range strings.Split(q.Get("sort"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		opts.Sort = append(opts.Sort, key)
+	}
+	return opts, nil
+}
+
+// Encode writes the options into q in the form ParseListOptions reads.
+func (o ListOptions) Encode(q url.Values) {
+	setInt(q, "limit", o.Limit)
+	setInt(q, "offset", o.Offset)
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if len(o.Sort) > 0 {
+		fields := make([]string, len(o.Sort))
+		for i, k := range o.Sort {
+			fields[i] = k.Field
+			if k.Desc {
+				fields[i] = "-" + k.Field
+			}
+		}
+		q.Set("sort", strings.Join(fields, ","))
+	}
+}
+
+func intParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", ErrInvalidQuery, name)
+	}
+	return n, nil
+}
+
+func setInt(q url.Values, name string, v int) {
+	if v != 0 {
+		q.Set(name, strconv.Itoa(v))
+	}
+}
+
+func boolParam(q url.Values, name string) (bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s must be true or false", ErrInvalidQuery, name)
+	}
+	return b, nil
+}
+
+func setBool(q url.Values, name string, v bool) {
+	if v {
+		q.Set(name, "true")
+	}
+}
+
+func timeParam(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", ErrInvalidQuery, name)
+	}
+	return t, nil
+}
+
+func setTime(q url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		q.Set(name, t.Format(time.RFC3339Nano))
+	}
+}
+
+// sortFields maps the field names a caller may sort by to the value to
+// compare. Values must be strings or ints.
+type sortFields[T any] map[string]func(T) any
+
+// paginate sorts items according to opts, always breaking ties by ID so the
+// order is total, and cuts out the requested page.
+func paginate[T any](items []T, id func(T) int, fields sortFields[T], opts ListOptions) (Page[T], error) {
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return Page[T]{}, fmt.Errorf("%w: limit and offset must not be negative", ErrInvalidQuery)
+	}
+	if opts.Limit > MaxLimit {
+		return Page[T]{}, fmt.Errorf("%w: limit must be at most %d", ErrInvalidQuery, MaxLimit)
+	}
+	if opts.Cursor != "" && opts.Offset != 0 {
+		return Page[T]{}, fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidQuery)
+	}
+
+	desc := make([]bool, 0, len(opts.Sort)+1)
+	accessors := make([]func(T) any, 0, len(opts.Sort)+1)
+	for _, k := range opts.Sort {
+		f, ok := fields[k.Field]
+		if !ok {
+			return Page[T]{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, k.Field)
+		}
+		accessors = append(accessors, f)
+		desc = append(desc, k.Desc)
+	}
+	accessors = append(accessors, func(v T) any { return id(v) })
+	desc = append(desc, false)
+
+	keyOf := func(v T) []any {
+		key := make([]any, len(accessors))
+		for i, f := range accessors {
+			key[i] = f(v)
+		}
+		return key
+	}
+	keys := make([][]any, len(items))
+	for i, v := range items {
+		keys[i] = keyOf(v)
+	}
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return compareKeys(keys[order[a]], keys[order[b]], desc) < 0
+	})
+
+	start := opts.Offset
+	if opts.Cursor != "" {
+		after, err := decodeCursor(opts.Cursor, len(accessors))
+		if err != nil {
+			return Page[T]{}, err
+		}
+		start = sort.Search(len(order), func(i int) bool {
+			return compareKeys(keys[order[i]], after, desc) > 0
+		})
+	}
+	start = min(start, len(order))
+	end := len(order)
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, len(order))
+	}
+
+	page := Page[T]{Items: make([]T, 0, end-start), Total: len(items)}
+	for _, i := range order[start:end] {
+		page.Items = append(page.Items, items[i])
+	}
+	if end < len(order) && end > start {
+		page.NextCursor = encodeCursor(keys[order[end-1]])
+	}
+	return page, nil
+}
+
+func compareKeys(a, b []any, desc []bool) int {
+	for i := range a {
+		c := compareValues(a[i], b[i])
+		if desc[i] {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders strings case-insensitively and numbers numerically.
+// Cursor values come back from JSON as float64, so numbers are compared as
+// floats.
+func compareValues(a, b any) int {
+	as, aIsString := a.(string)
+	bs, bIsString := b.(string)
+	if aIsString && bIsString {
+		return strings.Compare(strings.ToLower(as), strings.ToLower(bs))
+	}
+	af, bf := toFloat(a), toFloat(b)
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func encodeCursor(key []any) string {
+	data, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string, size int) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var key []any
+	if err := json.Unmarshal(data, &key); err != nil || len(key) != size {
+		return nil, fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalidQuery)
+	}
+	return key, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+func newListTestService(t *testing.T) *EmployeeService {
+	t.Helper()
+	service := NewEmployeeService()
+	for _, emp := range []models.Employee{
+		{ID: 1, FirstName: "John", LastName: "Doe", Email: "john@example.com", DepartmentID: 1},
+		{ID: 2, FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", DepartmentID: 2},
+		{ID: 3, FirstName: "Adam", LastName: "doe", Email: "adam@example.com", DepartmentID: 1},
+		{ID: 4, FirstName: "Bob", LastName: "Brown", Email: "bob@example.org", DepartmentID: 2},
+		{ID: 5, FirstName: "Zoe", LastName: "Doe", Email: "zoe@example.com", DepartmentID: 2},
+	} {
+		if _, err := service.Create(ctx, emp); err != nil {
+			t.Fatalf("Create() error = %v, want nil", err)
+		}
+	}
+	return service
+}
+
+func ids(emps []models.Employee) []int {
+	result := make([]int, len(emps))
+	for i, e := range emps {
+		result[i] = e.ID
+	}
+	return result
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEmployeeService_List_MultiKeySort(t *testing.T) {
+	service := newListTestService(t)
+
+	page, err := service.List(EmployeeFilter{}, ListOptions{Sort: []SortKey{{Field: "lastName"}, {Field: "id", Desc: true}}})
+	if err != nil {
+		t.Fatalf("List() error = %v, want nil", err)
+	}
+	if want := []int{4, 5, 3, 1, 2}; !equalInts(ids(page.Items), want) {
+		t.Errorf("List() IDs = %v, want %v", ids(page.Items), want)
+	}
+}
+
+func TestEmployeeService_List_LimitOffset(t *testing.T) {
+	service := newListTestService(t)
+
+	page, err := service.List(EmployeeFilter{}, ListOptions{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("List() error = %v, want nil", err)
+	}
+	if want := []int{3, 4}; !equalInts(ids(page.Items), want) {
+		t.Errorf("List() IDs = %v, want %v", ids(page.Items), want)
+	}
+	if page.Total != 5 {
+		t.Errorf("List() Total = %d, want 5", page.Total)
+	}
+}
+
+func TestEmployeeService_List_Cursor(t *testing.T) {
+	service := newListTestService(t)
+	opts := ListOptions{Limit: 2, Sort: []SortKey{{Field: "firstName"}}}
+
+	first, err := service.List(EmployeeFilter{}, opts)
+	if err != nil {
+		t.Fatalf("List() error = %v, want nil", err)
+	}
+	if want := []int{3, 4}; !equalInts(ids(first.Items), want) {
+		t.Fatalf("first page IDs = %v, want %v", ids(first.Items), want)
+	}
+
+	// Removing an item from the first page must not shift the next one.
+	service.Delete(ctx, 3)
+
+	opts.Cursor = first.NextCursor
+	second, err := service.List(EmployeeFilter{}, opts)
+	if err != nil {
+		t.Fatalf("List() error = %v, want nil", err)
+	}
+	if want := []int{2, 1}; !equalInts(ids(second.Items), want) {
+		t.Errorf("second page IDs = %v, want %v", ids(second.Items), want)
+	}
+
+	opts.Cursor = second.NextCursor
+	last, _ := service.List(EmployeeFilter{}, opts)
+	if want := []int{5}; !equalInts(ids(last.Items), want) || last.NextCursor != "" {
+		t.Errorf("last page = %v (cursor %q), want %v and no cursor", ids(last.Items), last.NextCursor, want)
+	}
+}
+
+func TestEmployeeService_List_Filter(t *testing.T) {
+	service := newListTestService(t)
+
+	page, err := service.List(EmployeeFilter{DepartmentID: 1, LastName: "DOE"}, ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v, want nil", err)
+	}
+	if want := []int{1, 3}; !equalInts(ids(page.Items), want) || page.Total != 2 {
+		t.Errorf("List() IDs = %v (total %d), want %v", ids(page.Items), page.Total, want)
+	}
+
+	page, _ = service.List(EmployeeFilter{EmailPrefix: "J"}, ListOptions{})
+	if want := []int{1, 2}; !equalInts(ids(page.Items), want) {
+		t.Errorf("List() with emailPrefix IDs = %v, want %v", ids(page.Items), want)
+	}
+}
+
+func TestEmployeeService_List_InvalidOptions(t *testing.T) {
+	service := newListTestService(t)
+
+	for _, opts := range []ListOptions{
+		{Sort: []SortKey{{Field: "salary"}}},
+		{Limit: -1},
+		{Limit: MaxLimit + 1},
+		{Cursor: "not a cursor"},
+		{Cursor: encodeCursor([]any{1}), Offset: 1},
+	} {
+		if _, err := service.List(EmployeeFilter{}, opts); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("List(%+v) error = %v, want %v", opts, err, ErrInvalidQuery)
+		}
+	}
+}
+
+func TestParseListOptions_RoundTrip(t *testing.T) {
+	q := url.Values{"limit": {"10"}, "offset": {"20"}, "sort": {"lastName,-id"}}
+
+	opts, err := ParseListOptions(q)
+	if err != nil {
+		t.Fatalf("ParseListOptions() error = %v, want nil", err)
+	}
+	if opts.Limit != 10 || opts.Offset != 20 || len(opts.Sort) != 2 || !opts.Sort[1].Desc {
+		t.Errorf("ParseListOptions() = %+v", opts)
+	}
+
+	encoded := url.Values{}
+	opts.Encode(encoded)
+	if encoded.Encode() != q.Encode() {
+		t.Errorf("Encode() = %s, want %s", encoded.Encode(), q.Encode())
+	}
+}
+
+func TestDepartmentService_List_NamePrefix(t *testing.T) {
+	service := NewDepartmentService()
+	service.Create(ctx, models.Department{Name: "Engineering"})
+	service.Create(ctx, models.Department{Name: "Marketing"})
+	service.Create(ctx, models.Department{Name: "Enablement"})
+
+	page, err := service.List(DepartmentFilter{NamePrefix: "en"}, ListOptions{Sort: []SortKey{{Field: "name"}}})
+	if err != nil {
+		t.Fatalf("List() error = %v, want nil", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Name != "Enablement" {
+		t.Errorf("List() = %v, want Enablement, Engineering", page.Items)
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+var (
+	ErrScheduledChangeNotFound = errors.New("scheduled change not found")
+	ErrInvalidScheduledChange  = errors.New("invalid scheduled change")
+	ErrChangeNotPending        = errors.New("scheduled change is no longer pending")
+)
+
+// ScheduledChangeService keeps changes to employees that are decided ahead
+// of the time they take effect, and makes them when that time comes. Until
+// then they are pending and can be cancelled.
+type ScheduledChangeService struct {
+	mu        sync.Mutex
+	changes   storage.Repository[models.ScheduledChange]
+	employees *EmployeeService
+	now       func() time.Time
+}
+
+func NewScheduledChangeService(employees *EmployeeService) *ScheduledChangeService {
+	return NewScheduledChangeServiceWithRepository(storage.NewMemory[models.ScheduledChange](), employees)
+}
+
+func NewScheduledChangeServiceWithRepository(repo storage.Repository[models.ScheduledChange], employees *EmployeeService) *ScheduledChangeService {
+	return &ScheduledChangeService{
+		changes:   repo,
+		employees: employees,
+		now:       time.Now,
+	}
+}
+
+// Schedule stores change as pending. It must name an existing employee who
+// has not been terminated, take effect in the future and change at least
+// one field; the department and manager it names must exist now, and are
+// checked again when it is applied.
+func (s *ScheduledChangeService) Schedule(ctx context.Context, change models.ScheduledChange) (models.ScheduledChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emp, err := s.employees.Retrieve(change.EmployeeID)
+	if err != nil {
+		return models.ScheduledChange{}, err
+	}
+	if emp.Status == models.StatusTerminated {
+		return models.ScheduledChange{}, fmt.Errorf("%w: employee %d has been terminated", ErrInvalidScheduledChange, emp.ID)
+	}
+
+	now := s.now().UTC()
+	var v validator
+	switch {
+	case change.EffectiveAt.IsZero():
+		v.fail("effectiveAt", "is required")
+	case !change.EffectiveAt.After(now):
+		v.fail("effectiveAt", "must be in the future")
+	}
+	if change.DepartmentID == nil && change.ManagerID == nil && change.Title == nil {
+		v.fail("departmentId", "one of departmentId, managerId or title is required")
+	}
+	if change.Title != nil {
+		v.optionalText("title", change.Title, maxNameLength)
+	}
+	if err := v.err(); err != nil {
+		return models.ScheduledChange{}, err
+	}
+	preview := applyFields(emp, change)
+	s.employees.mu.RLock()
+	err = s.employees.check(&preview)
+	s.employees.mu.RUnlock()
+	if err != nil {
+		return models.ScheduledChange{}, err
+	}
+
+	change.ID = s.nextID()
+	change.EffectiveAt = change.EffectiveAt.UTC()
+	change.Status = models.ChangePending
+	change.CreatedAt = now
+	change.CreatedBy = ActorFrom(ctx)
+	change.RequestID = RequestIDFrom(ctx)
+	change.AppliedAt, change.CancelledAt, change.CancelledBy, change.Error = nil, nil, "", ""
+	if err := s.changes.Put(change.ID, change); err != nil {
+		return models.ScheduledChange{}, err
+	}
+	return change, nil
+}
+
+func (s *ScheduledChangeService) nextID() int {
+	maxID := 0
+	for _, c := range s.changes.List() {
+		if c.ID > maxID {
+			maxID = c.ID
+		}
+	}
+	return maxID + 1
+}
+
+// applyFields returns emp with the fields change sets.
+func applyFields(emp models.Employee, change models.ScheduledChange) models.Employee {
+	if change.DepartmentID != nil {
+		emp.DepartmentID = *change.DepartmentID
+	}
+	if change.ManagerID != nil {
+		emp.ManagerID = *change.ManagerID
+	}
+	if change.Title != nil {
+		emp.Title = *change.Title
+	}
+	return emp
+}
+
+// ParseChangeStatuses reads the comma-separated status parameter of a list
+// of scheduled changes. Without one only pending changes are listed; "all"
+// lists every status.
+func ParseChangeStatuses(q url.Values) ([]models.ChangeStatus, error) {
+	v := q.Get("status")
+	switch v {
+	case "":
+		return []models.ChangeStatus{models.ChangePending}, nil
+	case "all":
+		return nil, nil
+	}
+	var result []models.ChangeStatus
+	for _, part := range strings.Split(v, ",") {
+		status := models.ChangeStatus(strings.TrimSpace(part))
+		switch status {
+		case models.ChangePending, models.ChangeApplied, models.ChangeCancelled, models.ChangeFailed:
+			result = append(result, status)
+		default:
+			return nil, fmt.Errorf("%w: status must be all or a list of pending, applied, cancelled and failed", ErrInvalidQuery)
+		}
+	}
+	return result, nil
+}
+
+// List returns the changes scheduled for an employee with one of statuses,
+// or any status if none are given, in the order they take effect.
+func (s *ScheduledChangeService) List(employeeID int, statuses []models.ChangeStatus) []models.ScheduledChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []models.ScheduledChange
+	for _, c := range s.changes.List() {
+		if c.EmployeeID != employeeID {
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, c.Status) {
+			continue
+		}
+		result = append(result, c)
+	}
+	sortByEffective(result)
+	return result
+}
+
+func sortByEffective(changes []models.ScheduledChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].EffectiveAt.Equal(changes[j].EffectiveAt) {
+			return changes[i].EffectiveAt.Before(changes[j].EffectiveAt)
+		}
+		return changes[i].ID < changes[j].ID
+	})
+}
+
+// Retrieve returns one of an employee's scheduled changes.
+func (s *ScheduledChangeService) Retrieve(employeeID, id int) (models.ScheduledChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(employeeID, id)
+}
+
+func (s *ScheduledChangeService) get(employeeID, id int) (models.ScheduledChange, error) {
+	change, exists := s.changes.Get(id)
+	if !exists || change.EmployeeID != employeeID {
+		return models.ScheduledChange{}, ErrScheduledChangeNotFound
+	}
+	return change, nil
+}
+
+// Cancel stops a pending change from being applied. The change is kept
+// with status cancelled.
+func (s *ScheduledChangeService) Cancel(ctx context.Context, employeeID, id int) (models.ScheduledChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change, err := s.get(employeeID, id)
+	if err != nil {
+		return models.ScheduledChange{}, err
+	}
+	if change.Status != models.ChangePending {
+		return models.ScheduledChange{}, fmt.Errorf("%w: it is %s", ErrChangeNotPending, change.Status)
+	}
+	now := s.now().UTC()
+	change.Status = models.ChangeCancelled
+	change.CancelledAt = &now
+	change.CancelledBy = ActorFrom(ctx)
+	if err := s.changes.Put(change.ID, change); err != nil {
+		return models.ScheduledChange{}, err
+	}
+	return change, nil
+}
+
+// ApplyDue applies the pending changes whose effective time is not after
+// now, in the order they take effect, and returns how many it applied. A
+// change that can no longer be made, for example because its department
+// was deleted, is marked failed with the reason and the rest go ahead.
+func (s *ScheduledChangeService) ApplyDue(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []models.ScheduledChange
+	for _, c := range s.changes.List() {
+		if c.Status == models.ChangePending && !c.EffectiveAt.After(now) {
+			due = append(due, c)
+		}
+	}
+	sortByEffective(due)
+
+	applied := 0
+	for _, c := range due {
+		changeCtx := WithRequestID(WithActor(ctx, c.CreatedBy), c.RequestID)
+		_, err := s.employees.applyScheduled(changeCtx, c)
+		appliedAt := s.now().UTC()
+		c.AppliedAt = &appliedAt
+		if err != nil {
+			c.Status = models.ChangeFailed
+			c.Error = err.Error()
+		} else {
+			c.Status = models.ChangeApplied
+			applied++
+		}
+		if err := s.changes.Put(c.ID, c); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// Run calls ApplyDue every interval until ctx is cancelled. Failures are
+// logged and retried on the next tick.
+func (s *ScheduledChangeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := s.ApplyDue(ctx, now); err != nil {
+				log.Printf("applying scheduled changes failed after %d: %v", n, err)
+			} else if n > 0 {
+				log.Printf("applied %d scheduled changes", n)
+			}
+		}
+	}
+}
+
+// applyScheduled makes a scheduled change to its employee. The audit log
+// records it as taking effect at the change's effective time, decided when
+// the change was scheduled.
+func (s *EmployeeService) applyScheduled(ctx context.Context, change models.ScheduledChange) (models.Employee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emp, exists := s.live(change.EmployeeID)
+	if !exists {
+		return models.Employee{}, ErrEmployeeNotFound
+	}
+	if emp.Status == models.StatusTerminated {
+		return models.Employee{}, fmt.Errorf("%w: employee %d has been terminated", ErrInvalidScheduledChange, emp.ID)
+	}
+	emp = applyFields(emp, change)
+	if err := s.check(&emp); err != nil {
+		return models.Employee{}, err
+	}
+	return s.put(withSchedule(ctx, change.EffectiveAt, change.CreatedAt), emp)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+func newScheduleServices(t *testing.T) (*ScheduledChangeService, *EmployeeService, *DepartmentService, *AuditLog) {
+	t.Helper()
+	emps, depts := newLinkedServices()
+	log := NewAuditLog()
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Research"})
+	emps.Create(ctx, testEmployee(1, 1))
+	sched := NewScheduledChangeService(emps)
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	sched.now = func() time.Time { return now }
+	return sched, emps, depts, log
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestScheduledChangeService_ApplyDue(t *testing.T) {
+	sched, emps, _, log := newScheduleServices(t)
+	effective := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	title := "Staff Engineer"
+
+	change, err := sched.Schedule(WithActor(ctx, "hr"), models.ScheduledChange{EmployeeID: 1, EffectiveAt: effective, DepartmentID: intPtr(2), Title: &title})
+	if err != nil {
+		t.Fatalf("Schedule() error = %v, want nil", err)
+	}
+	if n, _ := sched.ApplyDue(ctx, effective.Add(-time.Second)); n != 0 {
+		t.Errorf("ApplyDue(before) applied %d, want 0", n)
+	}
+	if emp, _ := emps.Retrieve(1); emp.DepartmentID != 1 {
+		t.Errorf("before effective time, DepartmentID = %d, want 1", emp.DepartmentID)
+	}
+
+	if n, err := sched.ApplyDue(ctx, effective.Add(time.Minute)); n != 1 || err != nil {
+		t.Fatalf("ApplyDue() = %d, %v, want 1, nil", n, err)
+	}
+	emp, _ := emps.Retrieve(1)
+	if emp.DepartmentID != 2 || emp.Title != title {
+		t.Errorf("after ApplyDue, employee = %+v, want department 2 and title %q", emp, title)
+	}
+	if got, _ := sched.Retrieve(1, change.ID); got.Status != models.ChangeApplied {
+		t.Errorf("change status = %q, want applied", got.Status)
+	}
+	history := log.History(EntityEmployee, 1)
+	last := history[len(history)-1]
+	if !last.EffectiveTime.Equal(effective) || !last.DecidedAt.Equal(change.CreatedAt) || last.Actor != "hr" {
+		t.Errorf("audit entry = effective %v, decided %v, actor %q, want %v, %v, hr", last.EffectiveTime, last.DecidedAt, last.Actor, effective, change.CreatedAt)
+	}
+}
+
+func TestScheduledChangeService_Schedule(t *testing.T) {
+	sched, _, _, _ := newScheduleServices(t)
+	future := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		change models.ScheduledChange
+		want   error
+	}{
+		{"past", models.ScheduledChange{EmployeeID: 1, EffectiveAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), DepartmentID: intPtr(2)}, ErrValidation},
+		{"no fields", models.ScheduledChange{EmployeeID: 1, EffectiveAt: future}, ErrValidation},
+		{"unknown department", models.ScheduledChange{EmployeeID: 1, EffectiveAt: future, DepartmentID: intPtr(9)}, ErrInvalidDepartment},
+		{"unknown employee", models.ScheduledChange{EmployeeID: 9, EffectiveAt: future, DepartmentID: intPtr(2)}, ErrEmployeeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sched.Schedule(ctx, tt.change); !errors.Is(err, tt.want) {
+				t.Errorf("Schedule() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduledChangeService_CancelAndFailure(t *testing.T) {
+	sched, emps, depts, _ := newScheduleServices(t)
+	effective := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	cancelled, _ := sched.Schedule(ctx, models.ScheduledChange{EmployeeID: 1, EffectiveAt: effective, DepartmentID: intPtr(2)})
+	failing, _ := sched.Schedule(ctx, models.ScheduledChange{EmployeeID: 1, EffectiveAt: effective, DepartmentID: intPtr(2)})
+
+	if _, err := sched.Cancel(ctx, 1, cancelled.ID); err != nil {
+		t.Fatalf("Cancel() error = %v, want nil", err)
+	}
+	if _, err := sched.Cancel(ctx, 1, cancelled.ID); !errors.Is(err, ErrChangeNotPending) {
+		t.Errorf("second Cancel() error = %v, want %v", err, ErrChangeNotPending)
+	}
+	if pending := sched.List(1, []models.ChangeStatus{models.ChangePending}); len(pending) != 1 || pending[0].ID != failing.ID {
+		t.Errorf("pending = %+v, want only change %d", pending, failing.ID)
+	}
+
+	depts.Delete(ctx, 2)
+	if n, err := sched.ApplyDue(ctx, effective); n != 0 || err != nil {
+		t.Fatalf("ApplyDue() = %d, %v, want 0, nil", n, err)
+	}
+	if got, _ := sched.Retrieve(1, failing.ID); got.Status != models.ChangeFailed || got.Error == "" {
+		t.Errorf("change = %+v, want failed with a reason", got)
+	}
+	if emp, _ := emps.Retrieve(1); emp.DepartmentID != 1 {
+		t.Errorf("DepartmentID = %d, want 1", emp.DepartmentID)
+	}
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"employee-maintenance/models"
+)
+
+// Relevance of a query term, by how it matched an indexed term. Matches in
+// names count for more than matches in the email address.
+const (
+	exactMatchScore  = 3.0
+	prefixMatchScore = 2.0
+	fuzzyMatchScore  = 1.0
+	nameFieldWeight  = 1.5
+	emailFieldWeight = 1.0
+)
+
+// SearchResult is one employee matching a search, with the fields that
+// matched rendered as HTML snippets with the matching parts in <mark> tags.
+type SearchResult struct {
+	Employee   models.Employee   `json:"employee"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type searchField struct {
+	name   string
+	weight float64
+	value  func(models.Employee) string
+}
+
+var searchFields = []searchField{
+	{name: "firstName", weight: nameFieldWeight, value: func(e models.Employee) string { return e.FirstName }},
+	{name: "lastName", weight: nameFieldWeight, value: func(e models.Employee) string { return e.LastName }},
+	{name: "email", weight: emailFieldWeight, value: func(e models.Employee) string { return e.Email }},
+}
+
+// searchIndex is an inverted index from folded terms to the employees and
+// fields containing them. Terms are also kept sorted so prefix lookups are a
+// binary search. Writes to a nil index are ignored.
+type searchIndex struct {
+	postings map[string]map[int]float64
+	terms    []string
+	docs     map[int][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int]float64),
+		docs:     make(map[int][]string),
+	}
+}
+
+// add indexes emp, replacing whatever was indexed for its ID before.
+func (ix *searchIndex) add(emp models.Employee) {
+	if ix == nil {
+		return
+	}
+	ix.remove(emp.ID)
+	seen := make(map[string]bool)
+	for _, f := range searchFields {
+		for _, tok := range tokenize(f.value(emp)) {
+			term := fold(tok.text)
+			docs, exists := ix.postings[term]
+			if !exists {
+				docs = make(map[int]float64)
+				ix.postings[term] = docs
+				i := sort.SearchStrings(ix.terms, term)
+				ix.terms = append(ix.terms, "")
+				copy(ix.terms[i+1:], ix.terms[i:])
+				ix.terms[i] = term
+			}
+			docs[emp.ID] = max(docs[emp.ID], f.weight)
+			if !seen[term] {
+				seen[term] = true
+				ix.docs[emp.ID] = append(ix.docs[emp.ID], term)
+			}
+		}
+	}
+}
+
+func (ix *searchIndex) remove(id int) {
+	if ix == nil {
+		return
+	}
+	for _, term := range ix.docs[id] {
+		docs := ix.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+			i := sort.SearchStrings(ix.terms, term)
+			ix.terms = append(ix.terms[:i], ix.terms[i+1:]...)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// matchingTerms returns every indexed term that query matches, with the
+// score of the best kind of match.
+func (ix *searchIndex) matchingTerms(query string) map[string]float64 {
+	matches := make(map[string]float64)
+	for i := sort.SearchStrings(ix.terms, query); i < len(ix.terms) && strings.HasPrefix(ix.terms[i], query); i++ {
+		matches[ix.terms[i]] = prefixMatchScore
+	}
+	if _, exists := ix.postings[query]; exists {
+		matches[query] = exactMatchScore
+	}
+	if utf8.RuneCountInString(query) >= 3 {
+		for _, term := range ix.terms {
+			if _, matched := matches[term]; !matched && withinOneEdit(query, term) {
+				matches[term] = fuzzyMatchScore
+			}
+		}
+	}
+	return matches
+}
+
+// search scores every employee that matches all query terms.
+func (ix *searchIndex) search(query []string) map[int]float64 {
+	var scores map[int]float64
+	for _, q := range query {
+		best := make(map[int]float64)
+		for term, score := range ix.matchingTerms(q) {
+			for id, weight := range ix.postings[term] {
+				best[id] = max(best[id], score*weight)
+			}
+		}
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if s, matched := best[id]; matched {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+// Search finds employees whose names or email match every word of query.
+// Words match case- and accent-insensitively as a whole word, as a prefix, or
+// with one typo (for words of three or more letters). Results are ordered by
+// relevance; a limit of zero returns all of them.
+func (s *EmployeeService) Search(query string, limit int) ([]SearchResult, error) {
+	var terms []string
+	for _, tok := range tokenize(query) {
+		terms = append(terms, fold(tok.text))
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: search query must contain a letter or digit", ErrInvalidQuery)
+	}
+	if limit < 0 || limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 0 and %d", ErrInvalidQuery, MaxLimit)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scores := s.searchIndex().search(terms)
+	results := make([]SearchResult, 0, len(scores))
+	for id, score := range scores {
+		emp, exists := s.employees.Get(id)
+		if !exists {
+			continue
+		}
+		results = append(results, SearchResult{Employee: normalize(emp), Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Employee.ID < results[j].Employee.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		results[i].Highlights = highlight(results[i].Employee, terms)
+	}
+	return results, nil
+}
+
+// searchIndex returns the index, first building it in a staged copy, which
+// starts without one since few of them are searched. The caller holds s.mu
+// for reading at least.
+func (s *EmployeeService) searchIndex() *searchIndex {
+	s.indexOnce.Do(func() {
+		if s.index == nil {
+			s.index = newSearchIndex()
+			for _, emp := range s.list() {
+				s.index.add(emp)
+			}
+		}
+	})
+	return s.index
+}
+
+// highlight marks the parts of each field that matched one of the terms.
+func highlight(emp models.Employee, terms []string) map[string]string {
+	highlights := make(map[string]string)
+	for _, f := range searchFields {
+		value := f.value(emp)
+		var b strings.Builder
+		last, matched := 0, false
+		for _, tok := range tokenize(value) {
+			n := matchLength(tok.text, terms)
+			if n == 0 {
+				continue
+			}
+			matched = true
+			b.WriteString(html.EscapeString(value[last:tok.start]))
+			b.WriteString("<mark>" + html.EscapeString(tok.text[:n]) + "</mark>")
+			last = tok.start + n
+		}
+		if matched {
+			b.WriteString(html.EscapeString(value[last:]))
+			highlights[f.name] = b.String()
+		}
+	}
+	return highlights
+}
+
+// matchLength returns how many bytes at the start of word matched one of the
+// terms: the whole word for exact and fuzzy matches, the prefix otherwise.
+func matchLength(word string, terms []string) int {
+	folded := fold(word)
+	best := 0
+	for _, t := range terms {
+		switch {
+		case folded == t || (utf8.RuneCountInString(t) >= 3 && withinOneEdit(t, folded)):
+			return len(word)
+		case strings.HasPrefix(folded, t):
+			best = max(best, prefixBytes(word, len(t)))
+		}
+	}
+	return best
+}
+
+// prefixBytes returns the length of the shortest prefix of word whose folded
+// form is at least n bytes long.
+func prefixBytes(word string, n int) int {
+	folded := 0
+	for i, r := range word {
+		if folded >= n {
+			return i
+		}
+		folded += len(fold(string(r)))
+	}
+	return len(word)
+}
+
+type token struct {
+	text  string
+	start int
+}
+
+// tokenize splits s into runs of letters, digits and combining marks.
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			tokens = append(tokens, token{text: s[start:i], start: start})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: s[start:], start: start})
+	}
+	return tokens
+}
+
+// fold lowercases s and strips accents, so "Núñez" and "nunez" compare
+// equal. Combining marks are dropped and precomposed Latin letters are mapped
+// to their base letters.
+func fold(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if base, ok := foldTable[r]; ok {
+			b.WriteString(base)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var foldTable = func() map[rune]string {
+	groups := map[string]string{
+		"a": "àáâãäåāăą", "c": "çćĉċč", "d": "ďđð", "e": "èéêëēĕėęě",
+		"g": "ĝğġģ", "h": "ĥħ", "i": "ìíîïĩīĭįı", "j": "ĵ", "k": "ķ",
+		"l": "ĺļľŀł", "n": "ñńņňŉ", "o": "òóôõöøōŏő", "r": "ŕŗř",
+		"s": "śŝşšș", "t": "ţťŧț", "u": "ùúûüũūŭůűų", "w": "ŵ",
+		"y": "ýÿŷ", "z": "źżž", "ae": "æ", "oe": "œ", "ss": "ß", "th": "þ",
+	}
+	table := make(map[rune]string)
+	for base, runes := range groups {
+		for _, r := range runes {
+			table[r] = base
+		}
+	}
+	return table
+}()
+
+// withinOneEdit reports whether a and b differ by at most one inserted,
+// deleted or substituted rune.
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) > len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(rb)-len(ra) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(ra) && ra[i] == rb[i] {
+		i++
+	}
+	if len(ra) == len(rb) {
+		return string(ra[i+min(1, len(ra)-i):]) == string(rb[i+min(1, len(rb)-i):])
+	}
+	return string(ra[i:]) == string(rb[i+1:])
+}
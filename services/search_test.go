@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+func newSearchTestService(t *testing.T) *EmployeeService {
+	t.Helper()
+	service := NewEmployeeService()
+	for _, emp := range []models.Employee{
+		{ID: 1, FirstName: "José", LastName: "Núñez", Email: "jose.nunez@example.com"},
+		{ID: 2, FirstName: "Joseph", LastName: "Smith", Email: "jsmith@example.com"},
+		{ID: 3, FirstName: "Anna", LastName: "Johnson", Email: "anna@example.com"},
+		{ID: 4, FirstName: "Hanna", LastName: "Jones", Email: "hjones@example.org"},
+	} {
+		if _, err := service.Create(ctx, emp); err != nil {
+			t.Fatalf("Create() error = %v, want nil", err)
+		}
+	}
+	return service
+}
+
+func resultIDs(results []SearchResult) []int {
+	ids := make([]int, len(results))
+	for i, r := range results {
+		ids[i] = r.Employee.ID
+	}
+	return ids
+}
+
+func TestEmployeeService_Search_AccentAndCaseFolding(t *testing.T) {
+	service := newSearchTestService(t)
+
+	results, err := service.Search("NUNEZ", 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v, want nil", err)
+	}
+	if !equalInts(resultIDs(results), []int{1}) {
+		t.Fatalf("Search() IDs = %v, want [1]", resultIDs(results))
+	}
+	if got := results[0].Highlights["lastName"]; got != "<mark>Núñez</mark>" {
+		t.Errorf("lastName highlight = %q, want <mark>Núñez</mark>", got)
+	}
+}
+
+func TestEmployeeService_Search_PrefixRanksBelowExact(t *testing.T) {
+	service := newSearchTestService(t)
+
+	results, err := service.Search("jose", 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v, want nil", err)
+	}
+	if !equalInts(resultIDs(results), []int{1, 2}) {
+		t.Fatalf("Search() IDs = %v, want [1 2]", resultIDs(results))
+	}
+	if got := results[1].Highlights["firstName"]; got != "<mark>Jose</mark>ph" {
+		t.Errorf("firstName highlight = %q, want <mark>Jose</mark>ph", got)
+	}
+}
+
+func TestEmployeeService_Search_TypoTolerance(t *testing.T) {
+	service := newSearchTestService(t)
+
+	results, err := service.Search("jonson", 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v, want nil", err)
+	}
+	if !equalInts(resultIDs(results), []int{3}) {
+		t.Errorf("Search() IDs = %v, want [3]", resultIDs(results))
+	}
+}
+
+func TestEmployeeService_Search_AllWordsMustMatch(t *testing.T) {
+	service := newSearchTestService(t)
+
+	results, _ := service.Search("anna johns", 0)
+	if !equalInts(resultIDs(results), []int{3}) {
+		t.Errorf("Search() IDs = %v, want [3]", resultIDs(results))
+	}
+}
+
+func TestEmployeeService_Search_FuzzyRanksBelowExact(t *testing.T) {
+	service := newSearchTestService(t)
+
+	results, _ := service.Search("anna", 0)
+	if !equalInts(resultIDs(results), []int{3, 4}) {
+		t.Errorf("Search() IDs = %v, want [3 4]", resultIDs(results))
+	}
+}
+
+func TestEmployeeService_Search_FollowsUpdatesAndDeletes(t *testing.T) {
+	service := newSearchTestService(t)
+
+	service.Update(ctx, models.Employee{ID: 3, FirstName: "Anna", LastName: "Karlsson", Email: "anna@example.com"})
+	service.Delete(ctx, 4)
+
+	if results, _ := service.Search("johnson", 0); len(results) != 0 {
+		t.Errorf("Search(johnson) IDs = %v, want none after rename", resultIDs(results))
+	}
+	if results, _ := service.Search("karlsson", 0); !equalInts(resultIDs(results), []int{3}) {
+		t.Errorf("Search(karlsson) IDs = %v, want [3]", resultIDs(results))
+	}
+	if results, _ := service.Search("hjones", 0); len(results) != 0 {
+		t.Errorf("Search(hjones) IDs = %v, want none after delete", resultIDs(results))
+	}
+}
+
+func TestEmployeeService_Search_EmptyQuery(t *testing.T) {
+	service := newSearchTestService(t)
+
+	if _, err := service.Search("  ,. ", 0); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Search() error = %v, want %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"smith", "smith", true},
+		{"smith", "smyth", true},
+		{"smith", "smit", true},
+		{"smith", "smiths", true},
+		{"smith", "msith", false},
+		{"smith", "sith", true},
+		{"smith", "smoth2", false},
+	}
+	for _, tt := range tests {
+		if got := withinOneEdit(tt.a, tt.b); got != tt.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+func TestEmployeeService_DeleteKeepsTombstone(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Create(ctx, testEmployee(2, 1))
+
+	if err := emps.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := emps.Retrieve(1); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("Retrieve() error = %v, want %v", err, ErrEmployeeNotFound)
+	}
+	if got := ids(emps.RetrieveAll()); !equalInts(got, []int{2}) {
+		t.Errorf("RetrieveAll() ids = %v, want [2]", got)
+	}
+	if results, _ := emps.Search("john", 0); len(results) != 1 {
+		t.Errorf("Search() returned %d results, want 1", len(results))
+	}
+
+	page, _ := emps.List(EmployeeFilter{IncludeDeleted: true}, ListOptions{})
+	if got := ids(page.Items); !equalInts(got, []int{1, 2}) {
+		t.Fatalf("List(includeDeleted) ids = %v, want [1 2]", got)
+	}
+	if page.Items[0].DeletedAt == nil {
+		t.Error("List(includeDeleted) tombstone has no DeletedAt")
+	}
+}
+
+func TestEmployeeService_Restore(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	if _, err := emps.Restore(ctx, 1); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("Restore(live) error = %v, want %v", err, ErrNotDeleted)
+	}
+	emps.Delete(ctx, 1)
+	restored, err := emps.Restore(ctx, 1)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.DeletedAt != nil || restored.Version != 3 {
+		t.Errorf("Restore() = %+v, want live at version 3", restored)
+	}
+	if _, err := emps.Retrieve(1); err != nil {
+		t.Errorf("Retrieve() after restore error = %v", err)
+	}
+}
+
+func TestEmployeeService_RestoreIntoDeletedDepartment(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Delete(ctx, 1)
+	depts.Delete(ctx, 1)
+
+	if _, err := emps.Restore(ctx, 1); !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Restore() error = %v, want %v", err, ErrInvalidDepartment)
+	}
+	depts.Restore(ctx, 1)
+	if _, err := emps.Restore(ctx, 1); err != nil {
+		t.Errorf("Restore() after department restore error = %v", err)
+	}
+}
+
+func TestDepartmentService_CascadeTombstonesEmployees(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	if err := depts.DeleteWithOptions(ctx, 1, DeleteOptions{Policy: DeleteCascade}); err != nil {
+		t.Fatalf("DeleteWithOptions() error = %v", err)
+	}
+	if _, err := depts.Retrieve(1); !errors.Is(err, ErrDepartmentNotFound) {
+		t.Errorf("Retrieve() error = %v, want %v", err, ErrDepartmentNotFound)
+	}
+	if _, err := emps.Create(ctx, testEmployee(0, 1)); !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Create() in deleted department error = %v, want %v", err, ErrInvalidDepartment)
+	}
+	if _, err := depts.Restore(ctx, 1); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := emps.Restore(ctx, 1); err != nil {
+		t.Errorf("Restore() cascaded employee error = %v", err)
+	}
+}
+
+func TestPurger_RemovesExpiredTombstones(t *testing.T) {
+	emps, depts, log := newAuditedServices()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	emps.now = func() time.Time { return now }
+	depts.now = func() time.Time { return now }
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	depts.Create(ctx, models.Department{ID: 2, Name: "Sales"})
+	emps.Create(ctx, testEmployee(1, 1))
+	emps.Create(ctx, testEmployee(2, 1))
+	emps.Delete(ctx, 1)
+	depts.Delete(ctx, 2)
+	now = start.Add(48 * time.Hour)
+	emps.Delete(ctx, 2)
+
+	p := Purger{Employees: emps, Departments: depts, Retention: 24 * time.Hour}
+	n, err := p.PurgeOnce(ctx, start.Add(50*time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeOnce() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("PurgeOnce() = %d, want 2", n)
+	}
+	if _, err := emps.Restore(ctx, 1); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("Restore(purged) error = %v, want %v", err, ErrEmployeeNotFound)
+	}
+	if _, err := emps.Restore(ctx, 2); err != nil {
+		t.Errorf("Restore(recent) error = %v", err)
+	}
+	history := log.History(EntityEmployee, 1)
+	if last := history[len(history)-1]; last.Operation != OperationPurge || last.Actor != PurgeActor {
+		t.Errorf("last history entry = %s by %s, want purge by %s", last.Operation, last.Actor, PurgeActor)
+	}
+}
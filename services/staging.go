@@ -0,0 +1,189 @@
+package services
+
+import (
+	"sync"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+// staging holds copies of a pair of services whose writes go to overlays of
+// the real repositories and to staged audit logs. Changes can be made
+// through the copies one after another, each checked against the state the
+// earlier ones left, and then committed together or dropped. Either
+// service may be nil.
+type staging struct {
+	employees   *EmployeeService
+	departments *DepartmentService
+	empWrites   *storage.Overlay[models.Employee]
+	deptWrites  *storage.Overlay[models.Department]
+	audits      map[*AuditLog]*AuditLog
+	// mu is the lock the copies share.
+	mu *sync.RWMutex
+}
+
+func newStaging(emps *EmployeeService, depts *DepartmentService) *staging {
+	var empBase storage.Repository[models.Employee]
+	var deptBase storage.Repository[models.Department]
+	if emps != nil {
+		empBase = emps.employees
+	}
+	if depts != nil {
+		deptBase = depts.departments
+	}
+	return newStagingOver(emps, depts, empBase, deptBase)
+}
+
+// newStagingOver is newStaging with the copies reading from the given
+// repositories, such as snapshots, instead of the services' own.
+func newStagingOver(emps *EmployeeService, depts *DepartmentService, empBase storage.Repository[models.Employee], deptBase storage.Repository[models.Department]) *staging {
+	mu := &sync.RWMutex{}
+	st := &staging{audits: make(map[*AuditLog]*AuditLog), mu: mu}
+	if emps != nil {
+		st.empWrites = storage.NewOverlay(empBase, employeeID)
+		// The copy builds its own search index only if it is searched;
+		// commit brings the service's index up to date.
+		st.employees = &EmployeeService{
+			mu:        mu,
+			employees: st.empWrites,
+			audit:     st.stageAudit(emps.audit),
+			now:       emps.now,
+			ended:     emps.ended,
+		}
+	}
+	if depts != nil {
+		st.deptWrites = storage.NewOverlay(deptBase, departmentID)
+		st.departments = &DepartmentService{
+			mu:          mu,
+			departments: st.deptWrites,
+			audit:       st.stageAudit(depts.audit),
+			now:         depts.now,
+			ended:       depts.ended,
+		}
+	}
+	if emps != nil && depts != nil {
+		st.employees.departments = st.departments
+		st.departments.employees = st.employees
+	}
+	return st
+}
+
+// checkPositions fails with a PositionsError if a department the staged
+// changes delete still has positions.
+func (st *staging) checkPositions(positions *PositionService) error {
+	deleted := make(map[int]bool)
+	for _, id := range st.deptWrites.Changed() {
+		if dept, exists := st.deptWrites.Get(id); exists && dept.DeletedAt != nil {
+			deleted[id] = true
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	if ids := positions.inDepartments(deleted); len(ids) > 0 {
+		return &PositionsError{PositionIDs: ids}
+	}
+	return nil
+}
+
+// stageAudit returns the staged log for a, shared by services that share a.
+func (st *staging) stageAudit(a *AuditLog) *AuditLog {
+	if a == nil {
+		return nil
+	}
+	if staged, ok := st.audits[a]; ok {
+		return staged
+	}
+	staged := a.stage()
+	st.audits[a] = staged
+	return staged
+}
+
+// lockPair write-locks emps and depts, once if they share a lock, and
+// returns the function that unlocks them.
+func lockPair(emps *EmployeeService, depts *DepartmentService) func() {
+	locks := pairLocks(emps, depts)
+	for _, mu := range locks {
+		mu.Lock()
+	}
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+	}
+}
+
+// readLockPair is lockPair for reading.
+func readLockPair(emps *EmployeeService, depts *DepartmentService) func() {
+	locks := pairLocks(emps, depts)
+	for _, mu := range locks {
+		mu.RLock()
+	}
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].RUnlock()
+		}
+	}
+}
+
+// pairLocks returns the distinct locks of emps and depts in locking order.
+func pairLocks(emps *EmployeeService, depts *DepartmentService) []*sync.RWMutex {
+	var locks []*sync.RWMutex
+	if depts != nil {
+		locks = append(locks, depts.mu)
+	}
+	if emps != nil && (depts == nil || emps.mu != depts.mu) {
+		locks = append(locks, emps.mu)
+	}
+	return locks
+}
+
+// commit writes the staged changes to emps and depts, whose write locks the
+// caller holds: the records first, then the audit entries. If a repository
+// or the audit journal fails, the records already written are put back.
+// Positions are not staged, so a department that still has positions is
+// only refused here, where no position can be added in the meantime.
+func (st *staging) commit(emps *EmployeeService, depts *DepartmentService) error {
+	if depts != nil && depts.positions != nil {
+		if err := st.checkPositions(depts.positions); err != nil {
+			return err
+		}
+	}
+	var undos []func()
+	undo := func() {
+		for i := len(undos) - 1; i >= 0; i-- {
+			undos[i]()
+		}
+	}
+	if depts != nil {
+		u, err := st.deptWrites.Apply(depts.departments)
+		if err != nil {
+			return err
+		}
+		undos = append(undos, u)
+	}
+	if emps != nil {
+		u, err := st.empWrites.Apply(emps.employees)
+		if err != nil {
+			undo()
+			return err
+		}
+		undos = append(undos, u)
+	}
+	for log, staged := range st.audits {
+		if err := log.commitStaged(staged); err != nil {
+			undo()
+			return err
+		}
+	}
+	if emps != nil {
+		for _, id := range st.empWrites.Changed() {
+			if emp, exists := emps.live(id); exists {
+				emps.index.add(emp)
+			} else {
+				emps.index.remove(id)
+			}
+		}
+	}
+	return nil
+}
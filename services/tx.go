@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"employee-maintenance/models"
+	"employee-maintenance/storage"
+)
+
+var (
+	ErrTxDone     = errors.New("transaction has already been committed or rolled back")
+	ErrTxConflict = errors.New("transaction conflicts with a concurrent change")
+)
+
+// Tx is a unit of work across the employee and department services. It
+// works on a snapshot of both taken when it began: Employees and
+// Departments return services with the usual methods that read the snapshot
+// with the transaction's own writes applied, and write only to the
+// transaction. Nobody else sees those writes until Commit makes all of them
+// at once; Rollback drops them.
+//
+// Commit fails with ErrTxConflict, changing nothing, if since the
+// transaction began someone else changed a record it changed, or a record
+// that refers to or is referred to by one it changed, such as the department
+// of an employee it moved. The first to commit wins and the other starts
+// over. Point-in-time reads and history inside a transaction see only the
+// audit entries recorded by its own writes.
+type Tx struct {
+	mu       sync.Mutex
+	done     atomic.Bool
+	emps     *EmployeeService
+	depts    *DepartmentService
+	empSnap  *storage.Memory[models.Employee]
+	deptSnap *storage.Memory[models.Department]
+	st       *staging
+}
+
+// Begin starts a transaction on emps and depts, either of which may be nil.
+func Begin(emps *EmployeeService, depts *DepartmentService) *Tx {
+	tx := &Tx{emps: emps, depts: depts}
+	unlock := readLockPair(emps, depts)
+	var empBase storage.Repository[models.Employee]
+	var deptBase storage.Repository[models.Department]
+	if emps != nil {
+		tx.empSnap = snapshot(emps.employees, employeeID)
+		empBase = tx.empSnap
+	}
+	if depts != nil {
+		tx.deptSnap = snapshot(depts.departments, departmentID)
+		deptBase = tx.deptSnap
+	}
+	unlock()
+	tx.st = newStagingOver(emps, depts, empBase, deptBase)
+	if tx.st.employees != nil {
+		tx.st.employees.ended = &tx.done
+	}
+	if tx.st.departments != nil {
+		tx.st.departments.ended = &tx.done
+	}
+	return tx
+}
+
+// checkOpen fails with ErrTxDone once ended is set.
+func checkOpen(ended *atomic.Bool) error {
+	if ended != nil && ended.Load() {
+		return ErrTxDone
+	}
+	return nil
+}
+
+// Employees returns the transaction's view of the employee service, or nil
+// if it was begun without one. Once the transaction has ended, writes
+// through it fail with ErrTxDone.
+func (tx *Tx) Employees() *EmployeeService {
+	return tx.st.employees
+}
+
+// Departments returns the transaction's view of the department service, or
+// nil if it was begun without one. Once the transaction has ended, writes
+// through it fail with ErrTxDone.
+func (tx *Tx) Departments() *DepartmentService {
+	return tx.st.departments
+}
+
+// Commit makes the transaction's writes and ends it. After a failed
+// commit the transaction is rolled back.
+func (tx *Tx) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done.Load() {
+		return ErrTxDone
+	}
+	tx.done.Store(true)
+	unlock := lockPair(tx.emps, tx.depts)
+	defer unlock()
+	tx.st.mu.Lock()
+	defer tx.st.mu.Unlock()
+	if err := tx.checkConflicts(); err != nil {
+		return err
+	}
+	return tx.st.commit(tx.emps, tx.depts)
+}
+
+// Rollback ends the transaction without making its writes. It waits for
+// writes already under way in the transaction to finish.
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done.Load() {
+		return ErrTxDone
+	}
+	tx.done.Store(true)
+	tx.st.mu.Lock()
+	tx.st.mu.Unlock()
+	return nil
+}
+
+func snapshot[T any](repo storage.Repository[T], id func(T) int) *storage.Memory[T] {
+	snap := storage.NewMemory[T]()
+	for _, v := range repo.List() {
+		snap.Put(id(v), v)
+	}
+	return snap
+}
+
+// changes holds the IDs of changed records and of the records they refer
+// to, before or after the change.
+type changes struct {
+	employees, departments       map[int]bool
+	refEmployees, refDepartments map[int]bool
+}
+
+func newChanges() changes {
+	return changes{
+		employees:      make(map[int]bool),
+		departments:    make(map[int]bool),
+		refEmployees:   make(map[int]bool),
+		refDepartments: make(map[int]bool),
+	}
+}
+
+func (c changes) addEmployee(id int, versions ...models.Employee) {
+	c.employees[id] = true
+	for _, emp := range versions {
+		c.refDepartments[emp.DepartmentID] = true
+		c.refEmployees[emp.ManagerID] = true
+	}
+}
+
+func (c changes) addDepartment(id int, versions ...models.Department) {
+	c.departments[id] = true
+	for _, dept := range versions {
+		c.refDepartments[dept.ParentID] = true
+	}
+}
+
+// overlaps reports a record changed in c that is changed or referred to in
+// other, by entity and ID.
+func (c changes) overlaps(other changes) (string, int, bool) {
+	for id := range c.employees {
+		if other.employees[id] || other.refEmployees[id] {
+			return EntityEmployee, id, true
+		}
+	}
+	for id := range c.departments {
+		if other.departments[id] || other.refDepartments[id] {
+			return EntityDepartment, id, true
+		}
+	}
+	return "", 0, false
+}
+
+// checkConflicts compares the transaction's changes with those made to the
+// services since it began. The caller holds the services' write locks.
+func (tx *Tx) checkConflicts() error {
+	mine, theirs := newChanges(), newChanges()
+	if tx.emps != nil {
+		for _, id := range tx.st.empWrites.Changed() {
+			mine.addEmployee(id, versions(tx.empSnap, tx.st.empWrites, id)...)
+		}
+		for _, id := range changedSince(tx.empSnap, tx.emps.employees, employeeID, func(e models.Employee) int { return e.Version }) {
+			theirs.addEmployee(id, versions(tx.empSnap, tx.emps.employees, id)...)
+		}
+	}
+	if tx.depts != nil {
+		for _, id := range tx.st.deptWrites.Changed() {
+			mine.addDepartment(id, versions(tx.deptSnap, tx.st.deptWrites, id)...)
+		}
+		for _, id := range changedSince(tx.deptSnap, tx.depts.departments, departmentID, func(d models.Department) int { return d.Version }) {
+			theirs.addDepartment(id, versions(tx.deptSnap, tx.depts.departments, id)...)
+		}
+	}
+	entity, id, found := mine.overlaps(theirs)
+	if !found {
+		entity, id, found = theirs.overlaps(mine)
+	}
+	if found {
+		return fmt.Errorf("%w: %s %d", ErrTxConflict, entity, id)
+	}
+	return nil
+}
+
+// versions returns the values of id in before and after that exist.
+func versions[T any](before, after storage.Repository[T], id int) []T {
+	var vs []T
+	if v, exists := before.Get(id); exists {
+		vs = append(vs, v)
+	}
+	if v, exists := after.Get(id); exists {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// changedSince returns the IDs whose version differs between snap and
+// current, including records added or removed.
+func changedSince[T any](snap, current storage.Repository[T], id, version func(T) int) []int {
+	seen := make(map[int]int)
+	for _, v := range snap.List() {
+		seen[id(v)] = version(v)
+	}
+	var changed []int
+	for _, v := range current.List() {
+		vid := id(v)
+		if before, exists := seen[vid]; !exists || before != version(v) {
+			changed = append(changed, vid)
+		}
+		delete(seen, vid)
+	}
+	for vid := range seen {
+		changed = append(changed, vid)
+	}
+	return changed
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"employee-maintenance/models"
+)
+
+func TestTx_CommitMakesWritesVisible(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	tx := Begin(emps, depts)
+	dept, err := tx.Departments().Create(ctx, models.Department{Name: "Research"})
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
+	emp, _ := tx.Employees().Retrieve(1)
+	emp.DepartmentID = dept.ID
+	if _, err := tx.Employees().Update(ctx, emp); err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+
+	if got, _ := tx.Employees().Retrieve(1); got.DepartmentID != dept.ID {
+		t.Errorf("in transaction, DepartmentID = %d, want %d", got.DepartmentID, dept.ID)
+	}
+	if got, _ := emps.Retrieve(1); got.DepartmentID != 1 {
+		t.Errorf("before commit, DepartmentID = %d, want 1", got.DepartmentID)
+	}
+	if _, err := depts.Retrieve(dept.ID); !errors.Is(err, ErrDepartmentNotFound) {
+		t.Errorf("before commit, Retrieve() error = %v, want %v", err, ErrDepartmentNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v, want nil", err)
+	}
+	if got, _ := emps.Retrieve(1); got.DepartmentID != dept.ID {
+		t.Errorf("after commit, DepartmentID = %d, want %d", got.DepartmentID, dept.ID)
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Errorf("second Commit() error = %v, want %v", err, ErrTxDone)
+	}
+}
+
+func TestTx_RollbackDropsWrites(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	tx := Begin(emps, depts)
+	tx.Employees().Create(ctx, testEmployee(0, 1))
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v, want nil", err)
+	}
+	if all := emps.RetrieveAll(); len(all) != 0 {
+		t.Errorf("employees = %+v, want none", all)
+	}
+}
+
+func TestTx_ReadsSnapshot(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	tx := Begin(emps, depts)
+	depts.Update(ctx, models.Department{ID: 1, Name: "Research"})
+
+	if got, _ := tx.Departments().Retrieve(1); got.Name != "Engineering" {
+		t.Errorf("in transaction, Name = %q, want Engineering", got.Name)
+	}
+}
+
+func TestTx_CommitConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		tx       func(emps *EmployeeService, depts *DepartmentService)
+		other    func(emps *EmployeeService, depts *DepartmentService)
+		conflict bool
+	}{
+		{
+			name: "same record",
+			tx: func(_ *EmployeeService, depts *DepartmentService) {
+				depts.Update(ctx, models.Department{ID: 1, Name: "Research"})
+			},
+			other: func(_ *EmployeeService, depts *DepartmentService) {
+				depts.Update(ctx, models.Department{ID: 1, Name: "Sales"})
+			},
+			conflict: true,
+		},
+		{
+			name: "referenced department deleted",
+			tx: func(emps *EmployeeService, _ *DepartmentService) {
+				emps.Create(ctx, testEmployee(0, 2))
+			},
+			other: func(_ *EmployeeService, depts *DepartmentService) {
+				depts.Delete(ctx, 2)
+			},
+			conflict: true,
+		},
+		{
+			name: "same new ID",
+			tx: func(_ *EmployeeService, depts *DepartmentService) {
+				depts.Create(ctx, models.Department{Name: "Research"})
+			},
+			other: func(_ *EmployeeService, depts *DepartmentService) {
+				depts.Create(ctx, models.Department{Name: "Sales"})
+			},
+			conflict: true,
+		},
+		{
+			name: "unrelated records",
+			tx: func(emps *EmployeeService, _ *DepartmentService) {
+				emps.Create(ctx, testEmployee(0, 1))
+			},
+			other: func(_ *EmployeeService, depts *DepartmentService) {
+				depts.Update(ctx, models.Department{ID: 2, Name: "Sales"})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emps, depts := newLinkedServices()
+			depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+			depts.Create(ctx, models.Department{ID: 2, Name: "Operations"})
+
+			tx := Begin(emps, depts)
+			tt.tx(tx.Employees(), tx.Departments())
+			tt.other(emps, depts)
+
+			err := tx.Commit()
+			if tt.conflict != errors.Is(err, ErrTxConflict) || !tt.conflict && err != nil {
+				t.Errorf("Commit() error = %v, want conflict %v", err, tt.conflict)
+			}
+		})
+	}
+}
+
+func TestTx_CommitRecordsEntriesAtCommitTime(t *testing.T) {
+	emps, depts := newLinkedServices()
+	log := NewAuditLog()
+	emps.SetAuditLog(log)
+	depts.SetAuditLog(log)
+	clock := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	log.now = func() time.Time { return clock }
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	tx := Begin(emps, depts)
+	tx.Employees().Create(ctx, testEmployee(1, 1))
+	clock = clock.Add(10 * time.Minute)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v, want nil", err)
+	}
+	history := log.History(EntityEmployee, 1)
+	if len(history) != 1 || !history[0].Time.Equal(clock) || !history[0].EffectiveTime.Equal(clock) {
+		t.Errorf("history = %+v, want one entry at the commit time %v", history, clock)
+	}
+}
+
+func TestTx_WritesFailAfterEnd(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	committed := Begin(emps, depts)
+	committed.Commit()
+	if _, err := committed.Employees().Create(ctx, testEmployee(0, 1)); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Create() after Commit error = %v, want %v", err, ErrTxDone)
+	}
+	rolledBack := Begin(emps, depts)
+	rolledBack.Rollback()
+	if _, err := rolledBack.Departments().Create(ctx, models.Department{Name: "Research"}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Create() after Rollback error = %v, want %v", err, ErrTxDone)
+	}
+	// Writes staged on their own, as imports and department deletes are,
+	// must fail too.
+	if _, err := rolledBack.Employees().Import(ctx, strings.NewReader("Ada,Lovelace,ada@example.com,1\n"), ImportOptions{}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Import() after Rollback error = %v, want %v", err, ErrTxDone)
+	}
+	if err := rolledBack.Departments().Delete(ctx, 1); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Delete() after Rollback error = %v, want %v", err, ErrTxDone)
+	}
+	if all := emps.RetrieveAll(); len(all) != 0 {
+		t.Errorf("employees = %+v, want none", all)
+	}
+}
+
+func TestTx_CommitKeepsDepartmentsWithPositions(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	positions := NewPositionService(emps, depts)
+	depts.SetPositionService(positions)
+
+	tx := Begin(emps, depts)
+	if err := tx.Departments().Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete() in transaction error = %v, want nil", err)
+	}
+	positions.Create(ctx, models.Position{Title: "Engineer", DepartmentID: 1, FTE: 1}, PositionOptions{})
+	if err := tx.Commit(); !errors.Is(err, ErrDepartmentHasPositions) {
+		t.Fatalf("Commit() error = %v, want %v", err, ErrDepartmentHasPositions)
+	}
+	if _, err := depts.Retrieve(1); err != nil {
+		t.Errorf("Retrieve(1) error = %v, want the department kept", err)
+	}
+}
+
+func TestTx_SearchSeesOwnWrites(t *testing.T) {
+	emps, depts := newLinkedServices()
+	depts.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+	emps.Create(ctx, testEmployee(1, 1))
+
+	tx := Begin(emps, depts)
+	ada := testEmployee(2, 1)
+	ada.FirstName, ada.Email = "Ada", "ada@example.com"
+	tx.Employees().Create(ctx, ada)
+	if found, _ := tx.Employees().Search("ada", 0); len(found) != 1 {
+		t.Errorf("Search(ada) in transaction found %d, want 1", len(found))
+	}
+	if found, _ := emps.Search("ada", 0); len(found) != 0 {
+		t.Errorf("Search(ada) outside transaction found %d, want 0", len(found))
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v, want nil", err)
+	}
+	if found, _ := emps.Search("ada", 0); len(found) != 1 {
+		t.Errorf("Search(ada) after commit found %d, want 1", len(found))
+	}
+}
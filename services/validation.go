@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"employee-maintenance/models"
+)
+
+var (
+	ErrValidation = errors.New("validation failed")
+)
+
+const (
+	maxNameLength  = 100
+	maxEmailLength = 254
+)
+
+// FieldError describes why a single field was rejected.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError lists every field that failed validation. It matches
+// ErrValidation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		reasons[i] = f.Field + ": " + f.Reason
+	}
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(reasons, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// validator collects field errors so all problems are reported at once.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) fail(field, reason string) {
+	v.fields = append(v.fields, FieldError{Field: field, Reason: reason})
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// text trims *value and checks it is present, valid UTF-8, free of control
+// characters and at most max characters (not bytes) long. It reports whether
+// the value passed so callers can layer further checks on top.
+func (v *validator) text(field string, value *string, max int) bool {
+	*value = strings.TrimSpace(*value)
+	switch {
+	case *value == "":
+		v.fail(field, "is required")
+	case !utf8.ValidString(*value):
+		v.fail(field, "must be valid UTF-8")
+	case utf8.RuneCountInString(*value) > max:
+		v.fail(field, fmt.Sprintf("must be at most %d characters", max))
+	case strings.IndexFunc(*value, unicode.IsControl) >= 0:
+		v.fail(field, "must not contain control characters")
+	default:
+		return true
+	}
+	return false
+}
+
+// optionalText is text for a field that may be left empty.
+func (v *validator) optionalText(field string, value *string, max int) {
+	if *value = strings.TrimSpace(*value); *value != "" {
+		v.text(field, value, max)
+	}
+}
+
+// personName checks a first or last name. Letters and combining marks from
+// any script are allowed, along with the spaces, hyphens, apostrophes and
+// periods that appear in real names.
+func (v *validator) personName(field string, value *string) {
+	if !v.text(field, value, maxNameLength) {
+		return
+	}
+	for _, r := range *value {
+		if unicode.IsLetter(r) || unicode.Is(unicode.M, r) {
+			continue
+		}
+		switch r {
+		case ' ', '-', '\'', '’', '.':
+			continue
+		}
+		v.fail(field, fmt.Sprintf("must not contain %q", r))
+		return
+	}
+}
+
+func (v *validator) email(field string, value *string) {
+	if !v.text(field, value, maxEmailLength) {
+		return
+	}
+	addr, err := mail.ParseAddress(*value)
+	if err != nil || addr.Address != *value || !strings.Contains(*value, "@") {
+		v.fail(field, "must be a valid email address")
+	}
+}
+
+// validateEmployee trims the employee's text fields in place and checks them.
+func validateEmployee(emp *models.Employee) error {
+	var v validator
+	v.personName("firstName", &emp.FirstName)
+	v.personName("lastName", &emp.LastName)
+	v.email("email", &emp.Email)
+	v.optionalText("title", &emp.Title, maxNameLength)
+	if emp.DepartmentID < 0 {
+		v.fail("departmentId", "must not be negative")
+	}
+	if emp.ManagerID < 0 {
+		v.fail("managerId", "must not be negative")
+	}
+	return v.err()
+}
+
+// validateDepartment trims the department's text fields in place and checks
+// them.
+func validateDepartment(dept *models.Department) error {
+	var v validator
+	v.text("name", &dept.Name, maxNameLength)
+	if dept.ParentID < 0 {
+		v.fail("parentId", "must not be negative")
+	}
+	if dept.HeadcountBudget != nil && *dept.HeadcountBudget < 0 {
+		v.fail("headcountBudget", "must not be negative")
+	}
+	return v.err()
+}
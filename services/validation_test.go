@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+func fieldReasons(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want *ValidationError", err)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("errors.Is(%v, ErrValidation) = false", err)
+	}
+	reasons := make(map[string]string)
+	for _, f := range verr.Fields {
+		reasons[f.Field] = f.Reason
+	}
+	return reasons
+}
+
+func TestEmployeeService_Create_Empty(t *testing.T) {
+	service := NewEmployeeService()
+
+	_, err := service.Create(ctx, models.Employee{})
+	reasons := fieldReasons(t, err)
+	for _, field := range []string{"firstName", "lastName", "email"} {
+		if reasons[field] != "is required" {
+			t.Errorf("reason for %s = %q, want %q", field, reasons[field], "is required")
+		}
+	}
+	if len(service.RetrieveAll()) != 0 {
+		t.Errorf("invalid employee was stored")
+	}
+}
+
+func TestEmployeeService_Create_TrimsWhitespace(t *testing.T) {
+	service := NewEmployeeService()
+
+	created, err := service.Create(ctx, models.Employee{FirstName: "  John ", LastName: "Doe\t", Email: " john@example.com "})
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
+	if created.FirstName != "John" || created.LastName != "Doe" || created.Email != "john@example.com" {
+		t.Errorf("Create() = %+v, want trimmed fields", created)
+	}
+}
+
+func TestEmployeeService_Create_WhitespaceOnly(t *testing.T) {
+	service := NewEmployeeService()
+
+	_, err := service.Create(ctx, models.Employee{FirstName: "   ", LastName: "Doe", Email: "john@example.com"})
+	if reasons := fieldReasons(t, err); reasons["firstName"] != "is required" {
+		t.Errorf("reason for firstName = %q, want %q", reasons["firstName"], "is required")
+	}
+}
+
+func TestEmployeeService_Create_InvalidEmail(t *testing.T) {
+	service := NewEmployeeService()
+
+	for _, email := range []string{"john", "john@", "John <john@example.com>", "john@@example.com"} {
+		_, err := service.Create(ctx, models.Employee{FirstName: "John", LastName: "Doe", Email: email})
+		if reasons := fieldReasons(t, err); reasons["email"] == "" {
+			t.Errorf("Create() with email %q was accepted", email)
+		}
+	}
+}
+
+func TestEmployeeService_Create_UnicodeNames(t *testing.T) {
+	service := NewEmployeeService()
+
+	for _, name := range [][2]string{{"José", "Núñez"}, {"Zoë", "O’Brien-Smith"}, {"明", "李"}, {"Ελένη", "Παπαδοπούλου"}} {
+		_, err := service.Create(ctx, models.Employee{FirstName: name[0], LastName: name[1], Email: "someone@example.com"})
+		if err != nil {
+			t.Errorf("Create() with name %v error = %v, want nil", name, err)
+		}
+	}
+}
+
+func TestEmployeeService_Create_InvalidNames(t *testing.T) {
+	service := NewEmployeeService()
+
+	_, err := service.Create(ctx, models.Employee{FirstName: "R2D2", LastName: strings.Repeat("é", maxNameLength+1), Email: "r2@example.com"})
+	reasons := fieldReasons(t, err)
+	if reasons["firstName"] == "" {
+		t.Errorf("firstName with digits was accepted")
+	}
+	if reasons["lastName"] != "must be at most 100 characters" {
+		t.Errorf("reason for lastName = %q, want length limit", reasons["lastName"])
+	}
+}
+
+func TestDepartmentService_Create_Empty(t *testing.T) {
+	service := NewDepartmentService()
+
+	_, err := service.Create(ctx, models.Department{Name: " "})
+	if reasons := fieldReasons(t, err); reasons["name"] != "is required" {
+		t.Errorf("reason for name = %q, want %q", reasons["name"], "is required")
+	}
+}
+
+func TestDepartmentService_Update_Invalid(t *testing.T) {
+	service := NewDepartmentService()
+	service.Create(ctx, models.Department{ID: 1, Name: "Engineering"})
+
+	_, err := service.Update(ctx, models.Department{ID: 1, Name: "Bad\x00Name"})
+	if reasons := fieldReasons(t, err); reasons["name"] == "" {
+		t.Errorf("name with control character was accepted")
+	}
+}
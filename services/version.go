@@ -0,0 +1,20 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// checkVersion enforces optimistic concurrency: a caller that read version
+// expected may only write if nobody else wrote since. An expected version of
+// zero skips the check.
+func checkVersion(expected, current int) error {
+	if expected != 0 && expected != current {
+		return fmt.Errorf("%w: expected version %d, current version is %d", ErrVersionConflict, expected, current)
+	}
+	return nil
+}
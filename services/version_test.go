@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"employee-maintenance/models"
+)
+
+func TestEmployeeService_VersionIncrementsOnWrite(t *testing.T) {
+	service := NewEmployeeService()
+
+	created, _ := service.Create(ctx, testEmployee(0, 0))
+	if created.Version != 1 {
+		t.Errorf("Create() Version = %d, want 1", created.Version)
+	}
+
+	updated, err := service.Update(ctx, created)
+	if err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Update() Version = %d, want 2", updated.Version)
+	}
+}
+
+func TestEmployeeService_Update_StaleVersion(t *testing.T) {
+	service := NewEmployeeService()
+	created, _ := service.Create(ctx, testEmployee(0, 0))
+
+	first := created
+	first.FirstName = "Alice"
+	if _, err := service.Update(ctx, first); err != nil {
+		t.Fatalf("first Update() error = %v, want nil", err)
+	}
+
+	second := created
+	second.FirstName = "Bob"
+	if _, err := service.Update(ctx, second); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("second Update() error = %v, want %v", err, ErrVersionConflict)
+	}
+
+	stored, _ := service.Retrieve(created.ID)
+	if stored.FirstName != "Alice" {
+		t.Errorf("stored FirstName = %v, want Alice", stored.FirstName)
+	}
+}
+
+func TestEmployeeService_DeleteIfVersion(t *testing.T) {
+	service := NewEmployeeService()
+	created, _ := service.Create(ctx, testEmployee(0, 0))
+	service.Update(ctx, created)
+
+	if err := service.DeleteIfVersion(ctx, created.ID, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("DeleteIfVersion(stale) error = %v, want %v", err, ErrVersionConflict)
+	}
+	if err := service.DeleteIfVersion(ctx, created.ID, 2); err != nil {
+		t.Errorf("DeleteIfVersion(current) error = %v, want nil", err)
+	}
+}
+
+func TestDepartmentService_Update_StaleVersion(t *testing.T) {
+	service := NewDepartmentService()
+	created, _ := service.Create(ctx, models.Department{Name: "Engineering"})
+	service.Update(ctx, models.Department{ID: created.ID, Name: "Software Engineering"})
+
+	_, err := service.Update(ctx, models.Department{ID: created.ID, Name: "Platform", Version: created.Version})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Update() error = %v, want %v", err, ErrVersionConflict)
+	}
+	if err := service.DeleteWithOptions(ctx, created.ID, DeleteOptions{IfVersion: created.Version}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("DeleteWithOptions() error = %v, want %v", err, ErrVersionConflict)
+	}
+}
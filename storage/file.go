@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File is a Repository backed by a single JSON file. Every write rewrites the
+// file atomically (write to a temp file, fsync, rename), so the file on disk
+// always holds either the previous or the new state.
+type File[T any] struct {
+	path  string
+	items map[int]T
+	ids   idIndex
+}
+
+// OpenFile loads the repository stored at path, creating an empty one if the
+// file does not exist yet.
+func OpenFile[T any](path string) (*File[T], error) {
+	f := &File[T]{
+		path:  path,
+		items: make(map[int]T),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &f.items); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+	}
+	f.ids = indexIDs(f.items)
+	return f, nil
+}
+
+func (f *File[T]) Get(id int) (T, bool) {
+	v, exists := f.items[id]
+	return v, exists
+}
+
+func (f *File[T]) List() []T {
+	return sortedValues(f.items)
+}
+
+func (f *File[T]) Scan(after, limit int) []T {
+	return scanValues(f.items, f.ids, after, limit)
+}
+
+func (f *File[T]) Put(id int, value T) error {
+	prev, existed := f.items[id]
+	f.items[id] = value
+	if err := f.save(); err != nil {
+		if existed {
+			f.items[id] = prev
+		} else {
+			delete(f.items, id)
+		}
+		return err
+	}
+	f.ids.add(id)
+	return nil
+}
+
+func (f *File[T]) Delete(id int) error {
+	prev, existed := f.items[id]
+	if !existed {
+		return nil
+	}
+	delete(f.items, id)
+	if err := f.save(); err != nil {
+		f.items[id] = prev
+		return err
+	}
+	f.ids.remove(id)
+	return nil
+}
+
+func (f *File[T]) save() error {
+	data, err := json.MarshalIndent(f.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic replaces path with data so that readers never observe a
+// partially written file, even if the process crashes mid-write.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes directory metadata so a completed rename survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
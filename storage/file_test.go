@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestFile_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+
+	repo, err := OpenFile[item](path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v, want nil", err)
+	}
+	repo.Put(1, item{ID: 1, Name: "one"})
+	repo.Put(2, item{ID: 2, Name: "two"})
+	repo.Delete(1)
+
+	reopened, err := OpenFile[item](path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v, want nil", err)
+	}
+	if _, exists := reopened.Get(1); exists {
+		t.Errorf("Get(1) found deleted item")
+	}
+	got, exists := reopened.Get(2)
+	if !exists || got.Name != "two" {
+		t.Errorf("Get(2) = %v, %v, want two, true", got, exists)
+	}
+}
+
+func TestFile_OpenMissingFile(t *testing.T) {
+	repo, err := OpenFile[item](filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v, want nil", err)
+	}
+	if len(repo.List()) != 0 {
+		t.Errorf("List() returned %d items, want 0", len(repo.List()))
+	}
+}
+
+func TestMemory_ListOrderedByID(t *testing.T) {
+	repo := NewMemory[item]()
+	repo.Put(3, item{ID: 3})
+	repo.Put(1, item{ID: 1})
+	repo.Put(2, item{ID: 2})
+
+	all := repo.List()
+	for i, it := range all {
+		if it.ID != i+1 {
+			t.Errorf("List()[%d].ID = %d, want %d", i, it.ID, i+1)
+		}
+	}
+}
+
+func TestMemory_ScanInBatches(t *testing.T) {
+	repo := NewMemory[item]()
+	for _, id := range []int{5, 1, 4, 2, 3, 6} {
+		repo.Put(id, item{ID: id})
+	}
+	repo.Put(3, item{ID: 3, Name: "three"})
+	repo.Delete(6)
+
+	var seen []int
+	for after := 0; ; {
+		batch := repo.Scan(after, 2)
+		if len(batch) == 0 {
+			break
+		}
+		for _, it := range batch {
+			seen = append(seen, it.ID)
+		}
+		after = batch[len(batch)-1].ID
+	}
+	if len(seen) != 5 {
+		t.Fatalf("Scan visited %v, want 1 to 5", seen)
+	}
+	for i, id := range seen {
+		if id != i+1 {
+			t.Errorf("Scan visited %v, want 1 to 5 in order", seen)
+			break
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Journal is an append-only sequence of values. Entries can never be changed
+// or removed once appended. Like Repository, implementations are not safe
+// for concurrent use.
+type Journal[T any] interface {
+	// Append adds values to the end of the journal in order: all of them,
+	// or none if it fails.
+	Append(values ...T) error
+	Entries() []T
+}
+
+// MemoryJournal is a Journal that keeps its entries in a slice.
+type MemoryJournal[T any] struct {
+	entries []T
+}
+
+func NewMemoryJournal[T any]() *MemoryJournal[T] {
+	return &MemoryJournal[T]{}
+}
+
+func (j *MemoryJournal[T]) Append(values ...T) error {
+	j.entries = append(j.entries, values...)
+	return nil
+}
+
+func (j *MemoryJournal[T]) Entries() []T {
+	return append([]T(nil), j.entries...)
+}
+
+// FileJournal is a Journal stored in a log file using the same checksummed
+// framing as Log. Every append is fsynced before it returns, and a torn final
+// record left by a crash is discarded on open.
+type FileJournal[T any] struct {
+	log     *logFile
+	entries []T
+}
+
+// OpenJournal opens (or creates) the journal stored at path and loads its
+// entries.
+func OpenJournal[T any](path string) (*FileJournal[T], error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	lf, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	j := &FileJournal[T]{log: lf}
+	err = lf.replay(func(payload []byte) error {
+		var v T
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return fmt.Errorf("failed to decode journal entry: %w", err)
+		}
+		j.entries = append(j.entries, v)
+		return nil
+	})
+	if err != nil {
+		lf.close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *FileJournal[T]) Append(values ...T) error {
+	payloads := make([][]byte, len(values))
+	for i, v := range values {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal entry: %w", err)
+		}
+		payloads[i] = payload
+	}
+	if err := j.log.append(payloads...); err != nil {
+		return err
+	}
+	j.entries = append(j.entries, values...)
+	return nil
+}
+
+func (j *FileJournal[T]) Entries() []T {
+	return append([]T(nil), j.entries...)
+}
+
+func (j *FileJournal[T]) Close() error {
+	return j.log.close()
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileJournal_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+
+	j, err := OpenJournal[item](path)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v, want nil", err)
+	}
+	j.Append(item{ID: 1, Name: "first"})
+	j.Append(item{ID: 2, Name: "second"}, item{ID: 3, Name: "third"})
+	j.Close()
+
+	reopened, err := OpenJournal[item](path)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v, want nil", err)
+	}
+	defer reopened.Close()
+	entries := reopened.Entries()
+	if len(entries) != 3 || entries[0].Name != "first" || entries[1].Name != "second" || entries[2].Name != "third" {
+		t.Errorf("Entries() = %v, want first, second, third", entries)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// DefaultCompactEvery is the number of log records after which a Log folds
+// its log into a fresh snapshot.
+const DefaultCompactEvery = 1000
+
+// Log is a Repository that keeps its items in memory and makes every write
+// durable by appending it to a write-ahead log, fsynced before the in-memory
+// map is touched. On open it loads the last snapshot and replays the log on
+// top of it. Every compactEvery records the current state is written to a new
+// snapshot and the log is truncated, which keeps the log bounded.
+type Log[T any] struct {
+	snapshotPath string
+	log          *logFile
+	items        map[int]T
+	ids          idIndex
+	records      int
+	compactEvery int
+}
+
+type logRecord[T any] struct {
+	Op    string `json:"op"`
+	ID    int    `json:"id"`
+	Value *T     `json:"value,omitempty"`
+}
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// OpenLog opens (or creates) the log-backed repository called name in dir.
+// A compactEvery of zero or less uses DefaultCompactEvery.
+func OpenLog[T any](dir, name string, compactEvery int) (*Log[T], error) {
+	if compactEvery <= 0 {
+		compactEvery = DefaultCompactEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	l := &Log[T]{
+		snapshotPath: filepath.Join(dir, name+".snapshot"),
+		items:        make(map[int]T),
+		compactEvery: compactEvery,
+	}
+	if err := l.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	l.ids = indexIDs(l.items)
+
+	lf, err := openLogFile(filepath.Join(dir, name+".log"))
+	if err != nil {
+		return nil, err
+	}
+	err = lf.replay(func(payload []byte) error {
+		var rec logRecord[T]
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return fmt.Errorf("failed to decode log record: %w", err)
+		}
+		l.apply(rec)
+		l.records++
+		return nil
+	})
+	if err != nil {
+		lf.close()
+		return nil, err
+	}
+	l.log = lf
+	return l, nil
+}
+
+func (l *Log[T]) loadSnapshot() error {
+	data, err := os.ReadFile(l.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", l.snapshotPath, err)
+	}
+	if err := json.Unmarshal(data, &l.items); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", l.snapshotPath, err)
+	}
+	return nil
+}
+
+func (l *Log[T]) Get(id int) (T, bool) {
+	v, exists := l.items[id]
+	return v, exists
+}
+
+func (l *Log[T]) List() []T {
+	return sortedValues(l.items)
+}
+
+func (l *Log[T]) Scan(after, limit int) []T {
+	return scanValues(l.items, l.ids, after, limit)
+}
+
+func (l *Log[T]) Put(id int, value T) error {
+	return l.write(logRecord[T]{Op: opPut, ID: id, Value: &value})
+}
+
+func (l *Log[T]) Delete(id int) error {
+	if _, exists := l.items[id]; !exists {
+		return nil
+	}
+	return l.write(logRecord[T]{Op: opDelete, ID: id})
+}
+
+func (l *Log[T]) write(rec logRecord[T]) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode log record: %w", err)
+	}
+	if err := l.log.append(payload); err != nil {
+		return err
+	}
+	l.apply(rec)
+	l.records++
+	if l.records >= l.compactEvery {
+		// The write is already durable in the log, so a failed compaction
+		// is only logged; the next write tries again.
+		if err := l.Compact(); err != nil {
+			log.Printf("compacting %s failed: %v", l.snapshotPath, err)
+		}
+	}
+	return nil
+}
+
+func (l *Log[T]) apply(rec logRecord[T]) {
+	switch rec.Op {
+	case opPut:
+		if rec.Value != nil {
+			l.items[rec.ID] = *rec.Value
+			l.ids.add(rec.ID)
+		}
+	case opDelete:
+		delete(l.items, rec.ID)
+		l.ids.remove(rec.ID)
+	}
+}
+
+// Compact writes the current state to the snapshot file and empties the log.
+// A crash between the two steps is harmless: replaying the old log over the
+// new snapshot yields the same state.
+func (l *Log[T]) Compact() error {
+	data, err := json.Marshal(l.items)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := writeFileAtomic(l.snapshotPath, data); err != nil {
+		return err
+	}
+	if err := l.log.truncate(); err != nil {
+		return err
+	}
+	l.records = 0
+	return nil
+}
+
+func (l *Log[T]) Close() error {
+	return l.log.close()
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLog_ReplayAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := OpenLog[item](dir, "items", 0)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	repo.Put(1, item{ID: 1, Name: "one"})
+	repo.Put(2, item{ID: 2, Name: "two"})
+	repo.Put(1, item{ID: 1, Name: "uno"})
+	repo.Delete(2)
+	repo.Close()
+
+	reopened, err := OpenLog[item](dir, "items", 0)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	defer reopened.Close()
+	got, exists := reopened.Get(1)
+	if !exists || got.Name != "uno" {
+		t.Errorf("Get(1) = %v, %v, want uno, true", got, exists)
+	}
+	if _, exists := reopened.Get(2); exists {
+		t.Errorf("Get(2) found deleted item")
+	}
+}
+
+func TestLog_TornFinalRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := OpenLog[item](dir, "items", 0)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	repo.Put(1, item{ID: 1, Name: "one"})
+	repo.Close()
+
+	// Simulate a crash halfway through appending a second record.
+	f, err := os.OpenFile(filepath.Join(dir, "items.log"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{40, 0, 0, 0, 1, 2, 3, 4, '{', '"', 'o'})
+	f.Close()
+
+	reopened, err := OpenLog[item](dir, "items", 0)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	if _, exists := reopened.Get(1); !exists {
+		t.Errorf("Get(1) lost the last complete record")
+	}
+	reopened.Put(2, item{ID: 2, Name: "two"})
+	reopened.Close()
+
+	again, err := OpenLog[item](dir, "items", 0)
+	if err != nil {
+		t.Fatalf("OpenLog() after truncating torn record error = %v, want nil", err)
+	}
+	defer again.Close()
+	if len(again.List()) != 2 {
+		t.Errorf("List() returned %d items, want 2", len(again.List()))
+	}
+}
+
+func TestLog_CompactionBoundsLog(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := OpenLog[item](dir, "items", 3)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	for i := 1; i <= 7; i++ {
+		repo.Put(i, item{ID: i})
+	}
+	repo.Close()
+
+	info, err := os.Stat(filepath.Join(dir, "items.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 7 records with a threshold of 3 leaves one record in the log.
+	if info.Size() == 0 || info.Size() > 64 {
+		t.Errorf("log size = %d bytes, want a single record", info.Size())
+	}
+
+	reopened, err := OpenLog[item](dir, "items", 3)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	defer reopened.Close()
+	if len(reopened.List()) != 7 {
+		t.Errorf("List() returned %d items, want 7", len(reopened.List()))
+	}
+}
+
+func TestLog_CompactionFailureKeepsWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := OpenLog[item](dir, "items", 1)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	// A snapshot path below a regular file cannot be written.
+	repo.snapshotPath = filepath.Join(dir, "items.log", "items.json")
+	if err := repo.Put(1, item{ID: 1, Name: "one"}); err != nil {
+		t.Fatalf("Put() error = %v, want nil", err)
+	}
+	repo.Close()
+
+	reopened, err := OpenLog[item](dir, "items", 0)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	defer reopened.Close()
+	if got, exists := reopened.Get(1); !exists || got.Name != "one" {
+		t.Errorf("Get(1) = %v, %v, want one, true", got, exists)
+	}
+}
+
+func TestLog_ScanAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := OpenLog[item](dir, "items", 2)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	for _, id := range []int{3, 1, 2, 4} {
+		repo.Put(id, item{ID: id})
+	}
+	repo.Delete(2)
+	repo.Close()
+
+	reopened, err := OpenLog[item](dir, "items", 2)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v, want nil", err)
+	}
+	defer reopened.Close()
+	if got := reopened.Scan(1, 2); len(got) != 2 || got[0].ID != 3 || got[1].ID != 4 {
+		t.Errorf("Scan(1, 2) = %v, want items 3 and 4", got)
+	}
+}
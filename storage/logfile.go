@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+var (
+	ErrCorruptLog = errors.New("corrupt log record")
+)
+
+// frameHeaderSize is the size of the length and CRC-32 that precede every
+// record payload in a log file.
+const frameHeaderSize = 8
+
+// logFile is an append-only file of length-prefixed, checksummed records.
+type logFile struct {
+	path string
+	f    *os.File
+	size int64
+}
+
+func openLogFile(path string) (*logFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &logFile{path: path, f: f}, nil
+}
+
+// replay calls fn with every record payload in order. A torn final record,
+// left behind by a crash during append, is discarded and the file truncated
+// to the last complete record; a damaged record in the middle of the file is
+// reported as ErrCorruptLog.
+func (l *logFile) replay(fn func(payload []byte) error) error {
+	data, err := io.ReadAll(l.f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", l.path, err)
+	}
+
+	offset := 0
+	for offset < len(data) {
+		payload, next, ok := readFrame(data, offset)
+		if !ok {
+			if next < len(data) {
+				return fmt.Errorf("%s at offset %d: %w", l.path, offset, ErrCorruptLog)
+			}
+			break
+		}
+		if err := fn(payload); err != nil {
+			return err
+		}
+		offset = next
+	}
+
+	if offset < len(data) {
+		if err := l.f.Truncate(int64(offset)); err != nil {
+			return fmt.Errorf("failed to truncate torn record in %s: %w", l.path, err)
+		}
+		if err := l.f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", l.path, err)
+		}
+	}
+	if _, err := l.f.Seek(int64(offset), io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek %s: %w", l.path, err)
+	}
+	l.size = int64(offset)
+	return nil
+}
+
+// readFrame decodes the frame starting at offset. It returns the payload, the
+// offset just past the frame, and whether the frame is intact. For a damaged
+// frame the returned offset is where the frame claims to end, so callers can
+// tell a torn tail (ends at or beyond EOF) from corruption mid-file.
+func readFrame(data []byte, offset int) ([]byte, int, bool) {
+	if len(data)-offset < frameHeaderSize {
+		return nil, len(data), false
+	}
+	size := int(binary.LittleEndian.Uint32(data[offset:]))
+	sum := binary.LittleEndian.Uint32(data[offset+4:])
+	start := offset + frameHeaderSize
+	end := start + size
+	if end > len(data) {
+		return nil, len(data), false
+	}
+	payload := data[start:end]
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, end, false
+	}
+	return payload, end, true
+}
+
+// append writes one record per payload in a single write and fsyncs them
+// before returning. If either step fails the partial frames are cut off
+// again, so none of the records is kept and later appends stay readable.
+func (l *logFile) append(payloads ...[]byte) error {
+	var frames []byte
+	for _, payload := range payloads {
+		frame := make([]byte, frameHeaderSize+len(payload))
+		binary.LittleEndian.PutUint32(frame, uint32(len(payload)))
+		binary.LittleEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(payload))
+		copy(frame[frameHeaderSize:], payload)
+		frames = append(frames, frame...)
+	}
+
+	if _, err := l.f.Write(frames); err != nil {
+		l.rewind()
+		return fmt.Errorf("failed to append to %s: %w", l.path, err)
+	}
+	if err := l.f.Sync(); err != nil {
+		l.rewind()
+		return fmt.Errorf("failed to sync %s: %w", l.path, err)
+	}
+	l.size += int64(len(frames))
+	return nil
+}
+
+func (l *logFile) rewind() {
+	l.f.Truncate(l.size)
+	l.f.Seek(l.size, io.SeekStart)
+}
+
+func (l *logFile) truncate() error {
+	if err := l.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", l.path, err)
+	}
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek %s: %w", l.path, err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", l.path, err)
+	}
+	l.size = 0
+	return nil
+}
+
+func (l *logFile) close() error {
+	return l.f.Close()
+}
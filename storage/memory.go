@@ -0,0 +1,39 @@
+package storage
+
+// Memory is a Repository that keeps everything in a map. Its contents are
+// lost when the process exits.
+type Memory[T any] struct {
+	items map[int]T
+	ids   idIndex
+}
+
+func NewMemory[T any]() *Memory[T] {
+	return &Memory[T]{
+		items: make(map[int]T),
+	}
+}
+
+func (m *Memory[T]) Get(id int) (T, bool) {
+	v, exists := m.items[id]
+	return v, exists
+}
+
+func (m *Memory[T]) List() []T {
+	return sortedValues(m.items)
+}
+
+func (m *Memory[T]) Scan(after, limit int) []T {
+	return scanValues(m.items, m.ids, after, limit)
+}
+
+func (m *Memory[T]) Put(id int, value T) error {
+	m.items[id] = value
+	m.ids.add(id)
+	return nil
+}
+
+func (m *Memory[T]) Delete(id int) error {
+	delete(m.items, id)
+	m.ids.remove(id)
+	return nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import "sort"
+
+// Overlay is a Repository that keeps its writes to itself: reads see the
+// base with the writes made so far laid over it, and the base is left
+// untouched until the writes are applied. id returns the ID of a value.
+type Overlay[T any] struct {
+	base    Repository[T]
+	id      func(T) int
+	written map[int]T
+	deleted map[int]bool
+}
+
+func NewOverlay[T any](base Repository[T], id func(T) int) *Overlay[T] {
+	return &Overlay[T]{
+		base:    base,
+		id:      id,
+		written: make(map[int]T),
+		deleted: make(map[int]bool),
+	}
+}
+
+func (o *Overlay[T]) Get(id int) (T, bool) {
+	if o.deleted[id] {
+		var zero T
+		return zero, false
+	}
+	if v, exists := o.written[id]; exists {
+		return v, true
+	}
+	return o.base.Get(id)
+}
+
+// merged returns the base's values, keyed by ID, with the writes applied.
+func (o *Overlay[T]) merged() map[int]T {
+	items := make(map[int]T)
+	for id, v := range o.written {
+		items[id] = v
+	}
+	for _, v := range o.base.List() {
+		id := o.id(v)
+		if _, written := o.written[id]; !written && !o.deleted[id] {
+			items[id] = v
+		}
+	}
+	return items
+}
+
+func (o *Overlay[T]) List() []T {
+	return sortedValues(o.merged())
+}
+
+// Scan pages through the base alongside the overlay's writes rather than
+// merging the whole base for every batch.
+func (o *Overlay[T]) Scan(after, limit int) []T {
+	if limit <= 0 {
+		return nil
+	}
+	var result []T
+	for cursor := after; len(result) < limit; {
+		batch := o.base.Scan(cursor, limit)
+		for _, v := range batch {
+			cursor = o.id(v)
+			if _, written := o.written[cursor]; !written && !o.deleted[cursor] {
+				result = append(result, v)
+			}
+		}
+		if len(batch) < limit {
+			break
+		}
+	}
+	for id, v := range o.written {
+		if id > after {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return o.id(result[i]) < o.id(result[j]) })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func (o *Overlay[T]) Put(id int, value T) error {
+	o.written[id] = value
+	delete(o.deleted, id)
+	return nil
+}
+
+func (o *Overlay[T]) Delete(id int) error {
+	delete(o.written, id)
+	o.deleted[id] = true
+	return nil
+}
+
+// Changed returns the IDs written or deleted through the overlay, in order.
+func (o *Overlay[T]) Changed() []int {
+	ids := make([]int, 0, len(o.written)+len(o.deleted))
+	for id := range o.written {
+		ids = append(ids, id)
+	}
+	for id := range o.deleted {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Apply makes the overlay's writes to target, usually its base, and returns
+// a function that undoes them. If target fails part way, the writes already
+// made are undone as far as possible and the error is returned.
+func (o *Overlay[T]) Apply(target Repository[T]) (undo func(), err error) {
+	type original struct {
+		id      int
+		value   T
+		existed bool
+	}
+	var applied []original
+	undo = func() {
+		for i := len(applied) - 1; i >= 0; i-- {
+			if a := applied[i]; a.existed {
+				target.Put(a.id, a.value)
+			} else {
+				target.Delete(a.id)
+			}
+		}
+	}
+	for _, id := range o.Changed() {
+		prev, existed := target.Get(id)
+		var err error
+		if o.deleted[id] {
+			if existed {
+				err = target.Delete(id)
+			}
+		} else {
+			err = target.Put(id, o.written[id])
+		}
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		applied = append(applied, original{id, prev, existed})
+	}
+	return undo, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func itemID(i item) int { return i.ID }
+
+func TestOverlay_ReadsOwnWritesAndLeavesBaseAlone(t *testing.T) {
+	base := NewMemory[item]()
+	base.Put(1, item{ID: 1, Name: "one"})
+	base.Put(2, item{ID: 2, Name: "two"})
+
+	o := NewOverlay[item](base, itemID)
+	o.Put(3, item{ID: 3, Name: "three"})
+	o.Put(1, item{ID: 1, Name: "uno"})
+	o.Delete(2)
+
+	if got, _ := o.Get(1); got.Name != "uno" {
+		t.Errorf("Get(1) = %v, want uno", got)
+	}
+	if _, exists := o.Get(2); exists {
+		t.Error("Get(2) found an item deleted in the overlay")
+	}
+	if got := o.List(); len(got) != 2 || got[0].Name != "uno" || got[1].Name != "three" {
+		t.Errorf("List() = %v, want [uno three]", got)
+	}
+	if got := o.Scan(1, 10); len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("Scan(1) = %v, want [three]", got)
+	}
+	if got, _ := base.Get(1); got.Name != "one" || len(base.List()) != 2 {
+		t.Errorf("base changed before Apply: %v", base.List())
+	}
+
+	undo, err := o.Apply(base)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got := base.List(); len(got) != 2 || got[0].Name != "uno" || got[1].Name != "three" {
+		t.Errorf("base after Apply = %v, want [uno three]", got)
+	}
+	undo()
+	if got := base.List(); len(got) != 2 || got[0].Name != "one" || got[1].Name != "two" {
+		t.Errorf("base after undo = %v, want [one two]", got)
+	}
+}
+
+// failingRepo fails every Put of the item with ID fail.
+type failingRepo struct {
+	*Memory[item]
+	fail int
+}
+
+func (f failingRepo) Put(id int, v item) error {
+	if id == f.fail {
+		return errors.New("disk full")
+	}
+	return f.Memory.Put(id, v)
+}
+
+func TestOverlay_ApplyUndoesOnFailure(t *testing.T) {
+	base := failingRepo{Memory: NewMemory[item](), fail: 3}
+	base.Memory.Put(1, item{ID: 1, Name: "one"})
+
+	o := NewOverlay[item](base, itemID)
+	o.Put(1, item{ID: 1, Name: "uno"})
+	o.Put(2, item{ID: 2, Name: "two"})
+	o.Put(3, item{ID: 3, Name: "three"})
+	if _, err := o.Apply(base); err == nil {
+		t.Fatal("Apply() succeeded, want the storage error")
+	}
+	if got := base.List(); len(got) != 1 || got[0].Name != "one" {
+		t.Errorf("base after failed Apply = %v, want [one]", got)
+	}
+}
+
+func TestOverlay_ScanInBatches(t *testing.T) {
+	base := NewMemory[item]()
+	for id := 1; id <= 6; id++ {
+		base.Put(id, item{ID: id})
+	}
+	o := NewOverlay[item](base, itemID)
+	o.Delete(2)
+	o.Delete(3)
+	o.Put(4, item{ID: 4, Name: "four"})
+	o.Put(8, item{ID: 8})
+	o.Put(0, item{ID: 0})
+
+	var seen []int
+	for after := 0; ; {
+		batch := o.Scan(after, 2)
+		if len(batch) == 0 {
+			break
+		}
+		for _, it := range batch {
+			seen = append(seen, it.ID)
+		}
+		after = batch[len(batch)-1].ID
+	}
+	want := []int{1, 4, 5, 6, 8}
+	if len(seen) != len(want) {
+		t.Fatalf("Scan visited %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("Scan visited %v, want %v", seen, want)
+		}
+	}
+	if got := o.Scan(3, 1); len(got) != 1 || got[0].Name != "four" {
+		t.Errorf("Scan(3, 1) = %v, want [four]", got)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import "sort"
+
+// Repository stores values of type T keyed by an integer ID. Implementations
+// are not safe for concurrent use; the services serialize access to them.
+type Repository[T any] interface {
+	Get(id int) (T, bool)
+	List() []T
+	// Scan returns up to limit values with IDs greater than after, ordered
+	// by ID, so large repositories can be walked a batch at a time.
+	Scan(after, limit int) []T
+	Put(id int, value T) error
+	Delete(id int) error
+}
+
+// sortedValues returns the values of items ordered by ID.
+func sortedValues[T any](items map[int]T) []T {
+	ids := make([]int, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	result := make([]T, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, items[id])
+	}
+	return result
+}
+
+// idIndex holds the IDs of a repository's items in order, so that Scan can
+// find its place by binary search instead of sorting every ID per batch.
+type idIndex []int
+
+func indexIDs[T any](items map[int]T) idIndex {
+	ids := make(idIndex, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// add inserts id unless it is already present. New IDs usually exceed all
+// others, so this is normally an append.
+func (x *idIndex) add(id int) {
+	i := sort.SearchInts(*x, id)
+	if i < len(*x) && (*x)[i] == id {
+		return
+	}
+	*x = append(*x, 0)
+	copy((*x)[i+1:], (*x)[i:])
+	(*x)[i] = id
+}
+
+func (x *idIndex) remove(id int) {
+	i := sort.SearchInts(*x, id)
+	if i < len(*x) && (*x)[i] == id {
+		*x = append((*x)[:i], (*x)[i+1:]...)
+	}
+}
+
+// scanValues implements Repository.Scan over a map and the index of its
+// IDs.
+func scanValues[T any](items map[int]T, ids idIndex, after, limit int) []T {
+	start := sort.SearchInts(ids, after+1)
+	end := min(start+max(limit, 0), len(ids))
+	result := make([]T, 0, end-start)
+	for _, id := range ids[start:end] {
+		result = append(result, items[id])
+	}
+	return result
+}